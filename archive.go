@@ -0,0 +1,560 @@
+// utility/archive.go
+package Utility
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Archives
+// --------
+// Archive writes a directory as a tar.gz or zip stream and Extract unpacks
+// one, without external tools and without holding the archive in memory
+// (zip extraction needs random access, so a zip read from a plain reader is
+// spooled to a temporary file first). Extract never writes outside dst:
+// entries with absolute or ".." paths, and symbolic links pointing out of
+// dst, are rejected.
+//
+//	err := Archive("site", w, ArchiveTarGz, ArchiveOptions{Exclude: []string{"*.log"}})
+
+// ArchiveFormat selects the archive format.
+type ArchiveFormat int
+
+const (
+	ArchiveTarGz ArchiveFormat = iota
+	ArchiveZip
+)
+
+func (f ArchiveFormat) String() string {
+	switch f {
+	case ArchiveTarGz:
+		return "tar.gz"
+	case ArchiveZip:
+		return "zip"
+	}
+	return fmt.Sprintf("ArchiveFormat(%d)", int(f))
+}
+
+// ArchiveOptions configures Archive and Extract. Patterns are globs matched
+// as in CopyTreeOptions, against entry paths relative to the archived
+// directory (after StripComponents when extracting).
+type ArchiveOptions struct {
+	Include        []string // only files matching one of them are archived or extracted
+	Exclude        []string // files and directories left out
+	FollowSymlinks bool     // Archive stores what symbolic links point to rather than the links
+	Prefix         string   // Archive prepends it to entry names ("." gives the names of tar -C dir .)
+	// StripComponents drops that many leading path elements from entry names
+	// when extracting, like tar --strip-components; shorter entries are skipped.
+	StripComponents int
+	// Progress counts the content bytes processed, file by file, and is
+	// completed once the whole archive is written or extracted.
+	Progress *Progress
+}
+
+// Archive writes the contents of the directory src to w in the given format.
+func Archive(src string, w io.Writer, format ArchiveFormat, opts ArchiveOptions) error {
+	return archiveDir(context.Background(), src, w, format, opts)
+}
+
+// Extract unpacks the tar.gz, tar or zip archive read from r into dst,
+// creating it. The format is detected from the content.
+func Extract(r io.Reader, dst string, opts ArchiveOptions) error {
+	return extractArchive(context.Background(), r, dst, opts)
+}
+
+func archiveDir(ctx context.Context, src string, w io.Writer, format ArchiveFormat, opts ArchiveOptions) (err error) {
+	defer endOperation(startOperation("archive.create", map[string]interface{}{"src": src, "format": format.String()}), &err)
+	if opts.Progress != nil {
+		defer func() {
+			if err == nil {
+				opts.Progress.Done()
+			}
+		}()
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return &GuardError{Field: "src", Reason: "not a directory: " + src}
+	}
+	a := &archiver{ctx: ctx, opts: opts}
+	if a.include, err = compileGlobs(opts.Include); err != nil {
+		return err
+	}
+	if a.exclude, err = compileGlobs(opts.Exclude); err != nil {
+		return err
+	}
+
+	switch format {
+	case ArchiveTarGz:
+		gz := gzip.NewWriter(w)
+		a.tw = tar.NewWriter(gz)
+		if err := a.dir(src, "", make(map[string]bool)); err != nil {
+			return err
+		}
+		if err := a.tw.Close(); err != nil {
+			return err
+		}
+		return gz.Close()
+	case ArchiveZip:
+		a.zw = zip.NewWriter(w)
+		if err := a.dir(src, "", make(map[string]bool)); err != nil {
+			return err
+		}
+		return a.zw.Close()
+	}
+	return &GuardError{Field: "format", Reason: "unknown archive format " + format.String()}
+}
+
+type archiver struct {
+	ctx     context.Context
+	opts    ArchiveOptions
+	include []*GlobMatcher
+	exclude []*GlobMatcher
+	tw      *tar.Writer
+	zw      *zip.Writer
+}
+
+// dir archives the entries of the directory src, rel being its path in the
+// archive.
+func (a *archiver) dir(src, rel string, visiting map[string]bool) error {
+	if real, err := filepath.EvalSymlinks(src); err == nil {
+		if visiting[real] {
+			return &GuardError{Field: "src", Reason: "symbolic link loop at " + src}
+		}
+		visiting[real] = true
+		defer delete(visiting, real)
+	}
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := a.ctx.Err(); err != nil {
+			return err
+		}
+		s, r := filepath.Join(src, e.Name()), path.Join(rel, e.Name())
+		if matchGlobs(a.exclude, r) {
+			continue
+		}
+		fi, err := os.Lstat(s)
+		if err != nil {
+			return err
+		}
+		if fi.Mode()&os.ModeSymlink != 0 && a.opts.FollowSymlinks {
+			if fi, err = os.Stat(s); err != nil {
+				return err
+			}
+		}
+		switch {
+		case fi.IsDir():
+			if len(a.include) == 0 {
+				if err := a.entry(s, r, fi); err != nil {
+					return err
+				}
+			}
+			if err := a.dir(s, r, visiting); err != nil {
+				return err
+			}
+		case len(a.include) > 0 && !matchGlobs(a.include, r):
+		case fi.Mode()&os.ModeSymlink != 0 || fi.Mode().IsRegular():
+			if err := a.entry(s, r, fi); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// entry writes one directory, link or file.
+func (a *archiver) entry(src, rel string, fi os.FileInfo) error {
+	name := rel
+	if a.opts.Prefix != "" {
+		name = path.Join(a.opts.Prefix, rel)
+		if a.opts.Prefix == "." {
+			name = "./" + name
+		}
+	}
+	link := ""
+	if fi.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		link = filepath.ToSlash(target)
+	}
+
+	var w io.Writer
+	if a.tw != nil {
+		hdr, err := tar.FileInfoHeader(fi, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = name
+		if fi.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := a.tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if link != "" {
+			return nil
+		}
+		w = a.tw
+	} else {
+		hdr, err := zip.FileInfoHeader(fi)
+		if err != nil {
+			return err
+		}
+		hdr.Name = name
+		if fi.IsDir() {
+			hdr.Name += "/"
+		} else if link == "" {
+			hdr.Method = zip.Deflate
+		}
+		zw, err := a.zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if link != "" {
+			_, err = io.WriteString(zw, link) // zip keeps the target as the content
+			return err
+		}
+		w = zw
+	}
+	if fi.IsDir() {
+		return nil
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	n, err := io.Copy(w, f)
+	if err != nil {
+		return err
+	}
+	if a.opts.Progress != nil {
+		a.opts.Progress.Add(n)
+	}
+	return nil
+}
+
+func extractArchive(ctx context.Context, r io.Reader, dst string, opts ArchiveOptions) (err error) {
+	defer endOperation(startOperation("archive.extract", map[string]interface{}{"dst": dst}), &err)
+	if opts.Progress != nil {
+		defer func() {
+			if err == nil {
+				opts.Progress.Done()
+			}
+		}()
+	}
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	root, err := filepath.Abs(dst)
+	if err != nil {
+		return err
+	}
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return err
+	}
+	x := &extractor{ctx: ctx, opts: opts, root: root}
+	if x.include, err = compileGlobs(opts.Include); err != nil {
+		return err
+	}
+	if x.exclude, err = compileGlobs(opts.Exclude); err != nil {
+		return err
+	}
+
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(512)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		if err := x.tar(tar.NewReader(gz)); err != nil {
+			return err
+		}
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")) || bytes.HasPrefix(magic, []byte("PK\x05\x06")):
+		if err := x.zip(r, br); err != nil {
+			return err
+		}
+	case len(magic) > 262 && string(magic[257:262]) == "ustar":
+		if err := x.tar(tar.NewReader(br)); err != nil {
+			return err
+		}
+	default:
+		return errors.New("extract: unknown archive format")
+	}
+	return x.finish()
+}
+
+type extractor struct {
+	ctx     context.Context
+	opts    ArchiveOptions
+	root    string // resolved destination
+	include []*GlobMatcher
+	exclude []*GlobMatcher
+	dirs    []extractedDir
+}
+
+// extractedDir gets its mode and time once its content is written, so
+// read-only directories can still be filled.
+type extractedDir struct {
+	path  string
+	mode  os.FileMode
+	mtime time.Time
+}
+
+func (x *extractor) tar(tr *tar.Reader) error {
+	for {
+		if err := x.ctx.Err(); err != nil {
+			return err
+		}
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var link string
+		var mode os.FileMode
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			mode = os.ModeDir
+		case tar.TypeSymlink:
+			mode, link = os.ModeSymlink, hdr.Linkname
+		case tar.TypeReg, tar.TypeRegA:
+		default:
+			continue // hard links, devices, pipes...
+		}
+		mode |= os.FileMode(hdr.Mode).Perm()
+		if err := x.entry(hdr.Name, mode, hdr.ModTime, link, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// zip extracts from r when it is a file, otherwise from a copy of br
+// spooled to a temporary file.
+func (x *extractor) zip(r io.Reader, br *bufio.Reader) error {
+	var ra io.ReaderAt
+	var size int64
+	if f, ok := r.(*os.File); ok {
+		if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() {
+			if pos, err := f.Seek(0, io.SeekCurrent); err == nil {
+				start := pos - int64(br.Buffered()) // where br started reading
+				size = fi.Size() - start
+				ra = io.NewSectionReader(f, start, size)
+			}
+		}
+	}
+	if ra == nil {
+		tmp, err := os.CreateTemp("", "extract-*.zip")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		if size, err = io.Copy(tmp, br); err != nil {
+			return err
+		}
+		ra = tmp
+	}
+	zr, err := zip.NewReader(ra, size)
+	if err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		if err := x.ctx.Err(); err != nil {
+			return err
+		}
+		mode := f.Mode()
+		var link string
+		if mode&os.ModeSymlink != 0 {
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			b, err := io.ReadAll(io.LimitReader(rc, 4096))
+			rc.Close()
+			if err != nil {
+				return err
+			}
+			link = string(b)
+		}
+		if err := x.zipEntry(f, mode, link); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *extractor) zipEntry(f *zip.File, mode os.FileMode, link string) error {
+	if mode.IsRegular() {
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		return x.entry(f.Name, mode, f.Modified, "", rc)
+	}
+	return x.entry(f.Name, mode, f.Modified, link, nil)
+}
+
+// entry writes one entry of the archive; content is read for files only.
+func (x *extractor) entry(name string, mode os.FileMode, mtime time.Time, link string, content io.Reader) error {
+	parts := strings.FieldsFunc(name, func(r rune) bool { return r == '/' })
+	if len(parts) <= x.opts.StripComponents {
+		return nil
+	}
+	rel := path.Clean(strings.Join(parts[x.opts.StripComponents:], "/"))
+	if path.IsAbs(name) || filepath.VolumeName(filepath.FromSlash(name)) != "" || rel == ".." || strings.HasPrefix(rel, "../") {
+		return fmt.Errorf("extract: entry %q escapes the destination", name)
+	}
+	if rel == "." {
+		return nil
+	}
+	for p := rel; p != "."; p = path.Dir(p) {
+		if matchGlobs(x.exclude, p) {
+			return nil
+		}
+	}
+	if len(x.include) > 0 && (mode.IsDir() || !matchGlobs(x.include, rel)) {
+		return nil
+	}
+
+	target := filepath.Join(x.root, filepath.FromSlash(rel))
+	if err := x.mkdirInside(filepath.Dir(target)); err != nil {
+		return err
+	}
+	if fi, err := os.Lstat(target); err == nil {
+		switch {
+		case fi.IsDir() && mode.IsDir():
+		case fi.IsDir():
+			return fmt.Errorf("extract: %s: is a directory", target)
+		default:
+			if err := os.Remove(target); err != nil { // replaced, never written through
+				return err
+			}
+		}
+	}
+
+	switch {
+	case mode.IsDir():
+		if err := os.MkdirAll(target, 0700); err != nil {
+			return err
+		}
+		x.dirs = append(x.dirs, extractedDir{target, mode.Perm(), mtime})
+		return nil
+	case mode&os.ModeSymlink != 0:
+		dest, err := x.resolveLink(filepath.Dir(target), filepath.FromSlash(link))
+		if err != nil {
+			return err
+		}
+		if !x.inside(dest) {
+			return fmt.Errorf("extract: link %q -> %q escapes the destination", name, link)
+		}
+		return os.Symlink(filepath.FromSlash(link), target)
+	}
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(out, content)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	os.Chmod(target, mode.Perm())
+	os.Chtimes(target, time.Now(), mtime)
+	if x.opts.Progress != nil {
+		x.opts.Progress.Add(n)
+	}
+	return nil
+}
+
+// inside reports whether p, cleaned, is within the destination.
+func (x *extractor) inside(p string) bool {
+	rel, err := filepath.Rel(x.root, filepath.Clean(p))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// resolveLink returns where link, read from the directory dir, points to.
+// It is followed one element at a time the way the system would, resolving
+// the links already extracted, so that a chain of relative links can't
+// climb out of the destination unnoticed.
+func (x *extractor) resolveLink(dir, link string) (string, error) {
+	cur, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", err
+	}
+	if filepath.IsAbs(link) {
+		cur = filepath.VolumeName(link) + string(filepath.Separator)
+		link = link[len(filepath.VolumeName(link)):]
+	}
+	for _, elem := range strings.Split(link, string(filepath.Separator)) {
+		switch elem {
+		case "", ".":
+		case "..":
+			cur = filepath.Dir(cur)
+		default:
+			cur = filepath.Join(cur, elem)
+			if real, err := filepath.EvalSymlinks(cur); err == nil {
+				cur = real
+			}
+		}
+	}
+	return cur, nil
+}
+
+// mkdirInside creates dir and checks that, links resolved, it is still
+// within the destination.
+func (x *extractor) mkdirInside(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	real, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	if !x.inside(real) {
+		return fmt.Errorf("extract: %s resolves outside the destination", dir)
+	}
+	return nil
+}
+
+// finish applies the directory modes and times, deepest first.
+func (x *extractor) finish() error {
+	for i := len(x.dirs) - 1; i >= 0; i-- {
+		d := x.dirs[i]
+		if err := os.Chmod(d.path, d.mode); err != nil {
+			return err
+		}
+		os.Chtimes(d.path, time.Now(), d.mtime)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// utility/buildinfo.go
+package Utility
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Build information
+// -----------------
+// BuildInfo describes the running binary: main module version, VCS revision
+// and time as recorded by the Go toolchain, plus the target platform. Release
+// builds can override the recorded values with
+//
+//	go build -ldflags "-X github.com/globulario/utility.BuildVersion=v1.2.3 \
+//	  -X github.com/globulario/utility.BuildTime=2024-05-01T10:00:00Z"
+//
+// and services attach their own labels with RegisterBuildTag.
+
+// Values set with -ldflags -X; they take precedence over debug.ReadBuildInfo.
+var (
+	BuildVersion  string
+	BuildRevision string
+	BuildTime     string // RFC 3339
+)
+
+// BuildMetadata is what BuildInfo reports.
+type BuildMetadata struct {
+	Path      string            `json:"path"`             // main module path
+	Version   string            `json:"version"`          // "(devel)" for local builds
+	SemVer    *Version          `json:"semver,omitempty"` // Version parsed, nil when not semantic
+	GoVersion string            `json:"goVersion"`
+	Revision  string            `json:"revision,omitempty"`
+	Time      time.Time         `json:"time,omitzero"` // build time, or commit time when unknown
+	Modified  bool              `json:"modified"`      // built from a dirty tree
+	GOOS      string            `json:"goos"`
+	GOARCH    string            `json:"goarch"`
+	Tags      map[string]string `json:"tags,omitempty"`
+}
+
+var (
+	buildOnce sync.Once
+	buildBase BuildMetadata
+
+	buildTagsMu sync.RWMutex
+	buildTags   = make(map[string]string)
+)
+
+// RegisterBuildTag attaches a label (channel, flavor, builder...) reported by
+// BuildInfo.
+func RegisterBuildTag(key, value string) {
+	buildTagsMu.Lock()
+	buildTags[key] = value
+	buildTagsMu.Unlock()
+}
+
+// BuildInfo returns the build information of the running binary.
+func BuildInfo() BuildMetadata {
+	buildOnce.Do(func() {
+		b := BuildMetadata{GoVersion: runtime.Version(), GOOS: runtime.GOOS, GOARCH: runtime.GOARCH}
+		if info, ok := debug.ReadBuildInfo(); ok {
+			b.Path, b.Version, b.GoVersion = info.Main.Path, info.Main.Version, info.GoVersion
+			for _, s := range info.Settings {
+				switch s.Key {
+				case "vcs.revision":
+					b.Revision = s.Value
+				case "vcs.time":
+					b.Time, _ = time.Parse(time.RFC3339, s.Value)
+				case "vcs.modified":
+					b.Modified = s.Value == "true"
+				case "GOOS":
+					b.GOOS = s.Value
+				case "GOARCH":
+					b.GOARCH = s.Value
+				}
+			}
+		}
+		if BuildVersion != "" {
+			b.Version = BuildVersion
+		}
+		if BuildRevision != "" {
+			b.Revision = BuildRevision
+		}
+		if t, err := time.Parse(time.RFC3339, BuildTime); err == nil {
+			b.Time = t
+		}
+		if strings.Count(b.Version, ".") >= 2 {
+			b.SemVer = NewVersion(b.Version)
+		}
+		buildBase = b
+	})
+
+	b := buildBase
+	buildTagsMu.RLock()
+	if len(buildTags) > 0 {
+		b.Tags = make(map[string]string, len(buildTags))
+		for k, v := range buildTags {
+			b.Tags[k] = v
+		}
+	}
+	buildTagsMu.RUnlock()
+	return b
+}
@@ -0,0 +1,60 @@
+// utility/call_interceptor.go
+package Utility
+
+import (
+	"reflect"
+)
+
+// Call interceptors
+// -----------------
+// Interceptors wrap every CallFunction/CallMethod invocation. Each one receives
+// the call name ("fnName" or "pkg.Type.Method"), the raw parameters and a next
+// function running the rest of the chain; it may inspect, short-circuit (by not
+// calling next) or post-process the results. The first registered interceptor
+// is the outermost.
+
+// CallInterceptor wraps a dynamic function or method invocation.
+type CallInterceptor func(name string, params []interface{}, next func() ([]reflect.Value, error)) ([]reflect.Value, error)
+
+// RegisterCallInterceptor appends an interceptor to the chain.
+func (tm *TypeManager) RegisterCallInterceptor(ic CallInterceptor) {
+	if ic == nil {
+		return
+	}
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.interceptors = append(tm.interceptors, ic)
+}
+
+// ClearCallInterceptors removes every registered interceptor.
+func (tm *TypeManager) ClearCallInterceptors() {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.interceptors = nil
+}
+
+// intercept runs call through the interceptor chain.
+func (tm *TypeManager) intercept(name string, params []interface{}, call func() ([]reflect.Value, error)) ([]reflect.Value, error) {
+	tm.mu.RLock()
+	chain := tm.interceptors
+	tm.mu.RUnlock()
+
+	next := call
+	for i := len(chain) - 1; i >= 0; i-- {
+		ic, inner := chain[i], next
+		next = func() ([]reflect.Value, error) {
+			return ic(name, params, inner)
+		}
+	}
+	return next()
+}
+
+// RegisterCallInterceptor adds an interceptor to the default TypeManager.
+func RegisterCallInterceptor(ic CallInterceptor) {
+	DefaultTypeManager().RegisterCallInterceptor(ic)
+}
+
+// ClearCallInterceptors removes the interceptors of the default TypeManager.
+func ClearCallInterceptors() {
+	DefaultTypeManager().ClearCallInterceptors()
+}
@@ -0,0 +1,84 @@
+// utility/call_safe.go
+package Utility
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"runtime/debug"
+)
+
+// Panic-safe calls
+// ----------------
+// A dynamic call panics when the payload doesn't fit the target (a string
+// where an int is expected, a nil map written to...), and a panic that
+// escapes the goroutine of an RPC handler takes the whole service down.
+// CallFunctionSafe and CallMethodSafe return such panics as *PanicError,
+// with the stack of the panic.
+
+// PanicError is a panic recovered from a dynamic call.
+type PanicError struct {
+	Name  string      // the function, or "Type.Method"
+	Value interface{} // the value passed to panic
+	Stack string      // stack of the panicking goroutine
+}
+
+func newPanicError(name string, r interface{}) *PanicError {
+	return &PanicError{Name: name, Value: r, Stack: string(debug.Stack())}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic in %s: %v", e.Name, e.Value)
+}
+
+// Unwrap returns the panic value when it is an error (e.g. a runtime.Error).
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// StackTrace returns the stack of the panic.
+func (e *PanicError) StackTrace() string { return e.Stack }
+
+// Format prints the stack after the message for %+v.
+func (e *PanicError) Format(s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		io.WriteString(s, e.Error()+"\n"+e.Stack)
+	case verb == 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	default:
+		io.WriteString(s, e.Error())
+	}
+}
+
+// CallFunctionSafe is CallFunction returning panics as *PanicError.
+func CallFunctionSafe(name string, params ...interface{}) (result []reflect.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, newPanicError(name, r)
+		}
+	}()
+	return CallFunction(name, params...)
+}
+
+// CallMethodSafe is CallMethod with a typed error: panics are returned as
+// *PanicError, and an error result of the method as itself.
+func CallMethodSafe(i interface{}, methodName string, params []interface{}) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, newPanicError(methodName, r)
+		}
+	}()
+	res, errv, pe := callMethod(i, methodName, params)
+	switch {
+	case pe != nil:
+		return nil, pe
+	case errv == nil:
+		return res, nil
+	}
+	if e, ok := errv.(error); ok {
+		return res, e
+	}
+	return res, fmt.Errorf("%v", errv)
+}
@@ -0,0 +1,164 @@
+// utility/cidr.go
+package Utility
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// CIDR and subnet math
+// --------------------
+// Helpers for IPv4 and IPv6 networks. Addresses are given as strings, like
+// everywhere else in the package; NextIP, PrevIP and the IPsInCIDR iterator
+// work on net.IP since they are meant for loops.
+
+// IPsInCIDR returns an iterator over every address of cidr, network and
+// broadcast addresses included.
+func IPsInCIDR(cidr string) (iter.Seq[net.IP], error) {
+	ip, ipnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+	start := ip.Mask(ipnet.Mask)
+	if v4 := start.To4(); v4 != nil {
+		start = v4
+	}
+	return func(yield func(net.IP) bool) {
+		for cur := start; ipnet.Contains(cur); cur = NextIP(cur) {
+			if !yield(cur) {
+				return
+			}
+			if isLastIP(cur) {
+				return
+			}
+		}
+	}, nil
+}
+
+// isLastIP reports whether every byte of ip is 0xff (NextIP would wrap).
+func isLastIP(ip net.IP) bool {
+	for _, b := range ip {
+		if b != 0xff {
+			return false
+		}
+	}
+	return true
+}
+
+// NextIP returns the address following ip (wrapping around at the end).
+func NextIP(ip net.IP) net.IP {
+	next := append(net.IP(nil), normalizeIP(ip)...)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
+
+// PrevIP returns the address preceding ip (wrapping around at the start).
+func PrevIP(ip net.IP) net.IP {
+	prev := append(net.IP(nil), normalizeIP(ip)...)
+	for i := len(prev) - 1; i >= 0; i-- {
+		prev[i]--
+		if prev[i] != 0xff {
+			break
+		}
+	}
+	return prev
+}
+
+// normalizeIP returns the 4-byte form of IPv4 addresses.
+func normalizeIP(ip net.IP) net.IP {
+	if v4 := ip.To4(); v4 != nil {
+		return v4
+	}
+	return ip
+}
+
+// parseMask accepts a prefix length ("24", "/24") or a dotted mask
+// ("255.255.255.0"); bits is the address size (32 or 128).
+func parseMask(mask string, bits int) (net.IPMask, error) {
+	mask = strings.TrimPrefix(strings.TrimSpace(mask), "/")
+	if n, err := strconv.Atoi(mask); err == nil {
+		if n < 0 || n > bits {
+			return nil, fmt.Errorf("invalid prefix length %d", n)
+		}
+		return net.CIDRMask(n, bits), nil
+	}
+	if ip := net.ParseIP(mask); ip != nil {
+		m := net.IPMask(normalizeIP(ip))
+		if _, size := m.Size(); size == 0 {
+			return nil, fmt.Errorf("invalid non-contiguous mask %s", mask)
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("invalid mask %q", mask)
+}
+
+// SameSubnet reports whether ip1 and ip2 belong to the same network for mask
+// (a prefix length such as "24" or a dotted mask such as "255.255.255.0").
+func SameSubnet(ip1, ip2, mask string) bool {
+	a, b := net.ParseIP(ip1), net.ParseIP(ip2)
+	if a == nil || b == nil {
+		return false
+	}
+	a, b = normalizeIP(a), normalizeIP(b)
+	if len(a) != len(b) {
+		return false
+	}
+	m, err := parseMask(mask, len(a)*8)
+	if err != nil || len(m) != len(a) {
+		return false
+	}
+	return a.Mask(m).Equal(b.Mask(m))
+}
+
+// IsIPInRange reports whether ip belongs to the network cidr.
+func IsIPInRange(ip, cidr string) bool {
+	addr := net.ParseIP(ip)
+	_, ipnet, err := net.ParseCIDR(cidr)
+	return addr != nil && err == nil && ipnet.Contains(addr)
+}
+
+// CIDRForInterface returns the IPv4 network of the interface name, e.g.
+// "192.168.1.0/24".
+func CIDRForInterface(name string) (string, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return "", err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", err
+	}
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() != nil {
+			return (&net.IPNet{IP: ipnet.IP.Mask(ipnet.Mask), Mask: ipnet.Mask}).String(), nil
+		}
+	}
+	return "", errors.New("no IPv4 network on interface " + name)
+}
+
+// cidrForIP returns the network of the local interface holding ip.
+func cidrForIP(ip string) (string, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return "", errors.New("invalid IP address " + ip)
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "", err
+	}
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok && ipnet.IP.Equal(addr) {
+			return (&net.IPNet{IP: ipnet.IP.Mask(ipnet.Mask), Mask: ipnet.Mask}).String(), nil
+		}
+	}
+	return "", errors.New("no local interface has address " + ip)
+}
@@ -0,0 +1,271 @@
+// utility/cmd/utility/main.go
+
+// Command utility exposes the helpers of the utility package to scripts, so
+// operators debugging a node run the exact code paths the services use.
+//
+//	utility checksum FILE...
+//	utility thumbnail [-width W] [-height H] IMAGE
+//	utility compress DIR ARCHIVE.tar.gz
+//	utility extract ARCHIVE.tar.gz [DEST]
+//	utility scan-network [-hosts]
+//	utility ping [-port N] [-timeout D] HOST|URL
+//	utility download [-timeout D] URL FILE
+//	utility metadata FILE
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	Utility "github.com/globulario/utility"
+)
+
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"checksum":     {"FILE...", checksum},
+	"thumbnail":    {"[-width W] [-height H] IMAGE", thumbnail},
+	"compress":     {"DIR ARCHIVE.tar.gz", compress},
+	"extract":      {"ARCHIVE.tar.gz [DEST]", extract},
+	"scan-network": {"[-hosts]", scanNetwork},
+	"ping":         {"[-port N] [-timeout D] HOST|URL", ping},
+	"download":     {"[-timeout D] URL FILE", download},
+	"metadata":     {"FILE", metadata},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "utility: unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(os.Args[2:]); err == errUsage {
+		fmt.Fprintf(os.Stderr, "usage: utility %s %s\n", os.Args[1], cmd.usage)
+		os.Exit(2)
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, "utility "+os.Args[1]+":", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(os.Stderr, "usage:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  utility %s %s\n", name, commands[name].usage)
+	}
+}
+
+// errUsage reports wrong positional arguments.
+var errUsage = errors.New("usage")
+
+// parse parses the flags of a subcommand and checks its positional arguments.
+func parse(fs *flag.FlagSet, args []string, min, max int) ([]string, error) {
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() < min || (max >= 0 && fs.NArg() > max) {
+		return nil, errUsage
+	}
+	return fs.Args(), nil
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func checksum(args []string) error {
+	files, err := parse(flag.NewFlagSet("checksum", flag.ExitOnError), args, 1, -1)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if !Utility.Exists(f) {
+			return fmt.Errorf("%s: no such file", f)
+		}
+		fmt.Printf("%s  %s\n", Utility.CreateFileChecksum(f), f)
+	}
+	return nil
+}
+
+func thumbnail(args []string) error {
+	fs := flag.NewFlagSet("thumbnail", flag.ExitOnError)
+	width := fs.Int("width", 256, "maximum width")
+	height := fs.Int("height", 256, "maximum height")
+	files, err := parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	thumb, err := Utility.CreateThumbnail(files[0], *height, *width)
+	if err != nil {
+		return err
+	}
+	fmt.Println(thumb)
+	return nil
+}
+
+func compress(args []string) error {
+	paths, err := parse(flag.NewFlagSet("compress", flag.ExitOnError), args, 2, 2)
+	if err != nil {
+		return err
+	}
+	out, err := os.Create(paths[1])
+	if err != nil {
+		return err
+	}
+	if _, err := Utility.CompressDir(paths[0], out); err != nil {
+		out.Close()
+		os.Remove(paths[1])
+		return err
+	}
+	return out.Close()
+}
+
+func extract(args []string) error {
+	paths, err := parse(flag.NewFlagSet("extract", flag.ExitOnError), args, 1, 2)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(paths[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if len(paths) == 1 {
+		dir, err := Utility.ExtractTarGz(f)
+		if err != nil {
+			return err
+		}
+		fmt.Println(dir)
+		return nil
+	}
+
+	// Extract next to DEST (or inside it when it exists) so that moving the
+	// result into place is a rename on the same device.
+	dest := paths[1]
+	exists := Utility.Exists(dest)
+	parent := filepath.Dir(dest)
+	if exists {
+		parent = dest
+	}
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp(parent, "extract-")
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(dir, 0755); err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+	if err := Utility.Extract(f, dir, Utility.ArchiveOptions{StripComponents: 1}); err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+	if !exists {
+		if err := os.Rename(dir, dest); err != nil {
+			os.RemoveAll(dir)
+			return err
+		}
+		dir = dest
+	}
+	fmt.Println(dir)
+	return nil
+}
+
+func scanNetwork(args []string) error {
+	fs := flag.NewFlagSet("scan-network", flag.ExitOnError)
+	hosts := fs.Bool("hosts", false, "resolve host names of the local network with nmap")
+	if _, err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if *hosts {
+		ip, err := Utility.GetPrimaryIPAddress()
+		if err != nil {
+			return err
+		}
+		return printJSON(Utility.GetHostnameIPMap(ip))
+	}
+	neighbors, err := Utility.Neighbors(context.Background())
+	if err != nil {
+		return err
+	}
+	return printJSON(neighbors)
+}
+
+func ping(args []string) error {
+	fs := flag.NewFlagSet("ping", flag.ExitOnError)
+	port := fs.Int("port", 0, "check a TCP port instead of sending ICMP")
+	timeout := fs.Duration("timeout", 3*time.Second, "time to wait for an answer")
+	targets, err := parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	target := targets[0]
+	start := time.Now()
+	switch {
+	case strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://"):
+		err = Utility.PingHTTP(target, *timeout)
+	case *port > 0:
+		err = Utility.PingTCP(target, *port, *timeout)
+	default:
+		err = Utility.PingCtx(context.Background(), target, Utility.WithTimeout(*timeout))
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s is reachable (%v)\n", target, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+func download(args []string) error {
+	fs := flag.NewFlagSet("download", flag.ExitOnError)
+	timeout := fs.Duration("timeout", 0, "abort after this duration (0 = none)")
+	paths, err := parse(fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	return Utility.DownloadFileCtx(context.Background(), paths[0], paths[1], Utility.WithTimeout(*timeout))
+}
+
+var audioExtensions = map[string]bool{".mp3": true, ".flac": true, ".m4a": true, ".ogg": true, ".wav": true, ".aac": true}
+
+func metadata(args []string) error {
+	files, err := parse(flag.NewFlagSet("metadata", flag.ExitOnError), args, 1, 1)
+	if err != nil {
+		return err
+	}
+	var meta map[string]interface{}
+	if audioExtensions[strings.ToLower(filepath.Ext(files[0]))] {
+		meta, err = Utility.ReadAudioMetadata(files[0], 256, 256)
+	} else {
+		meta, err = Utility.ReadMetadata(files[0])
+	}
+	if err != nil {
+		return err
+	}
+	return printJSON(meta)
+}
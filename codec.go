@@ -0,0 +1,94 @@
+// utility/codec.go
+package Utility
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"sync"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Serialization codecs
+// --------------------
+// ToBytes/FromBytes use gob by default, which only Go can read. A Codec can
+// be chosen per call (ToBytesWith/FromBytesWith) or globally
+// (SetDefaultCodec) to exchange the same entities with JSON or MessagePack
+// consumers such as the JS front end.
+
+// Codec encodes values to bytes and back.
+type Codec interface {
+	Name() string
+	Encode(v interface{}) ([]byte, error)
+	Decode(data []byte, v interface{}) error
+}
+
+// GobCodec encodes with encoding/gob (types must be registered with gob).
+type GobCodec struct{}
+
+func (GobCodec) Name() string { return "gob" }
+
+func (GobCodec) Encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+func (GobCodec) Decode(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// JSONCodec encodes with encoding/json.
+type JSONCodec struct{}
+
+func (JSONCodec) Name() string { return "json" }
+
+func (JSONCodec) Encode(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+func (JSONCodec) Decode(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// MsgpackCodec encodes with MessagePack.
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) Name() string { return "msgpack" }
+
+func (MsgpackCodec) Encode(v interface{}) ([]byte, error) { return msgpack.Marshal(v) }
+
+func (MsgpackCodec) Decode(data []byte, v interface{}) error { return msgpack.Unmarshal(data, v) }
+
+var (
+	codecMu      sync.RWMutex
+	defaultCodec Codec = GobCodec{}
+)
+
+// SetDefaultCodec selects the codec used by ToBytes and FromBytes (nil
+// restores gob).
+func SetDefaultCodec(c Codec) {
+	if c == nil {
+		c = GobCodec{}
+	}
+	codecMu.Lock()
+	defaultCodec = c
+	codecMu.Unlock()
+}
+
+// DefaultCodec returns the codec used by ToBytes and FromBytes.
+func DefaultCodec() Codec {
+	codecMu.RLock()
+	defer codecMu.RUnlock()
+	return defaultCodec
+}
+
+// CodecByName returns the built-in codec called name ("gob", "json", "msgpack").
+func CodecByName(name string) (Codec, bool) {
+	switch name {
+	case "gob":
+		return GobCodec{}, true
+	case "json":
+		return JSONCodec{}, true
+	case "msgpack":
+		return MsgpackCodec{}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,251 @@
+// utility/connpool.go
+package Utility
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Connection pool for TCP/Unix sockets
+// ------------------------------------
+// ConnPool reuses connections to a single address. MaxActive bounds the number
+// of connections handed out at once (Get waits for a slot), MaxIdle bounds how
+// many are kept for reuse, and idle connections are checked before reuse.
+
+// ErrPoolClosed is returned by Get after Close.
+var ErrPoolClosed = errors.New("connection pool is closed")
+
+// ConnPoolOptions configures a ConnPool.
+type ConnPoolOptions struct {
+	MaxIdle     int           // idle connections kept for reuse (default 2)
+	MaxActive   int           // connections in use at once, 0 means unlimited
+	IdleTimeout time.Duration // idle connections older than this are closed, 0 disables
+	// HealthCheck validates an idle connection before reuse; nil uses a
+	// non-blocking read probe that detects peers that hung up.
+	HealthCheck func(net.Conn) error
+}
+
+// ConnPoolStats is a snapshot of pool counters.
+type ConnPoolStats struct {
+	Active         int   // connections currently handed out
+	Idle           int   // idle connections
+	Hits           int64 // Get served from the idle list
+	Misses         int64 // Get that had to dial
+	DialErrors     int64
+	HealthFailures int64 // idle connections discarded by the health check
+	Expired        int64 // idle connections discarded by IdleTimeout
+}
+
+type idleConn struct {
+	conn  net.Conn
+	since time.Time
+}
+
+// ConnPool is a pool of net.Conn to one endpoint.
+type ConnPool struct {
+	dial func(ctx context.Context) (net.Conn, error)
+	opts ConnPoolOptions
+
+	mu     sync.Mutex
+	idle   []idleConn
+	active int
+	closed bool
+	slots  chan struct{} // nil when MaxActive is unlimited
+
+	hits, misses, dialErrors, healthFailures, expired int64
+}
+
+// NewConnPool creates a pool dialing network/address ("tcp", "unix", ...).
+func NewConnPool(network, address string, opts ConnPoolOptions) *ConnPool {
+	var d net.Dialer
+	return NewConnPoolWithDialer(func(ctx context.Context) (net.Conn, error) {
+		return d.DialContext(ctx, network, address)
+	}, opts)
+}
+
+// NewConnPoolWithDialer creates a pool using a custom dial function.
+func NewConnPoolWithDialer(dial func(ctx context.Context) (net.Conn, error), opts ConnPoolOptions) *ConnPool {
+	if opts.MaxIdle <= 0 {
+		opts.MaxIdle = 2
+	}
+	if opts.HealthCheck == nil {
+		opts.HealthCheck = probeConn
+	}
+	p := &ConnPool{dial: dial, opts: opts}
+	if opts.MaxActive > 0 {
+		p.slots = make(chan struct{}, opts.MaxActive)
+	}
+	return p
+}
+
+// Get returns an idle healthy connection or dials a new one. When MaxActive
+// is reached it waits until a connection is released or ctx is done.
+func (p *ConnPool) Get(ctx context.Context) (*PooledConn, error) {
+	if p.slots != nil {
+		select {
+		case p.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			p.releaseSlot()
+			return nil, ErrPoolClosed
+		}
+		n := len(p.idle)
+		if n == 0 {
+			p.active++
+			p.mu.Unlock()
+			break
+		}
+		ic := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+
+		// Rejected idle connections are closed while we keep our slot.
+		if p.opts.IdleTimeout > 0 && time.Since(ic.since) > p.opts.IdleTimeout {
+			atomic.AddInt64(&p.expired, 1)
+			ic.conn.Close()
+			continue
+		}
+		if err := p.opts.HealthCheck(ic.conn); err != nil {
+			atomic.AddInt64(&p.healthFailures, 1)
+			ic.conn.Close()
+			continue
+		}
+		p.mu.Lock()
+		p.active++
+		p.mu.Unlock()
+		atomic.AddInt64(&p.hits, 1)
+		return &PooledConn{Conn: ic.conn, pool: p}, nil
+	}
+
+	atomic.AddInt64(&p.misses, 1)
+	conn, err := p.dial(ctx)
+	if err != nil {
+		atomic.AddInt64(&p.dialErrors, 1)
+		p.mu.Lock()
+		p.active--
+		p.mu.Unlock()
+		p.releaseSlot()
+		return nil, err
+	}
+	return &PooledConn{Conn: conn, pool: p}, nil
+}
+
+// put returns conn to the idle list (or closes it when full/unusable).
+func (p *ConnPool) put(conn net.Conn, unusable bool) {
+	p.mu.Lock()
+	if unusable || p.closed || len(p.idle) >= p.opts.MaxIdle {
+		p.mu.Unlock()
+		p.discard(conn)
+		return
+	}
+	p.idle = append(p.idle, idleConn{conn: conn, since: time.Now()})
+	p.active--
+	p.mu.Unlock()
+	p.releaseSlot()
+}
+
+// discard closes conn and releases its slot.
+func (p *ConnPool) discard(conn net.Conn) {
+	conn.Close()
+	p.mu.Lock()
+	p.active--
+	p.mu.Unlock()
+	p.releaseSlot()
+}
+
+func (p *ConnPool) releaseSlot() {
+	if p.slots != nil {
+		select {
+		case <-p.slots:
+		default:
+		}
+	}
+}
+
+// Stats returns a snapshot of the pool counters.
+func (p *ConnPool) Stats() ConnPoolStats {
+	p.mu.Lock()
+	idle, active := len(p.idle), p.active
+	p.mu.Unlock()
+	return ConnPoolStats{
+		Active:         active,
+		Idle:           idle,
+		Hits:           atomic.LoadInt64(&p.hits),
+		Misses:         atomic.LoadInt64(&p.misses),
+		DialErrors:     atomic.LoadInt64(&p.dialErrors),
+		HealthFailures: atomic.LoadInt64(&p.healthFailures),
+		Expired:        atomic.LoadInt64(&p.expired),
+	}
+}
+
+// Close closes all idle connections; connections in use are closed when released.
+func (p *ConnPool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+
+	for _, ic := range idle {
+		ic.conn.Close()
+	}
+	return nil
+}
+
+// PooledConn is a connection borrowed from a ConnPool. Close returns it to the pool.
+type PooledConn struct {
+	net.Conn
+	pool     *ConnPool
+	unusable bool
+	once     sync.Once
+}
+
+// MarkUnusable makes Close discard the connection instead of pooling it
+// (use after protocol errors).
+func (c *PooledConn) MarkUnusable() {
+	c.unusable = true
+}
+
+// Close releases the connection back to its pool.
+func (c *PooledConn) Close() error {
+	c.once.Do(func() {
+		c.Conn.SetDeadline(time.Time{})
+		c.pool.put(c.Conn, c.unusable)
+	})
+	return nil
+}
+
+// probeConn performs a non-blocking read: a timeout means the connection is
+// alive and silent, anything else (EOF, unexpected data) means it is not reusable.
+func probeConn(conn net.Conn) error {
+	if err := conn.SetReadDeadline(time.Now().Add(time.Millisecond)); err != nil {
+		return err
+	}
+	defer conn.SetReadDeadline(time.Time{})
+
+	var one [1]byte
+	_, err := conn.Read(one[:])
+	if err == nil {
+		return errors.New("unexpected data on idle connection")
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+// utility/constructor.go
+package Utility
+
+import (
+	"log"
+	"reflect"
+	"strings"
+)
+
+// Constructor registry
+// --------------------
+// A constructor replaces reflect.New when a registered type is instantiated
+// dynamically (GetInstanceOf, InitializeStructure), so the instance comes out
+// wired to its dependencies instead of zero-valued. Dependencies are named
+// singletons (see RegisterSingleton) resolved at construction time.
+
+// Constructor builds an instance from its resolved dependencies, in the order
+// they were declared. It may return T or *T.
+type Constructor func(deps ...interface{}) interface{}
+
+type constructorEntry struct {
+	fn   Constructor
+	deps []string
+}
+
+// RegisterConstructor registers (or replaces) the constructor of typeName;
+// deps name the singletons passed to it. A missing singleton is passed as nil.
+func (tm *TypeManager) RegisterConstructor(typeName string, fn Constructor, deps ...string) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if tm.constructors == nil {
+		tm.constructors = make(map[string]constructorEntry)
+	}
+	tm.constructors[typeName] = constructorEntry{fn: fn, deps: append([]string(nil), deps...)}
+}
+
+// DeleteConstructor removes the constructor of typeName (no-op if not present).
+func (tm *TypeManager) DeleteConstructor(typeName string) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	delete(tm.constructors, typeName)
+}
+
+// constructor finds the constructor of name and the manager it belongs to,
+// following the same namespace rules as GetType.
+func (tm *TypeManager) constructor(name string) (constructorEntry, *TypeManager, bool) {
+	if ns, rest, qualified := strings.Cut(name, ":"); qualified {
+		if child := tm.findNamespace(ns); child != nil {
+			return child.constructor(rest)
+		}
+		return constructorEntry{}, nil, false
+	}
+	tm.mu.RLock()
+	c, ok := tm.constructors[name]
+	parent := tm.parent
+	tm.mu.RUnlock()
+	if !ok && parent != nil {
+		return parent.constructor(name)
+	}
+	return c, tm, ok
+}
+
+// newValue returns a *t for typeName, built by its constructor when one is
+// registered and returns a usable value, or by reflect.New otherwise.
+func (tm *TypeManager) newValue(typeName string, t reflect.Type) reflect.Value {
+	c, owner, ok := tm.constructor(typeName)
+	if !ok || c.fn == nil {
+		return reflect.New(t)
+	}
+	deps := make([]interface{}, len(c.deps))
+	for i, name := range c.deps {
+		deps[i], _ = owner.GetSingleton(name)
+	}
+
+	v := reflect.ValueOf(c.fn(deps...))
+	switch {
+	case v.IsValid() && v.Type() == reflect.PointerTo(t) && !v.IsNil():
+		return v
+	case v.IsValid() && v.Type() == t:
+		p := reflect.New(t)
+		p.Elem().Set(v)
+		return p
+	}
+	log.Printf("constructor of %s returned %v, using a zero value", typeName, v)
+	return reflect.New(t)
+}
+
+// RegisterConstructor registers a constructor in the default TypeManager.
+func RegisterConstructor(typeName string, fn Constructor, deps ...string) {
+	DefaultTypeManager().RegisterConstructor(typeName, fn, deps...)
+}
@@ -0,0 +1,150 @@
+// utility/conventions.go
+package Utility
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Naming conventions
+// ------------------
+// Dynamic entities carry their metadata under well-known names: the
+// registered type ("TYPENAME"), the identity ("UUID"), the schema version
+// ("TYPEVERSION"), and fields holding references to other entities start
+// with "M_". The same names are used as map keys and as struct field names.
+// Codebases with their own conventions set them per TypeManager; namespaces
+// inherit the conventions of their parent:
+//
+//	SetConventions(Conventions{TypeKey: "_type", IDKey: "Id", RefPrefix: "Ref"})
+//
+// An entity follows the conventions of the manager its type is registered
+// in; data whose type isn't known yet is read with those of the default
+// TypeManager.
+
+// Conventions names the metadata keys of dynamic entities.
+type Conventions struct {
+	TypeKey    string // default "TYPENAME"
+	IDKey      string // default "UUID"
+	VersionKey string // default "TYPEVERSION"
+	RefPrefix  string // default "M_"
+}
+
+// DefaultConventions returns the conventions used unless configured.
+func DefaultConventions() Conventions {
+	return Conventions{TypeKey: "TYPENAME", IDKey: "UUID", VersionKey: "TYPEVERSION", RefPrefix: "M_"}
+}
+
+// SetConventions sets the conventions of tm and of the namespaces that don't
+// set their own. Empty fields keep their default.
+func (tm *TypeManager) SetConventions(c Conventions) {
+	d := DefaultConventions()
+	if c.TypeKey == "" {
+		c.TypeKey = d.TypeKey
+	}
+	if c.IDKey == "" {
+		c.IDKey = d.IDKey
+	}
+	if c.VersionKey == "" {
+		c.VersionKey = d.VersionKey
+	}
+	if c.RefPrefix == "" {
+		c.RefPrefix = d.RefPrefix
+	}
+	tm.mu.Lock()
+	tm.conventions = &c
+	tm.mu.Unlock()
+}
+
+// Conventions returns the conventions in effect for tm.
+func (tm *TypeManager) Conventions() Conventions {
+	tm.mu.RLock()
+	c, parent := tm.conventions, tm.parent
+	tm.mu.RUnlock()
+	switch {
+	case c != nil:
+		return *c
+	case parent != nil:
+		return parent.Conventions()
+	}
+	return DefaultConventions()
+}
+
+// SetConventions sets the conventions of the default TypeManager.
+func SetConventions(c Conventions) { DefaultTypeManager().SetConventions(c) }
+
+// conventions returns the conventions of the default TypeManager.
+func conventions() Conventions { return DefaultTypeManager().Conventions() }
+
+// conventionsOf returns the conventions of the manager the type name
+// resolves in, following namespaces and parents as GetType does, or those
+// of tm when it isn't registered.
+func (tm *TypeManager) conventionsOf(name string) Conventions {
+	if ns, rest, qualified := strings.Cut(name, ":"); qualified {
+		if child := tm.findNamespace(ns); child != nil {
+			return child.conventionsOf(rest)
+		}
+		return tm.Conventions()
+	}
+	for m := tm; m != nil; m = m.parent {
+		m.mu.RLock()
+		_, ok := m.typeRegistry[name]
+		m.mu.RUnlock()
+		if ok {
+			return m.Conventions()
+		}
+	}
+	return tm.Conventions()
+}
+
+var (
+	typeOwners sync.Map // reflect.Type -> typeOwner
+	typeGen    uint64   // bumped when a type is registered or deleted anywhere
+)
+
+type typeOwner struct {
+	tm  *TypeManager // nil when the type isn't registered
+	gen uint64
+}
+
+// conventionsOfType returns the conventions of the manager that registered
+// t (or the type t points to): the default TypeManager or one of its
+// namespaces. Unregistered types get those of the default TypeManager.
+func conventionsOfType(t reflect.Type) Conventions {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return conventions()
+	}
+	gen := atomic.LoadUint64(&typeGen)
+	var owner *TypeManager
+	if o, ok := typeOwners.Load(t); ok && o.(typeOwner).gen == gen {
+		owner = o.(typeOwner).tm
+	} else {
+		owner = DefaultTypeManager().registrantOf(t)
+		typeOwners.Store(t, typeOwner{tm: owner, gen: gen})
+	}
+	if owner == nil {
+		return conventions()
+	}
+	return owner.Conventions()
+}
+
+// registrantOf returns tm when it registered t, else the first of its
+// namespaces, in name order, that did, or nil.
+func (tm *TypeManager) registrantOf(t reflect.Type) *TypeManager {
+	if _, ok := tm.nameOfType(t); ok {
+		return tm
+	}
+	for _, name := range tm.Namespaces() {
+		tm.mu.RLock()
+		child := tm.namespaces[name]
+		tm.mu.RUnlock()
+		if owner := child.registrantOf(t); owner != nil {
+			return owner
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// utility/converter.go
+package Utility
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// FieldConverter turns a raw (usually decoded JSON) value into a value that
+// can be assigned to a struct field of the registered target type.
+type FieldConverter func(value interface{}) (interface{}, error)
+
+// converterKey identifies a converter by source and target type. A nil source
+// type acts as a wildcard matching any input.
+type converterKey struct {
+	from reflect.Type
+	to   reflect.Type
+}
+
+// RegisterFieldConverter registers a converter from one type to another.
+// Pass a nil fromType to accept any input type for toType.
+func (tm *TypeManager) RegisterFieldConverter(fromType, toType reflect.Type, fn FieldConverter) {
+	if toType == nil || fn == nil {
+		return
+	}
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.converters[converterKey{fromType, toType}] = fn
+	atomic.AddUint64(&tm.convGen, 1)
+}
+
+// GetFieldConverter returns the converter for (fromType → toType), falling
+// back to a wildcard converter registered for toType, then to the built-in
+// one for time.Time, *time.Time and time.Duration.
+func (tm *TypeManager) GetFieldConverter(fromType, toType reflect.Type) (FieldConverter, bool) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	if fn, ok := tm.converters[converterKey{fromType, toType}]; ok {
+		return fn, true
+	}
+	if fn, ok := tm.converters[converterKey{nil, toType}]; ok {
+		return fn, true
+	}
+	fn, ok := builtinFieldConverters[toType]
+	return fn, ok
+}
+
+// builtinFieldConverters populate the time fields that JSON can't carry
+// natively; registered converters take precedence.
+var builtinFieldConverters = map[reflect.Type]FieldConverter{
+	timeType: func(v interface{}) (interface{}, error) { return ToTimeE(v) },
+	reflect.PointerTo(timeType): func(v interface{}) (interface{}, error) {
+		t, err := ToTimeE(v)
+		if err != nil || t.IsZero() {
+			return (*time.Time)(nil), err
+		}
+		return &t, nil
+	},
+	reflect.TypeOf(time.Duration(0)): func(v interface{}) (interface{}, error) { return ToDurationE(v) },
+}
+
+// DeleteFieldConverter removes a converter (no-op if not present).
+func (tm *TypeManager) DeleteFieldConverter(fromType, toType reflect.Type) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	delete(tm.converters, converterKey{fromType, toType})
+	atomic.AddUint64(&tm.convGen, 1)
+}
+
+// RegisterFieldConverter registers a converter with the default TypeManager.
+//
+//	RegisterFieldConverter(reflect.TypeOf(""), reflect.TypeOf(time.Time{}),
+//		func(v interface{}) (interface{}, error) { return time.Parse(time.RFC3339, v.(string)) })
+func RegisterFieldConverter(fromType, toType reflect.Type, fn FieldConverter) {
+	DefaultTypeManager().RegisterFieldConverter(fromType, toType, fn)
+}
+
+// convertFieldValue applies a registered converter for value → t, if any.
+// The boolean reports whether a converter was found.
+func convertFieldValue(t reflect.Type, value interface{}) (reflect.Value, bool, error) {
+	if value == nil {
+		return reflect.Value{}, false, nil
+	}
+	fn, ok := DefaultTypeManager().GetFieldConverter(reflect.TypeOf(value), t)
+	if !ok {
+		return reflect.Value{}, false, nil
+	}
+	out, err := fn(value)
+	if err != nil {
+		return reflect.Value{}, true, err
+	}
+	rv := reflect.ValueOf(out)
+	if !rv.IsValid() {
+		return reflect.Zero(t), true, nil
+	}
+	if rv.Type() != t {
+		if !rv.CanConvert(t) {
+			return reflect.Value{}, true, fmt.Errorf("converter returned %v, not assignable to %v", rv.Type(), t)
+		}
+		rv = rv.Convert(t)
+	}
+	return rv, true, nil
+}
+
+// setConvertedField sets dst from a registered converter when one matches.
+// It returns true when the converter handled the value (successfully or not).
+func setConvertedField(st *initState, dst reflect.Value, fieldName string, value interface{}) bool {
+	fv, ok, err := convertFieldValue(dst.Type(), value)
+	if !ok {
+		return false
+	}
+	if err != nil {
+		log.Printf("initializeStructureFieldValue: converter for field %s failed: %v\n", fieldName, err)
+		st.fail("converter failed: %v", err)
+		return true
+	}
+	dst.Set(fv)
+	return true
+}
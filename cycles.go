@@ -0,0 +1,407 @@
+// utility/cycles.go
+package Utility
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Cycle-safe serialization support
+// --------------------------------
+// gob follows pointers blindly, so a parent/child graph never terminates.
+// Before encoding, ToBytes walks the value: every Referenceable pointer that
+// was already visited is replaced (in a copy) by a stub carrying only its
+// UUID. After decoding, FromBytes links stubs back to the full node with the
+// same UUID, or to whatever a caller-supplied resolver returns.
+
+var referenceableType = reflect.TypeOf((*Referenceable)(nil)).Elem()
+
+// ReferenceResolver maps a UUID found in a decoded graph to the instance that
+// should be used for it; returning nil keeps the in-graph node.
+type ReferenceResolver func(uuid string) interface{}
+
+type visitKey struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// graphUUID returns the UUID of a non-nil Referenceable pointer, or "".
+func graphUUID(v reflect.Value) string {
+	if v.Kind() != reflect.Ptr || v.IsNil() || !v.Type().Implements(referenceableType) {
+		return ""
+	}
+	return v.Interface().(Referenceable).GetUUID()
+}
+
+// sortedMapKeys returns the keys of a map value in a stable order, so the
+// pre-encode and post-decode walks see entries in the same sequence.
+func sortedMapKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+	return keys
+}
+
+// ---------------------------
+// Encode side
+// ---------------------------
+
+type cycleScanner struct {
+	visited   map[visitKey]bool
+	onPath    map[visitKey]bool
+	needsCopy bool
+}
+
+// scan reports an error for cycles that cannot be broken (no UUID to substitute)
+// and sets needsCopy when a Referenceable node is reached more than once.
+func (s *cycleScanner) scan(v reflect.Value, path string) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		key := visitKey{v.Pointer(), v.Type()}
+		if s.visited[key] {
+			if graphUUID(v) != "" {
+				s.needsCopy = true
+				return nil
+			}
+			if s.onPath[key] {
+				return fmt.Errorf("cycle detected at %s (type %v is not Referenceable)", path, v.Type())
+			}
+			return nil // shared, acyclic: gob simply duplicates it
+		}
+		s.visited[key] = true
+		s.onPath[key] = true
+		defer delete(s.onPath, key)
+		return s.scan(v.Elem(), path)
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return s.scan(v.Elem(), path)
+
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).PkgPath != "" {
+				continue
+			}
+			if err := s.scan(v.Field(i), path+"."+t.Field(i).Name); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := s.scan(v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		for _, k := range sortedMapKeys(v) {
+			if err := s.scan(v.MapIndex(k), fmt.Sprintf("%s[%v]", path, k.Interface())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type cycleBreaker struct {
+	visited map[visitKey]bool
+}
+
+// copy returns a deep copy of v where revisited Referenceable pointers are
+// replaced with UUID-only stubs.
+func (b *cycleBreaker) copy(v reflect.Value) (reflect.Value, error) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v, nil
+		}
+		key := visitKey{v.Pointer(), v.Type()}
+		if b.visited[key] {
+			if uuid := graphUUID(v); uuid != "" {
+				stub := reflect.New(v.Type().Elem())
+				if idKey := conventionsOfType(v.Type()).IDKey; !SetProperty(stub.Interface(), idKey, uuid) {
+					return reflect.Value{}, fmt.Errorf("type %v has no settable %s field", v.Type(), idKey)
+				}
+				return stub, nil
+			}
+		}
+		b.visited[key] = true
+		elem, err := b.copy(v.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		p := reflect.New(v.Type().Elem())
+		p.Elem().Set(elem)
+		return p, nil
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return v, nil
+		}
+		elem, err := b.copy(v.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(elem)
+		return out, nil
+
+	case reflect.Struct:
+		out := reflect.New(v.Type()).Elem()
+		out.Set(v) // carries unexported fields as-is
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).PkgPath != "" {
+				continue
+			}
+			f, err := b.copy(v.Field(i))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.Field(i).Set(f)
+		}
+		return out, nil
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return v, nil
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			e, err := b.copy(v.Index(i))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.Index(i).Set(e)
+		}
+		return out, nil
+
+	case reflect.Array:
+		out := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			e, err := b.copy(v.Index(i))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.Index(i).Set(e)
+		}
+		return out, nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			return v, nil
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for _, k := range sortedMapKeys(v) {
+			e, err := b.copy(v.MapIndex(k))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.SetMapIndex(k, e)
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// breakCycles returns val unchanged when it is safe to encode, a copy with
+// UUID stubs when Referenceable nodes repeat, or an error for unbreakable cycles.
+func breakCycles(val interface{}) (interface{}, error) {
+	if val == nil {
+		return nil, nil
+	}
+	rv := reflect.ValueOf(val)
+	s := &cycleScanner{visited: make(map[visitKey]bool), onPath: make(map[visitKey]bool)}
+	if err := s.scan(rv, "$"); err != nil {
+		return nil, err
+	}
+	if !s.needsCopy {
+		return val, nil
+	}
+	b := &cycleBreaker{visited: make(map[visitKey]bool)}
+	cp, err := b.copy(rv)
+	if err != nil {
+		return nil, err
+	}
+	return cp.Interface(), nil
+}
+
+// ---------------------------
+// Decode side
+// ---------------------------
+
+// isReferenceStub reports whether the struct behind ptr only carries its UUID
+// (and possibly TYPENAME) — the shape produced by breakCycles.
+func isReferenceStub(ptr reflect.Value) bool {
+	v := ptr.Elem()
+	if v.Kind() != reflect.Struct {
+		return false
+	}
+	t := v.Type()
+	c := conventionsOfType(t)
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Name
+		if t.Field(i).PkgPath != "" || name == c.IDKey || name == c.TypeKey {
+			continue
+		}
+		if !v.Field(i).IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+type referenceLinker struct {
+	canonical map[string]reflect.Value
+	visited   map[visitKey]bool
+	resolve   ReferenceResolver
+}
+
+// index records, for every UUID, the first fully populated node.
+func (l *referenceLinker) index(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return
+		}
+		key := visitKey{v.Pointer(), v.Type()}
+		if l.visited[key] {
+			return
+		}
+		l.visited[key] = true
+		if uuid := graphUUID(v); uuid != "" {
+			if cur, ok := l.canonical[uuid]; !ok || (isReferenceStub(cur) && !isReferenceStub(v)) {
+				l.canonical[uuid] = v
+			}
+		}
+		l.index(v.Elem())
+	case reflect.Interface:
+		if !v.IsNil() {
+			l.index(v.Elem())
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" {
+				l.index(v.Field(i))
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			l.index(v.Index(i))
+		}
+	case reflect.Map:
+		for _, k := range sortedMapKeys(v) {
+			l.index(v.MapIndex(k))
+		}
+	}
+}
+
+// target returns the node that should replace ptr, if any.
+func (l *referenceLinker) target(ptr reflect.Value) (reflect.Value, bool) {
+	uuid := graphUUID(ptr)
+	if uuid == "" {
+		return reflect.Value{}, false
+	}
+	if l.resolve != nil {
+		if r := l.resolve(uuid); r != nil {
+			rv := reflect.ValueOf(r)
+			if rv.Type().AssignableTo(ptr.Type()) {
+				return rv, rv.Pointer() != ptr.Pointer()
+			}
+		}
+	}
+	if c, ok := l.canonical[uuid]; ok && c.Type() == ptr.Type() && c.Pointer() != ptr.Pointer() {
+		return c, true
+	}
+	return reflect.Value{}, false
+}
+
+// link replaces duplicate/stub pointers in settable positions below v.
+func (l *referenceLinker) link(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return
+		}
+		if t, ok := l.target(v); ok && v.CanSet() {
+			v.Set(t)
+			return
+		}
+		key := visitKey{v.Pointer(), v.Type()}
+		if l.visited[key] {
+			return
+		}
+		l.visited[key] = true
+		l.link(v.Elem())
+	case reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		elem := v.Elem()
+		if elem.Kind() == reflect.Ptr {
+			if t, ok := l.target(elem); ok && v.CanSet() {
+				v.Set(t)
+				return
+			}
+			l.link(elem)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" {
+				l.link(v.Field(i))
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			l.link(v.Index(i))
+		}
+	case reflect.Map:
+		for _, k := range sortedMapKeys(v) {
+			e := v.MapIndex(k)
+			if e.Kind() == reflect.Ptr || e.Kind() == reflect.Interface {
+				inner := e
+				if inner.Kind() == reflect.Interface && !inner.IsNil() {
+					inner = inner.Elem()
+				}
+				if t, ok := l.target(inner); ok {
+					v.SetMapIndex(k, t)
+					continue
+				}
+			}
+			// map values aren't addressable: relink a copy and store it back
+			cp := reflect.New(e.Type()).Elem()
+			cp.Set(e)
+			l.link(cp)
+			v.SetMapIndex(k, cp)
+		}
+	}
+}
+
+// relinkReferences rewires UUID stubs and duplicates in a decoded value.
+func relinkReferences(decoded interface{}, resolve ReferenceResolver) error {
+	rv := reflect.ValueOf(decoded)
+	if !rv.IsValid() {
+		return nil
+	}
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("relinkReferences: expected a non-nil pointer")
+	}
+	l := &referenceLinker{canonical: make(map[string]reflect.Value), visited: make(map[visitKey]bool), resolve: resolve}
+	l.index(rv)
+	if len(l.canonical) == 0 {
+		return nil
+	}
+	l.visited = make(map[visitKey]bool)
+	l.link(rv.Elem())
+	return nil
+}
@@ -0,0 +1,172 @@
+// utility/dag.go
+package Utility
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Dependency graphs
+// -----------------
+// DAG keeps nodes in insertion order so every result (sort, levels, cycle)
+// is deterministic. AddEdge(a, b) means a must come before b (b depends on a).
+
+// ErrCycle is matched (errors.Is) by every *CycleError.
+var ErrCycle = errors.New("dependency cycle")
+
+// CycleError reports a cycle; Cycle starts and ends with the same node.
+type CycleError[K comparable] struct {
+	Cycle []K
+}
+
+func (e *CycleError[K]) Error() string {
+	parts := make([]string, len(e.Cycle))
+	for i, k := range e.Cycle {
+		parts[i] = fmt.Sprint(k)
+	}
+	return "dependency cycle: " + strings.Join(parts, " -> ")
+}
+
+// Is makes errors.Is(err, ErrCycle) true.
+func (e *CycleError[K]) Is(target error) bool { return target == ErrCycle }
+
+// DAG is a directed graph of comparable keys. It is not safe for concurrent use.
+type DAG[K comparable] struct {
+	order []K
+	out   map[K][]K
+	in    map[K]int
+}
+
+// NewDAG creates an empty graph.
+func NewDAG[K comparable]() *DAG[K] {
+	return &DAG[K]{out: make(map[K][]K), in: make(map[K]int)}
+}
+
+// AddNode adds k (no-op if present).
+func (g *DAG[K]) AddNode(k K) {
+	if _, ok := g.in[k]; ok {
+		return
+	}
+	g.order = append(g.order, k)
+	g.in[k] = 0
+}
+
+// AddEdge records that from must come before to, adding missing nodes.
+// Duplicate edges are ignored.
+func (g *DAG[K]) AddEdge(from, to K) {
+	g.AddNode(from)
+	g.AddNode(to)
+	for _, k := range g.out[from] {
+		if k == to {
+			return
+		}
+	}
+	g.out[from] = append(g.out[from], to)
+	g.in[to]++
+}
+
+// Nodes returns the nodes in insertion order.
+func (g *DAG[K]) Nodes() []K {
+	return append([]K(nil), g.order...)
+}
+
+// Successors returns the nodes that must come after k.
+func (g *DAG[K]) Successors(k K) []K {
+	return append([]K(nil), g.out[k]...)
+}
+
+// FindCycle returns a cycle (first node repeated at the end), or nil.
+func (g *DAG[K]) FindCycle() []K {
+	const (
+		white = iota
+		grey
+		black
+	)
+	color := make(map[K]int, len(g.order))
+	var stack []K
+	var cycle []K
+
+	var visit func(k K) bool
+	visit = func(k K) bool {
+		color[k] = grey
+		stack = append(stack, k)
+		for _, next := range g.out[k] {
+			switch color[next] {
+			case grey:
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == next {
+						cycle = append(append([]K(nil), stack[i:]...), next)
+						return true
+					}
+				}
+			case white:
+				if visit(next) {
+					return true
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		color[k] = black
+		return false
+	}
+	for _, k := range g.order {
+		if color[k] == white && visit(k) {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// Levels groups nodes so every node's predecessors are in earlier levels;
+// nodes of one level can be processed in parallel.
+func (g *DAG[K]) Levels() ([][]K, error) {
+	in := make(map[K]int, len(g.in))
+	for k, n := range g.in {
+		in[k] = n
+	}
+	var current []K
+	for _, k := range g.order {
+		if in[k] == 0 {
+			current = append(current, k)
+		}
+	}
+
+	var levels [][]K
+	done := 0
+	for len(current) > 0 {
+		levels = append(levels, current)
+		done += len(current)
+		ready := make(map[K]bool)
+		for _, k := range current {
+			for _, next := range g.out[k] {
+				if in[next]--; in[next] == 0 {
+					ready[next] = true
+				}
+			}
+		}
+		current = nil
+		for _, k := range g.order {
+			if ready[k] {
+				current = append(current, k)
+			}
+		}
+	}
+	if done != len(g.order) {
+		return nil, &CycleError[K]{Cycle: g.FindCycle()}
+	}
+	return levels, nil
+}
+
+// TopologicalSort returns every node after all of its predecessors.
+func (g *DAG[K]) TopologicalSort() ([]K, error) {
+	levels, err := g.Levels()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]K, 0, len(g.order))
+	for _, l := range levels {
+		out = append(out, l...)
+	}
+	return out, nil
+}
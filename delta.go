@@ -0,0 +1,276 @@
+// utility/delta.go
+package Utility
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Delta archives
+// --------------
+// CreateDelta compares two directory trees file by file and writes a tar.gz
+// holding a manifest plus only the added or changed files. ApplyDelta checks
+// that the target still matches the base the delta was made from, stages the
+// new files and applies everything through a FileTransaction, so a failed
+// update leaves the directory untouched.
+
+const deltaManifestName = "DELTA_MANIFEST.json"
+
+// DeltaManifest describes the content of a delta archive. Paths are
+// slash-separated and relative to the directory root.
+type DeltaManifest struct {
+	Version    int               `json:"version"`
+	Added      []string          `json:"added,omitempty"`
+	Changed    []string          `json:"changed,omitempty"`
+	Removed    []string          `json:"removed,omitempty"`
+	BaseHashes map[string]string `json:"baseHashes,omitempty"` // sha256 of changed/removed files before the update
+	NewHashes  map[string]string `json:"newHashes,omitempty"`  // sha256 of added/changed files after the update
+}
+
+// ErrDeltaBaseMismatch is returned when the target directory differs from
+// the base the delta was created against.
+var ErrDeltaBaseMismatch = errors.New("delta base does not match target directory")
+
+// hashTree returns slash-relative path -> sha256 for every regular file of dir.
+func hashTree(dir string) (map[string]string, error) {
+	hashes := make(map[string]string)
+	if dir == "" {
+		return hashes, nil
+	}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		sum, err := fileSHA256(p)
+		if err != nil {
+			return err
+		}
+		hashes[filepath.ToSlash(rel)] = sum
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return hashes, nil
+	}
+	return hashes, err
+}
+
+func fileSHA256(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// checkRelPath returns an error unless p, a slash-separated path from an
+// archive manifest, stays under the directory it is joined to: it must be
+// relative and have no ".." element.
+func checkRelPath(p string) error {
+	if p == "" || path.IsAbs(p) || filepath.IsAbs(filepath.FromSlash(p)) || filepath.VolumeName(filepath.FromSlash(p)) != "" {
+		return &GuardError{Field: "path", Reason: fmt.Sprintf("%q is not a relative path", p)}
+	}
+	for _, elem := range strings.FieldsFunc(p, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if elem == ".." {
+			return &GuardError{Field: "path", Reason: fmt.Sprintf("%q escapes the root", p)}
+		}
+	}
+	return nil
+}
+
+// CreateDelta writes to w a delta turning oldDir into newDir. An empty or
+// missing oldDir produces a full archive.
+func CreateDelta(oldDir, newDir string, w io.Writer) (manifest *DeltaManifest, err error) {
+	defer endOperation(startOperation("archive.delta.create", map[string]interface{}{"old": oldDir, "new": newDir}), &err)
+
+	oldHashes, err := hashTree(oldDir)
+	if err != nil {
+		return nil, err
+	}
+	newHashes, err := hashTree(newDir)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest = &DeltaManifest{Version: 1, BaseHashes: map[string]string{}, NewHashes: map[string]string{}}
+	for p, sum := range newHashes {
+		old, existed := oldHashes[p]
+		switch {
+		case !existed:
+			manifest.Added = append(manifest.Added, p)
+		case old != sum:
+			manifest.Changed = append(manifest.Changed, p)
+			manifest.BaseHashes[p] = old
+		default:
+			continue
+		}
+		manifest.NewHashes[p] = sum
+	}
+	for p, sum := range oldHashes {
+		if _, ok := newHashes[p]; !ok {
+			manifest.Removed = append(manifest.Removed, p)
+			manifest.BaseHashes[p] = sum
+		}
+	}
+	sort.Strings(manifest.Added)
+	sort.Strings(manifest.Changed)
+	sort.Strings(manifest.Removed)
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: deltaManifestName, Mode: 0644, Size: int64(len(data))}); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return nil, err
+	}
+
+	for _, p := range append(append([]string(nil), manifest.Added...), manifest.Changed...) {
+		if err := addFileToTar(tw, filepath.Join(newDir, filepath.FromSlash(p)), "files/"+p); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, gz.Close()
+}
+
+func addFileToTar(tw *tar.Writer, src, name string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// ApplyDelta applies a delta produced by CreateDelta to dir.
+func ApplyDelta(dir string, delta io.Reader) (err error) {
+	defer endOperation(startOperation("archive.delta.apply", map[string]interface{}{"dir": dir}), &err)
+
+	gz, err := gzip.NewReader(delta)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	hdr, err := tr.Next()
+	if err != nil {
+		return fmt.Errorf("read delta manifest: %w", err)
+	}
+	if hdr.Name != deltaManifestName {
+		return errors.New("delta archive does not start with " + deltaManifestName)
+	}
+	var manifest DeltaManifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return fmt.Errorf("read delta manifest: %w", err)
+	}
+
+	paths := append(append(append([]string(nil), manifest.Added...), manifest.Changed...), manifest.Removed...)
+	for p := range manifest.BaseHashes {
+		paths = append(paths, p)
+	}
+	for p := range manifest.NewHashes {
+		paths = append(paths, p)
+	}
+	for _, p := range paths {
+		if err := checkRelPath(p); err != nil {
+			return fmt.Errorf("delta manifest: %w", err)
+		}
+	}
+
+	// the target must still be the base the delta was built from
+	for p, want := range manifest.BaseHashes {
+		got, err := fileSHA256(filepath.Join(dir, filepath.FromSlash(p)))
+		if err != nil || got != want {
+			return fmt.Errorf("%w: %s", ErrDeltaBaseMismatch, p)
+		}
+	}
+
+	staging, err := os.MkdirTemp(filepath.Dir(filepath.Clean(dir)), ".delta-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	tx := NewFileTransaction()
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		rel := strings.TrimPrefix(path.Clean(hdr.Name), "files/")
+		want, listed := manifest.NewHashes[rel]
+		if !listed || hdr.Typeflag != tar.TypeReg || checkRelPath(rel) != nil {
+			return fmt.Errorf("unexpected delta entry %q", hdr.Name)
+		}
+		staged := filepath.Join(staging, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(staged), 0755); err != nil {
+			return err
+		}
+		out, err := os.OpenFile(staged, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode).Perm())
+		if err != nil {
+			return err
+		}
+		h := sha256.New()
+		_, err = io.Copy(io.MultiWriter(out, h), tr)
+		out.Close()
+		if err != nil {
+			return err
+		}
+		if hex.EncodeToString(h.Sum(nil)) != want {
+			return fmt.Errorf("delta entry %s is corrupted", rel)
+		}
+		tx.Move(staged, filepath.Join(dir, filepath.FromSlash(rel)))
+	}
+	for _, p := range manifest.Removed {
+		tx.Delete(filepath.Join(dir, filepath.FromSlash(p)))
+	}
+	return tx.Execute()
+}
@@ -0,0 +1,192 @@
+// utility/diff.go
+package Utility
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Text diffs
+// ----------
+// DiffStrings computes a minimal edit script between two string slices
+// (Myers' algorithm) and DiffLines renders the difference between two texts
+// as a unified diff with three lines of context, the format `diff -u` and
+// git print.
+
+// DiffOpKind is the kind of an edit operation.
+type DiffOpKind int
+
+const (
+	DiffEqual DiffOpKind = iota
+	DiffDelete
+	DiffInsert
+)
+
+// String returns the unified-diff prefix of the kind (" ", "-" or "+").
+func (k DiffOpKind) String() string {
+	switch k {
+	case DiffDelete:
+		return "-"
+	case DiffInsert:
+		return "+"
+	}
+	return " "
+}
+
+// DiffOp is one element of an edit script: Text is kept, deleted from the
+// first slice or inserted from the second.
+type DiffOp struct {
+	Kind DiffOpKind
+	Text string
+}
+
+// DiffStrings returns the shortest edit script turning a into b.
+func DiffStrings(a, b []string) []DiffOp {
+	n, m := len(a), len(b)
+	maxD := n + m
+	off := maxD + 1
+	v := make([]int, 2*maxD+3)
+	var trace [][]int
+
+search:
+	for d := 0; d <= maxD; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk the trace back from (n, m), collecting operations in reverse.
+	var ops []DiffOp
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[off+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			ops = append(ops, DiffOp{DiffEqual, a[x]})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, DiffOp{DiffInsert, b[y]})
+		} else {
+			x--
+			ops = append(ops, DiffOp{DiffDelete, a[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x, y = x-1, y-1
+		ops = append(ops, DiffOp{DiffEqual, a[x]})
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// splitLines splits text into lines, ignoring a final newline.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// DiffLines returns the unified diff of texts a and b, or "" when they are
+// equal.
+func DiffLines(a, b string) string {
+	const contextLines = 3
+	ops := DiffStrings(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	for i := 0; i < len(ops); {
+		// Find the next change and extend the hunk while changes are close.
+		start := i
+		for start < len(ops) && ops[start].Kind == DiffEqual {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		end := start
+		for j := start; j < len(ops); j++ {
+			if ops[j].Kind != DiffEqual {
+				end = j + 1
+			} else if j-end >= 2*contextLines {
+				break
+			}
+		}
+		from := start - contextLines
+		if from < i {
+			from = i
+		}
+		if from < 0 {
+			from = 0
+		}
+		to := end + contextLines
+		if to > len(ops) {
+			to = len(ops)
+		}
+
+		// Line numbers of the hunk in a and b.
+		aLine, bLine := 1, 1
+		for _, op := range ops[:from] {
+			if op.Kind != DiffInsert {
+				aLine++
+			}
+			if op.Kind != DiffDelete {
+				bLine++
+			}
+		}
+		aLen, bLen := 0, 0
+		for _, op := range ops[from:to] {
+			if op.Kind != DiffInsert {
+				aLen++
+			}
+			if op.Kind != DiffDelete {
+				bLen++
+			}
+		}
+		if aLen == 0 {
+			aLine--
+		}
+		if bLen == 0 {
+			bLine--
+		}
+
+		if out.Len() == 0 {
+			out.WriteString("--- a\n+++ b\n")
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", aLine, aLen, bLine, bLen)
+		for _, op := range ops[from:to] {
+			out.WriteString(op.Kind.String())
+			out.WriteString(op.Text)
+			out.WriteByte('\n')
+		}
+		i = to
+	}
+	return out.String()
+}
@@ -0,0 +1,75 @@
+// utility/diskusage.go
+package Utility
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"sync/atomic"
+)
+
+// Disk usage
+// ----------
+// DirSize and CountFiles walk a tree with several goroutines, which matters
+// on network and spinning disks where most of the time is spent waiting for
+// directory reads. GetDiskUsage asks the file system holding a path for its
+// capacity. None of them runs du or df.
+
+// DiskUsage is the capacity of a file system, in bytes.
+type DiskUsage struct {
+	Total uint64 `json:"total"`
+	Free  uint64 `json:"free"` // available to unprivileged users
+	Used  uint64 `json:"used"`
+}
+
+// errNoDiskUsage is returned by GetDiskUsage on unsupported platforms.
+var errNoDiskUsage = errors.New("disk usage not available on this platform")
+
+// GetDiskUsage returns the capacity of the file system holding path.
+func GetDiskUsage(path string) (*DiskUsage, error) {
+	return diskUsage(path)
+}
+
+// diskWalkers is the number of goroutines reading directories at once.
+func diskWalkers() int { return 4 * runtime.GOMAXPROCS(0) }
+
+// DirSize returns the total size of the regular files under the directory
+// path. Sizes are apparent sizes, not allocated blocks; symbolic links are
+// not followed and hard-linked files count once per link.
+func DirSize(path string) (size int64, err error) {
+	defer endOperation(startOperation("fs.dirsize", map[string]interface{}{"path": path}), &err)
+	err = walkDirConcurrent(path, diskWalkers(), func(p, _ string, e os.DirEntry) error {
+		if !e.Type().IsRegular() {
+			return nil
+		}
+		fi, err := e.Info()
+		if err != nil {
+			return err
+		}
+		atomic.AddInt64(&size, fi.Size())
+		return nil
+	})
+	return size, err
+}
+
+// CountFiles returns the number of regular files under the directory path
+// that match pattern, a glob matched against the base name or, when it
+// contains '/', against the slash-separated path relative to path. An empty
+// pattern counts every file.
+func CountFiles(path, pattern string) (count int, err error) {
+	defer endOperation(startOperation("fs.countfiles", map[string]interface{}{"path": path, "pattern": pattern}), &err)
+	var globs []*GlobMatcher
+	if pattern != "" {
+		if globs, err = compileGlobs([]string{pattern}); err != nil {
+			return 0, err
+		}
+	}
+	var n int64
+	err = walkDirConcurrent(path, diskWalkers(), func(_, rel string, e os.DirEntry) error {
+		if e.Type().IsRegular() && (globs == nil || matchGlobs(globs, rel)) {
+			atomic.AddInt64(&n, 1)
+		}
+		return nil
+	})
+	return int(n), err
+}
@@ -0,0 +1,20 @@
+// utility/diskusage_darwin.go
+//go:build darwin
+
+package Utility
+
+import "syscall"
+
+// diskUsage reads the capacity of the file system with statfs(2).
+func diskUsage(path string) (*DiskUsage, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return nil, err
+	}
+	bs := uint64(st.Bsize)
+	return &DiskUsage{
+		Total: st.Blocks * bs,
+		Free:  st.Bavail * bs,
+		Used:  (st.Blocks - st.Bfree) * bs,
+	}, nil
+}
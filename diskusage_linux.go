@@ -0,0 +1,23 @@
+// utility/diskusage_linux.go
+//go:build linux
+
+package Utility
+
+import "syscall"
+
+// diskUsage reads the capacity of the file system with statfs(2).
+func diskUsage(path string) (*DiskUsage, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return nil, err
+	}
+	bs := uint64(st.Frsize)
+	if bs == 0 {
+		bs = uint64(st.Bsize)
+	}
+	return &DiskUsage{
+		Total: st.Blocks * bs,
+		Free:  st.Bavail * bs,
+		Used:  (st.Blocks - st.Bfree) * bs,
+	}, nil
+}
@@ -0,0 +1,8 @@
+// utility/diskusage_other.go
+//go:build !linux && !windows && !darwin
+
+package Utility
+
+func diskUsage(path string) (*DiskUsage, error) {
+	return nil, errNoDiskUsage
+}
@@ -0,0 +1,26 @@
+// utility/diskusage_windows.go
+//go:build windows
+
+package Utility
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var procGetDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// diskUsage reads the capacity of the volume with GetDiskFreeSpaceExW.
+func diskUsage(path string) (*DiskUsage, error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	var avail, total, free uint64
+	r, _, err := procGetDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(p)),
+		uintptr(unsafe.Pointer(&avail)), uintptr(unsafe.Pointer(&total)), uintptr(unsafe.Pointer(&free)))
+	if r == 0 {
+		return nil, err
+	}
+	return &DiskUsage{Total: total, Free: avail, Used: total - free}, nil
+}
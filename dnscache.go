@@ -0,0 +1,146 @@
+// utility/dnscache.go
+package Utility
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Caching DNS resolver
+// --------------------
+// The network helpers (GetIpv4, DomainHasIp, Ping) resolve names through a
+// package-level resolver that caches answers for TTL and failures for
+// NegativeTTL, so a busy service doesn't query DNS on every call.
+// ConfigureResolver can point it at specific upstream servers and add
+// fallback servers tried when the primary ones fail.
+
+// ResolverConfig configures the package resolver; zero values use the
+// defaults.
+type ResolverConfig struct {
+	Servers     []string      // upstream "host:port" servers; empty uses the system resolver
+	Fallback    []string      // servers tried when the primary lookup fails, e.g. "8.8.8.8:53"
+	TTL         time.Duration // lifetime of a positive answer (default 1m)
+	NegativeTTL time.Duration // lifetime of a failure (default 5s); < 0 disables negative caching
+	Timeout     time.Duration // per-server timeout (default 5s)
+}
+
+type dnsEntry struct {
+	ips     []net.IP
+	err     error
+	expires time.Time
+}
+
+var (
+	dnsMu     sync.RWMutex
+	dnsConfig ResolverConfig
+	dnsCache  = make(map[string]dnsEntry)
+)
+
+// ConfigureResolver replaces the resolver configuration and flushes the cache.
+func ConfigureResolver(cfg ResolverConfig) {
+	if cfg.TTL <= 0 {
+		cfg.TTL = time.Minute
+	}
+	if cfg.NegativeTTL == 0 {
+		cfg.NegativeTTL = 5 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	cfg.Servers = append([]string(nil), cfg.Servers...)
+	cfg.Fallback = append([]string(nil), cfg.Fallback...)
+
+	dnsMu.Lock()
+	dnsConfig = cfg
+	dnsCache = make(map[string]dnsEntry)
+	dnsMu.Unlock()
+}
+
+func init() { ConfigureResolver(ResolverConfig{}) }
+
+// LookupIP resolves host through the caching resolver. IP literals are
+// returned as is.
+func LookupIP(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	key := strings.ToLower(strings.TrimSuffix(host, "."))
+
+	dnsMu.RLock()
+	entry, ok := dnsCache[key]
+	cfg := dnsConfig
+	dnsMu.RUnlock()
+	if ok && time.Now().Before(entry.expires) {
+		return append([]net.IP(nil), entry.ips...), entry.err
+	}
+
+	ips, err := resolve(ctx, cfg.Servers, cfg.Timeout, host)
+	if err != nil && len(cfg.Fallback) > 0 && ctx.Err() == nil {
+		ips, err = resolve(ctx, cfg.Fallback, cfg.Timeout, host)
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err() // a cancelled lookup says nothing about host
+	}
+
+	ttl := cfg.TTL
+	if err != nil {
+		ttl = cfg.NegativeTTL
+	}
+	if ttl > 0 {
+		dnsMu.Lock()
+		dnsCache[key] = dnsEntry{ips: ips, err: err, expires: time.Now().Add(ttl)}
+		dnsMu.Unlock()
+	}
+	return append([]net.IP(nil), ips...), err
+}
+
+// resolve looks host up with servers (the system resolver when empty).
+func resolve(ctx context.Context, servers []string, timeout time.Duration, host string) ([]net.IP, error) {
+	r := net.DefaultResolver
+	if len(servers) > 0 {
+		r = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				d := net.Dialer{Timeout: timeout}
+				var errs []error
+				for _, server := range servers {
+					conn, err := d.DialContext(ctx, network, server)
+					if err == nil {
+						return conn, nil
+					}
+					errs = append(errs, err)
+				}
+				return nil, errors.Join(errs...)
+			},
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	addrs, err := r.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, len(addrs))
+	for i, a := range addrs {
+		ips[i] = a.IP
+	}
+	return ips, nil
+}
+
+// InvalidateDNS removes host from the cache.
+func InvalidateDNS(host string) {
+	dnsMu.Lock()
+	delete(dnsCache, strings.ToLower(strings.TrimSuffix(host, ".")))
+	dnsMu.Unlock()
+}
+
+// FlushDNSCache empties the cache.
+func FlushDNSCache() {
+	dnsMu.Lock()
+	dnsCache = make(map[string]dnsEntry)
+	dnsMu.Unlock()
+}
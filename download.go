@@ -0,0 +1,380 @@
+// utility/download.go
+package Utility
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Download manager
+// ----------------
+// DownloadFileEx writes to dest+".part" and renames it into place once the
+// transfer (and the optional checksum) succeeded. An interrupted download
+// leaves the .part file behind, and the next attempt, whether a retry or a
+// later call, resumes it with a Range request. The ETag or Last-Modified date
+// of the resource is kept in dest+".part.validator" and sent as If-Range, so
+// a resource changed in between is downloaded again from zero, as it is from
+// servers ignoring Range or giving no validator.
+
+// DownloadOptions configures DownloadFileEx; zero values use the defaults.
+type DownloadOptions struct {
+	Header         http.Header
+	Retry          RetryPolicy // default 5 attempts, 500ms backoff doubling up to 30s
+	Checksum       string      // "sha256:<hex>" (also md5, sha1, sha512); bare hex means sha256
+	BandwidthLimit int64       // bytes per second, 0 = unlimited
+	Progress       *Progress   // counts the bytes on disk (resumed ones included), completed at the end
+}
+
+// transferError is an HTTP failure a retry can't fix (4xx but 408/429).
+type transferError struct{ op, status string }
+
+func (e *transferError) Error() string { return e.op + " failed: " + e.status }
+
+// DownloadFileEx downloads url to dest with resume, retries, checksum
+// verification, bandwidth limiting and progress reporting.
+func DownloadFileEx(ctx context.Context, url, dest string, opts DownloadOptions) (err error) {
+	defer endOperation(startOperation("download", map[string]interface{}{"url": url, "file": dest}), &err)
+
+	if err := RequireNonEmpty(url, "url"); err != nil {
+		return err
+	}
+	if err := RequireNonEmpty(dest, "dest"); err != nil {
+		return err
+	}
+	newHash, want, err := opts.prepare(dest)
+	if err != nil {
+		return err
+	}
+
+	part := dest + ".part"
+	o := NewOptions(WithRetry(opts.Retry))
+	err = o.Do(ctx, func(ctx context.Context) error {
+		return downloadPart(ctx, url, part, &opts)
+	})
+	if err != nil {
+		return err
+	}
+	if err := finishDownload(url, part, dest, newHash, want); err != nil {
+		return err
+	}
+	if opts.Progress != nil {
+		opts.Progress.Done()
+	}
+	return nil
+}
+
+// prepare fills the defaults, parses the checksum and creates the
+// destination directory.
+func (opts *DownloadOptions) prepare(dest string) (newHash func() hash.Hash, want string, err error) {
+	if opts.Checksum != "" {
+		if newHash, want, err = parseChecksum(opts.Checksum); err != nil {
+			return nil, "", err
+		}
+	}
+	opts.Retry = transferRetry(opts.Retry)
+	return newHash, want, os.MkdirAll(filepath.Dir(dest), 0755)
+}
+
+// transferRetry fills the retry defaults shared by downloads and uploads.
+func transferRetry(p RetryPolicy) RetryPolicy {
+	if p.Attempts == 0 {
+		p = RetryPolicy{Attempts: 5, Backoff: 500 * time.Millisecond, MaxBackoff: 30 * time.Second}
+	}
+	if p.RetryIf == nil {
+		p.RetryIf = func(err error) bool {
+			var te *transferError
+			return !errors.As(err, &te)
+		}
+	}
+	return p
+}
+
+// finishDownload verifies the checksum of part and renames it to dest.
+func finishDownload(url, part, dest string, newHash func() hash.Hash, want string) error {
+	if newHash != nil {
+		got, err := hashFile(part, newHash)
+		if err != nil {
+			return err
+		}
+		if got != want {
+			removePart(part) // don't resume a corrupt file
+			return fmt.Errorf("checksum mismatch for %s: got %s, want %s", url, got, want)
+		}
+	}
+	if err := os.Rename(part, dest); err != nil {
+		return err
+	}
+	os.Remove(partValidatorPath(part))
+	return nil
+}
+
+// partValidatorPath is where the validator of the resource held by part is
+// recorded.
+func partValidatorPath(part string) string { return part + ".validator" }
+
+// partValidator returns the validator recorded for part, "" if none.
+func partValidator(part string) string {
+	b, err := os.ReadFile(partValidatorPath(part))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// setPartValidator records the validator of the response part is filled
+// from, or removes the record when the response has none.
+func setPartValidator(part string, h http.Header) error {
+	v := responseValidator(h)
+	if v == "" {
+		if err := os.Remove(partValidatorPath(part)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(partValidatorPath(part), []byte(v), 0644)
+}
+
+// responseValidator returns what to send as If-Range to get the same
+// version: the ETag when strong (weak ones aren't allowed), else the
+// Last-Modified date.
+func responseValidator(h http.Header) string {
+	if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return h.Get("Last-Modified")
+}
+
+// removePart removes a partial download and its validator.
+func removePart(part string) {
+	os.Remove(part)
+	os.Remove(partValidatorPath(part))
+}
+
+// downloadPart fetches the missing tail of part.
+func downloadPart(ctx context.Context, url, part string, opts *DownloadOptions) error {
+	var offset int64
+	validator := partValidator(part)
+	if fi, err := os.Stat(part); err == nil && validator != "" {
+		offset = fi.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range opts.Header {
+		req.Header[k] = v
+	}
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+		req.Header.Set("If-Range", validator)
+	}
+	resp, err := httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	total := int64(0)
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+		start, _, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			removePart(part)
+			return fmt.Errorf("download: got range %q for offset %d, restarting", resp.Header.Get("Content-Range"), offset)
+		}
+		if offset == 0 {
+			flags |= os.O_TRUNC
+			if err := setPartValidator(part, resp.Header); err != nil {
+				return err
+			}
+		} else {
+			flags |= os.O_APPEND
+		}
+		if size > 0 {
+			total = size
+		}
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// The .part file already holds the whole resource.
+		if t := contentRangeTotal(resp.Header.Get("Content-Range")); t == offset {
+			if opts.Progress != nil {
+				opts.Progress.SetTotal(offset)
+				opts.Progress.Set(offset)
+			}
+			return nil
+		}
+		removePart(part)
+		return errors.New("download: stale partial file, restarting")
+	case resp.StatusCode == http.StatusOK:
+		flags |= os.O_TRUNC
+		offset = 0
+		if resp.ContentLength >= 0 {
+			total = resp.ContentLength
+		}
+		if err := setPartValidator(part, resp.Header); err != nil {
+			return err
+		}
+	default:
+		return statusError("download", resp)
+	}
+
+	file, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if opts.Progress != nil {
+		opts.Progress.SetTotal(total)
+		opts.Progress.Set(offset)
+	}
+	w := &downloadWriter{w: file, ctx: ctx, opts: opts, start: time.Now()}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if total > 0 && offset+w.n != total {
+		return fmt.Errorf("download: got %d of %d bytes", offset+w.n, total)
+	}
+	return nil
+}
+
+// downloadWriter counts, throttles and reports the bytes written to w.
+type downloadWriter struct {
+	w     io.Writer
+	ctx   context.Context
+	opts  *DownloadOptions
+	n     int64 // bytes written by this attempt
+	start time.Time
+}
+
+func (d *downloadWriter) Write(b []byte) (int, error) {
+	n, err := d.w.Write(b)
+	d.n += int64(n)
+	if d.opts.Progress != nil {
+		d.opts.Progress.Add(int64(n))
+	}
+	if err != nil {
+		return n, err
+	}
+	if limit := d.opts.BandwidthLimit; limit > 0 {
+		// Sleep until the average rate of this attempt falls back to limit.
+		ahead := time.Duration(float64(d.n)/float64(limit)*float64(time.Second)) - time.Since(d.start)
+		if ahead > 0 {
+			timer := time.NewTimer(ahead)
+			select {
+			case <-d.ctx.Done():
+				timer.Stop()
+				return n, d.ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+	return n, nil
+}
+
+// statusError reports an unexpected status of op, as a non-retryable
+// *transferError for 4xx but 408/429.
+func statusError(op string, resp *http.Response) error {
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return &transferError{op: op, status: resp.Status}
+	}
+	return errors.New(op + " failed: " + resp.Status)
+}
+
+// parseContentRange parses "bytes start-end/size"; size is -1 when given
+// as "*".
+func parseContentRange(h string) (start, end, size int64, ok bool) {
+	spec, found := strings.CutPrefix(strings.TrimSpace(h), "bytes ")
+	if !found {
+		return 0, 0, 0, false
+	}
+	rng, total, found := strings.Cut(spec, "/")
+	if !found {
+		return 0, 0, 0, false
+	}
+	from, to, found := strings.Cut(rng, "-")
+	if !found {
+		return 0, 0, 0, false
+	}
+	var err1, err2 error
+	start, err1 = strconv.ParseInt(from, 10, 64)
+	end, err2 = strconv.ParseInt(to, 10, 64)
+	if err1 != nil || err2 != nil || start < 0 || end < start {
+		return 0, 0, 0, false
+	}
+	size = -1
+	if total != "*" {
+		if size, err1 = strconv.ParseInt(total, 10, 64); err1 != nil || size <= end {
+			return 0, 0, 0, false
+		}
+	}
+	return start, end, size, true
+}
+
+// contentRangeTotal returns the size in "bytes a-b/size", 0 when unknown.
+func contentRangeTotal(h string) int64 {
+	_, size, ok := strings.Cut(h, "/")
+	if !ok {
+		return 0
+	}
+	n, _ := strconv.ParseInt(size, 10, 64)
+	return n
+}
+
+// parseChecksum splits "algo:hex" into a hash constructor and the lowercase digest.
+func parseChecksum(s string) (func() hash.Hash, string, error) {
+	algo, digest, ok := strings.Cut(s, ":")
+	if !ok {
+		algo, digest = "sha256", s
+	}
+	var newHash func() hash.Hash
+	switch strings.ToLower(algo) {
+	case "md5":
+		newHash = md5.New
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return nil, "", &GuardError{Field: "Checksum", Reason: "unsupported algorithm " + algo}
+	}
+	digest = strings.ToLower(strings.TrimSpace(digest))
+	if _, err := hex.DecodeString(digest); err != nil || len(digest) != 2*newHash().Size() {
+		return nil, "", &GuardError{Field: "Checksum", Reason: "invalid " + algo + " digest"}
+	}
+	return newHash, digest, nil
+}
+
+func hashFile(path string, newHash func() hash.Hash) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := newHash()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,255 @@
+// utility/download_parallel.go
+package Utility
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Segmented downloads
+// -------------------
+// DownloadFileParallel splits a large file into ranges fetched over several
+// connections and written in place into a pre-allocated dest+".part". Each
+// segment retries on its own from where it stopped, with If-Range set to the
+// validator seen when probing so every segment comes from the same version.
+// Servers without Range support, and files too small to be worth splitting,
+// go through DownloadFileEx. A .part left by DownloadFileEx for the same
+// version is kept and only the rest is fetched, but unlike DownloadFileEx a
+// failed segmented download is not resumable: its .part file is removed.
+
+const minDownloadSegment = 1 << 20
+
+// DownloadFileParallel downloads url to dest over up to connections parallel
+// range requests (0 uses 4). opts work as for DownloadFileEx; BandwidthLimit
+// applies to the whole download.
+func DownloadFileParallel(ctx context.Context, url, dest string, connections int, opts DownloadOptions) (err error) {
+	if err := RequireNonEmpty(url, "url"); err != nil {
+		return err
+	}
+	if err := RequireNonEmpty(dest, "dest"); err != nil {
+		return err
+	}
+	if connections <= 0 {
+		connections = 4
+	}
+	newHash, want, err := opts.prepare(dest)
+	if err != nil {
+		return err
+	}
+
+	size, validator, ranged, err := probeRangeSupport(ctx, url, opts.Header)
+	if err != nil {
+		return err
+	}
+	part := dest + ".part"
+	var resumed int64 // bytes already in a .part of the same version
+	if v := partValidator(part); v != "" && v == validator {
+		if fi, err := os.Stat(part); err == nil && fi.Size() <= size {
+			resumed = fi.Size()
+		}
+	}
+	if !ranged || size-resumed < 2*minDownloadSegment || connections == 1 {
+		return DownloadFileEx(ctx, url, dest, opts)
+	}
+
+	defer endOperation(startOperation("download", map[string]interface{}{"url": url, "file": dest, "connections": connections}), &err)
+
+	n := int64(connections)
+	if most := (size - resumed) / minDownloadSegment; n > most {
+		n = most
+	}
+	flags := os.O_CREATE | os.O_WRONLY
+	if resumed == 0 {
+		flags |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return err
+	}
+	if err := file.Truncate(size); err != nil {
+		file.Close()
+		removePart(part)
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if opts.Progress != nil {
+		opts.Progress.SetTotal(size)
+		opts.Progress.Set(resumed)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	seg := &segmentedDownload{url: url, file: file, size: size, validator: validator, n: n, opts: &opts}
+	segment := (size - resumed) / n
+	for i := int64(0); i < n; i++ {
+		from, to := resumed+i*segment, resumed+(i+1)*segment-1
+		if i == n-1 {
+			to = size - 1
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := seg.fetch(ctx, from, to); err != nil {
+				errOnce.Do(func() { firstErr = err; cancel() })
+			}
+		}()
+	}
+	wg.Wait()
+
+	if cerr := file.Close(); firstErr == nil {
+		firstErr = cerr
+	}
+	if firstErr != nil {
+		removePart(part)
+		return firstErr
+	}
+	if err := finishDownload(url, part, dest, newHash, want); err != nil {
+		return err
+	}
+	if opts.Progress != nil {
+		opts.Progress.Done()
+	}
+	return nil
+}
+
+// probeRangeSupport asks for the first byte of url and reports the resource
+// size, its validator (see responseValidator) and whether the server honors
+// Range.
+func probeRangeSupport(ctx context.Context, url string, header http.Header) (int64, string, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, "", false, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Range", "bytes=0-0")
+	resp, err := httpClient().Do(req)
+	if err != nil {
+		return 0, "", false, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		size := contentRangeTotal(resp.Header.Get("Content-Range"))
+		return size, responseValidator(resp.Header), size > 0, nil
+	case http.StatusOK:
+		return resp.ContentLength, responseValidator(resp.Header), false, nil
+	}
+	return 0, "", false, statusError("download", resp)
+}
+
+// segmentedDownload is what the segments of one DownloadFileParallel share.
+type segmentedDownload struct {
+	url       string
+	file      *os.File
+	size      int64
+	validator string // sent as If-Range, may be empty
+	n         int64  // number of segments sharing the bandwidth
+	opts      *DownloadOptions
+}
+
+// fetch downloads bytes [from, to] into the file, retrying from the last
+// byte written.
+func (d *segmentedDownload) fetch(ctx context.Context, from, to int64) error {
+	o := NewOptions(WithRetry(d.opts.Retry))
+	return o.Do(ctx, func(ctx context.Context) error {
+		if from > to {
+			return nil
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.url, nil)
+		if err != nil {
+			return err
+		}
+		for k, v := range d.opts.Header {
+			req.Header[k] = v
+		}
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(from, 10)+"-"+strconv.FormatInt(to, 10))
+		if d.validator != "" {
+			req.Header.Set("If-Range", d.validator)
+		}
+		resp, err := httpClient().Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		switch {
+		case resp.StatusCode == http.StatusOK && d.validator != "":
+			return &transferError{op: "download", status: "the resource changed during the download"}
+		case resp.StatusCode == http.StatusOK:
+			return &transferError{op: "download", status: "server ignored the Range request"}
+		case resp.StatusCode != http.StatusPartialContent:
+			return statusError("download", resp)
+		}
+		if start, _, size, ok := parseContentRange(resp.Header.Get("Content-Range")); !ok || start != from || (size >= 0 && size != d.size) {
+			return &transferError{op: "download", status: "unexpected Content-Range " + strconv.Quote(resp.Header.Get("Content-Range"))}
+		}
+
+		w := &segmentWriter{file: d.file, off: from, ctx: ctx, progress: d.opts.Progress, start: time.Now()}
+		if d.opts.BandwidthLimit > 0 {
+			w.limit = d.opts.BandwidthLimit / d.n
+			if w.limit < 1 {
+				w.limit = 1
+			}
+		}
+		_, err = io.Copy(w, io.LimitReader(resp.Body, to-from+1))
+		from = w.off // a retry resumes here
+		if err != nil {
+			return err
+		}
+		if from <= to {
+			return errors.New("download: segment ended early at byte " + strconv.FormatInt(from, 10))
+		}
+		return nil
+	})
+}
+
+// segmentWriter writes at increasing offsets of file, counting and
+// throttling the bytes.
+type segmentWriter struct {
+	file     *os.File
+	off      int64
+	ctx      context.Context
+	progress *Progress // may be nil
+	limit    int64     // bytes per second, 0 = unlimited
+	n        int64     // bytes written by this attempt
+	start    time.Time
+}
+
+func (w *segmentWriter) Write(b []byte) (int, error) {
+	n, err := w.file.WriteAt(b, w.off)
+	w.off += int64(n)
+	w.n += int64(n)
+	if w.progress != nil {
+		w.progress.Add(int64(n))
+	}
+	if err != nil {
+		return n, fmt.Errorf("download: %w", err)
+	}
+	if w.limit > 0 {
+		ahead := time.Duration(float64(w.n)/float64(w.limit)*float64(time.Second)) - time.Since(w.start)
+		if ahead > 0 {
+			timer := time.NewTimer(ahead)
+			select {
+			case <-w.ctx.Done():
+				timer.Stop()
+				return n, w.ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+	return n, nil
+}
@@ -2,10 +2,10 @@
 package Utility
 
 import (
-	"bytes"
 	b64 "encoding/base64"
-	"encoding/gob"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"log"
 	"reflect"
 	"strconv"
@@ -42,62 +42,114 @@ func GetTypeOf(typeName string) reflect.Type {
 	return nil
 }
 
-// GetInstanceOf creates a new *T instance of a registered type name.
-// If the struct has an exported field "TYPENAME", it is set to typeName.
+// GetInstanceOf creates a new *T instance of a registered type name, which may
+// be namespace-qualified ("pluginA:model.User"), using its registered
+// constructor if any. If the struct has an exported field "TYPENAME", it is set to typeName.
 func GetInstanceOf(typeName string) interface{} {
 	if t, ok := DefaultTypeManager().GetType(typeName); ok {
-		instance := reflect.New(t).Interface()
-		SetProperty(instance, "TYPENAME", typeName) // best-effort
+		c := DefaultTypeManager().conventionsOf(typeName)
+		instance := DefaultTypeManager().newValue(typeName, t).Interface()
+		SetProperty(instance, c.TypeKey, typeName) // best-effort
+		if version, ok := DefaultTypeManager().TypeVersion(typeName); ok {
+			SetProperty(instance, c.VersionKey, version)
+		}
 		return instance
 	}
 	return nil
 }
 
 // RegisterType registers a type (by typed nil pointer) with the TypeManager
-// and gob so values can be serialized/deserialized by name.
+// and gob so values can be serialized/deserialized by name. An optional
+// schema version makes it a versioned type (see RegisterTypeVersion): stored
+// maps with an older TYPEVERSION are migrated with RegisterMigration steps.
 //
 //   type Foo struct{}
 //   RegisterType((*Foo)(nil))
-func RegisterType(typedNil interface{}) {
+//   RegisterType((*Foo)(nil), 2)
+func RegisterType(typedNil interface{}, version ...int) {
 	t := reflect.TypeOf(typedNil).Elem()
-	idx := strings.LastIndex(t.PkgPath(), "/")
-	typeName := t.Name()
+	fq := typeNameOf(t)
 
-	var fq string
-	if idx > 0 {
-		fq = t.PkgPath()[idx+1:] + "." + typeName
-	} else {
-		fq = t.PkgPath() + "." + typeName
+	if len(version) > 0 {
+		if err := DefaultTypeManager().RegisterTypeVersion(fq, version[0], typedNil); err != nil {
+			log.Println("RegisterType:", err)
+		}
+		return
 	}
-
 	if _, ok := DefaultTypeManager().GetType(fq); !ok {
 		DefaultTypeManager().RegisterType(fq, t)
-		gob.RegisterName(fq, typedNil)
+		if err := registerGobName(fq, typedNil); err != nil {
+			log.Println("RegisterType:", err)
+		}
+	}
+}
+
+// GetTypesImplementing lists the types of the default TypeManager that
+// implement the interface ifacePtr points to (see TypeManager.GetTypesImplementing).
+func GetTypesImplementing(ifacePtr interface{}) []string {
+	return DefaultTypeManager().GetTypesImplementing(ifacePtr)
+}
+
+// typeNameOf returns the registry name used by RegisterType for t,
+// i.e. the last package path element and the type name ("mypkg.MyType").
+func typeNameOf(t reflect.Type) string {
+	idx := strings.LastIndex(t.PkgPath(), "/")
+	if idx > 0 {
+		return t.PkgPath()[idx+1:] + "." + t.Name()
 	}
+	return t.PkgPath() + "." + t.Name()
 }
 
-// ToBytes serializes any value via gob into a byte slice.
+// ToBytes serializes any value with the default codec (gob unless changed
+// with SetDefaultCodec) into a byte slice.
+// Object graphs with cycles are supported when the repeated nodes are
+// Referenceable: later occurrences are encoded as UUID-only stubs.
 func ToBytes(val interface{}) ([]byte, error) {
-	var buf bytes.Buffer
-	enc := gob.NewEncoder(&buf)
-	err := enc.Encode(val)
-	return buf.Bytes(), err
+	return ToBytesWith(val, DefaultCodec())
+}
+
+// ToBytesWith is ToBytes with an explicit codec.
+func ToBytesWith(val interface{}, codec Codec) ([]byte, error) {
+	safe, err := breakCycles(val)
+	if err != nil {
+		return nil, err
+	}
+	return codec.Encode(safe)
 }
 
-// FromBytes deserializes data into a new instance of typeName if registered;
-// otherwise into a map[string]interface{}.
+// FromBytes deserializes data into a new instance of typeName (optionally
+// namespace-qualified) if registered; otherwise into a map[string]interface{}. UUID stubs written by ToBytes are
+// linked back to the node carrying the same UUID.
 func FromBytes(data []byte, typeName string) (interface{}, error) {
-	buf := bytes.NewBuffer(data)
-	dec := gob.NewDecoder(buf)
+	return FromBytesWithResolver(data, typeName, nil)
+}
+
+// FromBytesWith is FromBytes with an explicit codec.
+func FromBytesWith(data []byte, typeName string, codec Codec) (interface{}, error) {
+	return fromBytes(data, typeName, nil, codec)
+}
 
+// FromBytesWithResolver is FromBytes with a resolver consulted for every
+// Referenceable node, letting callers substitute their own instances
+// (e.g. from an entity cache) for references found in the payload.
+func FromBytesWithResolver(data []byte, typeName string, resolve ReferenceResolver) (interface{}, error) {
+	return fromBytes(data, typeName, resolve, DefaultCodec())
+}
+
+func fromBytes(data []byte, typeName string, resolve ReferenceResolver, codec Codec) (interface{}, error) {
 	if t, ok := DefaultTypeManager().GetType(typeName); ok {
 		v := reflect.New(t).Interface()
-		err := dec.Decode(v)
-		return v, err
+		if err := codec.Decode(data, v); err != nil {
+			return v, err
+		}
+		if err := relinkReferences(v, resolve); err != nil {
+			return v, err
+		}
+		return DefaultTypeManager().migrateValue(typeName, v)
 	}
 
 	v := make(map[string]interface{})
-	err := dec.Decode(&v)
+	err := codec.Decode(data, &v)
 	return v, err
 }
 
@@ -109,7 +161,19 @@ func FromBytes(data []byte, typeName string) (interface{}, error) {
 // with the provided map data. Optionally, setEntity is called for each created
 // nested value (useful for building reference indexes).
 func MakeInstance(typeName string, data map[string]interface{}, setEntity func(interface{})) reflect.Value {
-	value := initializeStructureValue(typeName, data, setEntity)
+	return makeInstance(newInitState(conventions(), InitOptions{}), typeName, data, setEntity)
+}
+
+// MakeInstanceWithOptions is MakeInstance configured by opts; in strict mode
+// it returns an *InitError listing the fields that could not be set.
+func MakeInstanceWithOptions(typeName string, data map[string]interface{}, setEntity func(interface{}), opts InitOptions) (reflect.Value, error) {
+	st := newInitState(conventions(), opts)
+	value := makeInstance(st, typeName, data, setEntity)
+	return value, st.result(typeName)
+}
+
+func makeInstance(st *initState, typeName string, data map[string]interface{}, setEntity func(interface{})) reflect.Value {
+	value := initializeStructureValue(st, typeName, data, setEntity)
 	if setEntity != nil && value.IsValid() {
 		setEntity(value.Interface())
 	}
@@ -118,20 +182,43 @@ func MakeInstance(typeName string, data map[string]interface{}, setEntity func(i
 
 // InitializeStructure builds a single *T from a map containing "TYPENAME".
 func InitializeStructure(data map[string]interface{}, setEntity func(interface{})) (reflect.Value, error) {
+	return initializeStructure(newInitState(conventions(), InitOptions{}), data, setEntity)
+}
+
+// InitializeStructureWithOptions is InitializeStructure configured by opts;
+// in strict mode it returns an *InitError listing the fields that could not
+// be set.
+func InitializeStructureWithOptions(data map[string]interface{}, setEntity func(interface{}), opts InitOptions) (reflect.Value, error) {
+	st := newInitState(conventions(), opts)
+	value, err := initializeStructure(st, data, setEntity)
+	if err != nil {
+		return value, err
+	}
+	return value, st.result(ToString(data[st.conv.TypeKey]))
+}
+
+func initializeStructure(st *initState, data map[string]interface{}, setEntity func(interface{})) (reflect.Value, error) {
 	var value reflect.Value
-	tnAny, hasTN := data["TYPENAME"]
+	tnAny, hasTN := data[st.conv.TypeKey]
 	if !hasTN {
 		return value, errors.New("NotDynamicObject")
 	}
 	tn := ToString(tnAny)
 	if _, ok := DefaultTypeManager().GetType(tn); ok {
-		value = MakeInstance(tn, data, setEntity)
+		data, err := DefaultTypeManager().migrate(tn, data)
+		if err != nil {
+			return value, err
+		}
+		value = makeInstance(st, tn, data, setEntity)
 		if setEntity != nil && value.IsValid() {
 			setEntity(value.Interface())
 		}
 		return value, nil
 	}
 	// If not registered, return the raw map.
+	if st.opts.Strict && st.opts.RequireRegisteredTypes {
+		return value, fmt.Errorf("type %q is not registered", tn)
+	}
 	return reflect.ValueOf(data), nil
 }
 
@@ -153,7 +240,7 @@ func InitializeStructures(data []interface{}, typeName string, setEntity func(in
 	if m, ok := first.(map[string]interface{}); ok {
 		tn := typeName
 		if tn == "" {
-			if v, ok := m["TYPENAME"]; ok {
+			if v, ok := m[conventions().TypeKey]; ok {
 				tn, _ = v.(string)
 			}
 		}
@@ -212,19 +299,32 @@ func InitializeArray(data []interface{}) (reflect.Value, error) {
 
 // initializeStructureValue creates a *T for the registered type and sets fields from data.
 // If the type is not registered, it returns reflect.ValueOf(data).
-func initializeStructureValue(typeName string, data map[string]interface{}, setEntity func(interface{})) reflect.Value {
+func initializeStructureValue(st *initState, typeName string, data map[string]interface{}, setEntity func(interface{})) reflect.Value {
 	t, ok := DefaultTypeManager().GetType(typeName)
 	if !ok {
+		st.unregistered(typeName)
 		return reflect.ValueOf(data)
 	}
-	v := reflect.New(t)
+	outer := st.conv
+	st.conv = DefaultTypeManager().conventionsOf(typeName)
+	defer func() { st.conv = outer }()
+	if migrated, err := DefaultTypeManager().migrate(typeName, data); err != nil {
+		log.Println("initializeStructureValue:", err)
+		st.fail("%v", err)
+	} else {
+		data = migrated
+	}
+	v := DefaultTypeManager().newValue(typeName, t)
+	fields := cachedFields(t)
 
 	for name, raw := range data {
 		if raw == nil {
 			continue
 		}
-		if ft, exist := t.FieldByName(name); exist {
-			initializeStructureFieldValue(v, name, ft.Type, raw, setEntity)
+		if fd, exist := fields[name]; exist {
+			st.field(name, func() { initializeStructureFieldValue(st, v, name, fd.Type, raw, setEntity) })
+		} else {
+			st.skip(name)
 		}
 	}
 	return v
@@ -232,132 +332,327 @@ func initializeStructureValue(typeName string, data map[string]interface{}, setE
 
 // InitializeStructureFieldArrayValue fills a slice with values converted from `values`.
 func InitializeStructureFieldArrayValue(slice reflect.Value, fieldName string, fieldType reflect.Type, values reflect.Value, setEntity func(interface{})) {
+	initializeArrayValue(newInitState(conventions(), InitOptions{}), slice, fieldName, fieldType, values, setEntity)
+}
+
+func initializeArrayValue(st *initState, slice reflect.Value, fieldName string, fieldType reflect.Type, values reflect.Value, setEntity func(interface{})) {
 	for i := 0; i < values.Len(); i++ {
-		v_ := values.Index(i).Interface()
-		if v_ == nil {
-			continue
-		}
+		st.field("["+strconv.Itoa(i)+"]", func() { initializeArrayElement(st, slice, i, fieldName, fieldType, values, setEntity) })
+	}
+}
 
-		switch reflect.TypeOf(v_).String() {
-		case "map[string]interface {}":
-			m := v_.(map[string]interface{})
-			if tn, hasTN := m["TYPENAME"]; hasTN {
-				fv := initializeStructureValue(tn.(string), m, setEntity)
-				if setEntity != nil && fv.IsValid() {
-					setEntity(fv.Interface())
-				}
-				if strings.HasPrefix(fieldName, "M_") {
-					if uuidAny, ok := m["UUID"]; ok {
-						slice.Index(i).Set(reflect.ValueOf(ToString(uuidAny)))
-					}
-				} else {
-					slice.Index(i).Set(fv)
-				}
-			} else {
-				slice.Index(i).Set(reflect.ValueOf(m))
+func initializeArrayElement(st *initState, slice reflect.Value, i int, fieldName string, fieldType reflect.Type, values reflect.Value, setEntity func(interface{})) {
+	v_ := values.Index(i).Interface()
+	if v_ == nil {
+		return
+	}
+
+	switch reflect.TypeOf(v_).String() {
+	case "map[string]interface {}":
+		m := v_.(map[string]interface{})
+		if tn, hasTN := m[st.conv.TypeKey]; hasTN {
+			fv := initializeStructureValue(st, ToString(tn), m, setEntity)
+			if setEntity != nil && fv.IsValid() {
+				setEntity(fv.Interface())
 			}
-		default:
-			if reflect.TypeOf(v_).Kind() == reflect.Slice {
-				slice_ := reflect.MakeSlice(fieldType, reflect.ValueOf(v_).Len(), reflect.ValueOf(v_).Len())
-				InitializeStructureFieldArrayValue(slice_, fieldName, reflect.TypeOf(v_), reflect.ValueOf(v_), setEntity)
-				if slice.Index(i).IsValid() {
-					slice.Index(i).Set(slice_)
-				}
-			} else {
-				fv := InitializeBaseTypeValue(slice.Type().Elem(), v_)
-				if fv.IsValid() {
-					if fv.Type() != slice.Index(i).Type() && fv.CanConvert(slice.Index(i).Type()) {
-						fv = fv.Convert(slice.Index(i).Type())
-					}
-					slice.Index(i).Set(fv)
+			if strings.HasPrefix(fieldName, st.conv.RefPrefix) {
+				if uuidAny, ok := m[st.conv.IDKey]; ok {
+					slice.Index(i).Set(reflect.ValueOf(ToString(uuidAny)))
 				}
+			} else if fv.IsValid() && fv.Type().AssignableTo(slice.Type().Elem()) {
+				slice.Index(i).Set(fv)
+			} else if fv.IsValid() && fv.Kind() == reflect.Ptr && fv.Elem().Type().AssignableTo(slice.Type().Elem()) {
+				slice.Index(i).Set(fv.Elem()) // []T rather than []*T
+			} else if fv.IsValid() {
+				st.fail("cannot hold %v", fv.Type())
 			}
+		} else if ev, ok := initializeTypedValue(st, slice.Type().Elem(), m, fieldName, setEntity); ok {
+			slice.Index(i).Set(ev)
+		} else {
+			st.fail("cannot convert an object to %v", slice.Type().Elem())
+		}
+	default:
+		if slice.Type().Elem().Kind() == reflect.Array {
+			st.set(slice.Index(i), st.baseValue(slice.Type().Elem(), v_))
+		} else if reflect.TypeOf(v_).Kind() == reflect.Slice {
+			slice_ := reflect.MakeSlice(fieldType, reflect.ValueOf(v_).Len(), reflect.ValueOf(v_).Len())
+			initializeArrayValue(st, slice_, fieldName, reflect.TypeOf(v_), reflect.ValueOf(v_), setEntity)
+			if slice.Index(i).IsValid() {
+				st.set(slice.Index(i), slice_)
+			}
+		} else if setConvertedField(st, slice.Index(i), fieldName, v_) {
+			return
+		} else {
+			st.set(slice.Index(i), st.baseValue(slice.Type().Elem(), v_))
 		}
 	}
 }
 
 // initializeStructureFieldValue sets a struct field from an arbitrary value.
-func initializeStructureFieldValue(v reflect.Value, fieldName string, fieldType reflect.Type, fieldValue interface{}, setEntity func(interface{})) {
+func initializeStructureFieldValue(st *initState, v reflect.Value, fieldName string, fieldType reflect.Type, fieldValue interface{}, setEntity func(interface{})) {
+	field := settableField(v.Elem(), fieldName)
+	if !field.IsValid() || !field.CanSet() {
+		st.fail("field is not settable")
+		return
+	}
+
+	// Registered converters take precedence over the built-in rules.
+	if fd, ok := lookupField(v.Elem().Type(), fieldName); ok && fd.HasConverter && fd.Type == fieldType && field.IsValid() {
+		if setConvertedField(st, field, fieldName, fieldValue) {
+			return
+		}
+	}
+
 	switch fieldType.Kind() {
 
 	case reflect.Slice:
-		// []byte special-case (often base64 in JSON payloads)
-		rt := reflect.TypeOf(fieldValue)
-		if rt != nil && (rt.String() == "[]uint8" || rt.String() == "[]byte") {
-			fv := InitializeBaseTypeValue(rt, fieldValue)
-			val := fv.Bytes()
-			// try base64 decode if it looks like a base64-encoded string in a []byte shell
-			if str := string(val); len(str) > 0 {
-				if decoded, err := b64.StdEncoding.DecodeString(str); err == nil {
-					val = decoded
+		// []byte from a string or bytes, base64 or not (see Base64Mode)
+		if fieldType.Elem().Kind() == reflect.Uint8 {
+			switch fieldValue.(type) {
+			case string, []byte:
+				b, err := bytesFieldValue(fieldValue, st.base64Mode(v.Elem().Type(), fieldName))
+				if err != nil {
+					st.fail("%v", err)
+					return
 				}
+				field.Set(reflect.ValueOf(b).Convert(fieldType))
+				return
 			}
-			v.Elem().FieldByName(fieldName).Set(reflect.ValueOf(val))
-			return
 		}
 		// Generic slice
 		rvv := reflect.ValueOf(fieldValue)
 		if rvv.IsValid() && rvv.Kind() == reflect.Slice {
 			slice := reflect.MakeSlice(fieldType, rvv.Len(), rvv.Len())
-			InitializeStructureFieldArrayValue(slice, fieldName, fieldType, rvv, setEntity)
+			initializeArrayValue(st, slice, fieldName, fieldType, rvv, setEntity)
 			if slice.IsValid() {
-				v.Elem().FieldByName(fieldName).Set(slice)
+				field.Set(slice)
 			}
+		} else {
+			st.fail("expected a list, got %T", fieldValue)
 		}
 
-	case reflect.Struct:
-		if m, ok := fieldValue.(map[string]interface{}); ok {
-			if fv, _ := InitializeStructure(m, setEntity); fv.IsValid() {
-				v.Elem().FieldByName(fieldName).Set(fv.Elem())
+	case reflect.Struct, reflect.Ptr:
+		m, ok := fieldValue.(map[string]interface{})
+		if !ok {
+			if fv, ok := initializeTypedValue(st, fieldType, fieldValue, fieldName, setEntity); ok {
+				field.Set(fv)
+			} else {
+				st.fail("expected an object, got %T", fieldValue)
+			}
+		} else if _, hasTN := m[st.conv.TypeKey]; !hasTN {
+			if fv, ok := initializeTypedValue(st, fieldType, m, fieldName, setEntity); ok {
+				field.Set(fv)
+			} else {
+				st.fail("cannot convert an object to %v", fieldType)
 			}
+		} else if fv, _ := initializeStructure(st, m, setEntity); fieldType.Kind() == reflect.Struct {
+			st.set(field, indirectValue(fv))
+		} else {
+			st.set(field, fv)
 		}
 
-	case reflect.Ptr:
-		if m, ok := fieldValue.(map[string]interface{}); ok {
-			if fv, _ := InitializeStructure(m, setEntity); fv.IsValid() {
-				v.Elem().FieldByName(fieldName).Set(fv)
-			}
+	case reflect.Array:
+		rvv := reflect.ValueOf(fieldValue)
+		if isBaseKind(fieldType.Elem().Kind()) || fieldType.Elem().Kind() == reflect.Array ||
+			(rvv.Kind() != reflect.Slice && rvv.Kind() != reflect.Array) {
+			st.set(field, st.baseValue(fieldType, fieldValue))
+		} else if rvv.Len() != fieldType.Len() {
+			st.fail("expected %d elements, got %d", fieldType.Len(), rvv.Len())
+		} else {
+			// Arrays of objects go through the slice initializer.
+			tmp := reflect.MakeSlice(reflect.SliceOf(fieldType.Elem()), rvv.Len(), rvv.Len())
+			initializeArrayValue(st, tmp, fieldName, tmp.Type(), rvv, setEntity)
+			reflect.Copy(field, tmp)
 		}
 
 	case reflect.Interface:
-		initializeStructureFieldValue(v, fieldName, reflect.TypeOf(fieldValue), fieldValue, setEntity)
+		initializeStructureFieldValue(st, v, fieldName, reflect.TypeOf(fieldValue), fieldValue, setEntity)
 
 	case reflect.Map:
-		if m, ok := fieldValue.(map[string]interface{}); ok {
-			if fv, err := InitializeStructure(m, setEntity); err == nil && fv.IsValid() {
-				v.Elem().FieldByName(fieldName).Set(fv)
-			} else {
-				v.Elem().FieldByName(fieldName).Set(reflect.ValueOf(fieldValue))
-			}
+		if fv, ok := initializeTypedValue(st, fieldType, fieldValue, fieldName, setEntity); ok {
+			field.Set(fv)
+		} else {
+			st.fail("cannot convert %T to %v", fieldValue, fieldType)
 		}
 
 	case reflect.String:
 		if m, ok := fieldValue.(map[string]interface{}); ok {
-			if fv, err := InitializeStructure(m, setEntity); err == nil && fv.IsValid() {
-				// write UUID field of nested value into string field
-				u := fv.Elem().FieldByName("UUID")
+			if fv, err := initializeStructure(st, m, setEntity); err == nil && fv.IsValid() {
+				// write the id field of nested value into string field
+				u := fv.Elem().FieldByName(st.conv.IDKey)
 				if u.IsValid() && u.Kind() == reflect.String {
-					v.Elem().FieldByName(fieldName).Set(u)
+					field.Set(u)
 					return
 				}
 			}
-			v.Elem().FieldByName(fieldName).Set(reflect.ValueOf(fieldValue))
+			st.fail("cannot store an object in %v", fieldType)
 		} else {
-			fv := InitializeBaseTypeValue(fieldType, fieldValue)
-			if fv.IsValid() {
-				v.Elem().FieldByName(fieldName).Set(fv.Convert(fieldType))
-			}
+			st.set(field, st.baseValue(fieldType, fieldValue))
 		}
 
 	default:
-		fv := InitializeBaseTypeValue(fieldType, fieldValue)
-		if fv.IsValid() {
-			if fv.Type() != fieldType && fv.CanConvert(fieldType) {
-				fv = fv.Convert(fieldType)
+		st.set(field, st.baseValue(fieldType, fieldValue))
+	}
+}
+
+// newStructFromMap builds a *T for the struct type st from m, whether or not
+// st is registered, initializing every known field.
+func newStructFromMap(st *initState, t reflect.Type, m map[string]interface{}, setEntity func(interface{})) reflect.Value {
+	v := reflect.New(t)
+	fields := cachedFields(t)
+	for name, raw := range m {
+		if raw == nil {
+			continue
+		}
+		if fd, exist := fields[name]; exist {
+			st.field(name, func() { initializeStructureFieldValue(st, v, name, fd.Type, raw, setEntity) })
+		} else {
+			st.skip(name)
+		}
+	}
+	if setEntity != nil {
+		setEntity(v.Interface())
+	}
+	return v
+}
+
+// initializeTypedValue converts raw into a value of type t, recursively
+// initializing structs, pointers to structs, slices and string-keyed maps
+// (e.g. map[string]*T, map[string][]T). It reports false when raw cannot be
+// represented as t.
+func initializeTypedValue(st *initState, t reflect.Type, raw interface{}, fieldName string, setEntity func(interface{})) (reflect.Value, bool) {
+	if raw == nil {
+		return reflect.Zero(t), true
+	}
+	rv := reflect.ValueOf(raw)
+	if rv.Type() == t {
+		return rv, true
+	}
+	m, isMap := raw.(map[string]interface{})
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if isMap && t != timeType {
+			return newStructFromMap(st, t, m, setEntity).Elem(), true
+		}
+	case reflect.Ptr:
+		if isMap && t.Elem().Kind() == reflect.Struct {
+			return newStructFromMap(st, t.Elem(), m, setEntity), true
+		}
+	case reflect.Interface:
+		if tn, ok := m[st.conv.TypeKey].(string); isMap && ok {
+			if fv := initializeStructureValue(st, tn, m, setEntity); fv.IsValid() && fv.Type().AssignableTo(t) {
+				if setEntity != nil {
+					setEntity(fv.Interface())
+				}
+				return fv, true
+			}
+		}
+	case reflect.Slice:
+		if rv.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 {
+			slice := reflect.MakeSlice(t, rv.Len(), rv.Len())
+			initializeArrayValue(st, slice, fieldName, t, rv, setEntity)
+			return slice, true
+		}
+	case reflect.Map:
+		if rv.Kind() == reflect.Map {
+			return initializeMapValue(st, t, rv, fieldName, setEntity), true
+		}
+	}
+
+	if rv.Type().AssignableTo(t) {
+		return rv, true
+	}
+	return reflect.Value{}, false
+}
+
+// initializeMapValue converts the entries of the map rv into a new map of
+// type t (e.g. map[string]int, map[int]string, map[string][]string,
+// map[string]*T). Keys are always converted with the checked conversions, as
+// a lenient one would silently merge entries.
+func initializeMapValue(st *initState, t reflect.Type, rv reflect.Value, fieldName string, setEntity func(interface{})) reflect.Value {
+	out := reflect.MakeMapWithSize(t, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		k, e := iter.Key().Interface(), iter.Value().Interface()
+		st.field("["+ToString(k)+"]", func() {
+			kv, err := mapKey(t.Key(), k)
+			if err != nil {
+				st.fail("invalid key: %v", err)
+				return
+			}
+			if ev, ok := initializeMapElement(st, t.Elem(), e, fieldName, setEntity); ok {
+				out.SetMapIndex(kv, ev)
+			}
+		})
+	}
+	return out
+}
+
+// mapKey converts k to the key type kt.
+func mapKey(kt reflect.Type, k interface{}) (reflect.Value, error) {
+	if kv := reflect.ValueOf(k); kv.IsValid() && kv.Type().AssignableTo(kt) {
+		return kv, nil
+	}
+	if kv, ok, err := convertFieldValue(kt, k); ok {
+		return kv, err
+	}
+	if kt.Kind() == reflect.Interface {
+		return reflect.Value{}, fmt.Errorf("%T does not implement %v", k, kt)
+	}
+	kv, err := baseValueE(kt, k)
+	if err == nil && !kv.IsValid() {
+		err = fmt.Errorf("cannot convert %T to %v", k, kt)
+	}
+	return kv, err
+}
+
+// initializeMapElement converts the map value e to the element type t: base
+// values like fields, objects carrying a TYPENAME through the registry, and
+// everything else through initializeTypedValue. Failures are recorded.
+func initializeMapElement(st *initState, t reflect.Type, e interface{}, fieldName string, setEntity func(interface{})) (reflect.Value, bool) {
+	if e == nil {
+		return reflect.Zero(t), true
+	}
+	if reflect.TypeOf(e) == t {
+		return reflect.ValueOf(e), true
+	}
+	if fv, ok, err := convertFieldValue(t, e); ok {
+		if err != nil {
+			st.fail("converter failed: %v", err)
+			return reflect.Value{}, false
+		}
+		return fv, true
+	}
+	if isBaseKind(t.Kind()) || t.Kind() == reflect.Array {
+		v := st.baseValue(t, e)
+		if v.IsValid() && v.Type() != t {
+			v = v.Convert(t) // named types, e.g. map[string]Level
+		}
+		return v, v.IsValid()
+	}
+	if m, ok := e.(map[string]interface{}); ok && t.Kind() != reflect.Map {
+		if tn, ok := m[st.conv.TypeKey].(string); ok {
+			fv := initializeStructureValue(st, tn, m, setEntity)
+			if setEntity != nil && fv.IsValid() {
+				setEntity(fv.Interface())
+			}
+			switch {
+			case fv.IsValid() && fv.Type().AssignableTo(t):
+				return fv, true
+			case fv.IsValid() && fv.Kind() == reflect.Ptr && fv.Elem().Type().AssignableTo(t):
+				return fv.Elem(), true // map[string]T rather than map[string]*T
 			}
-			v.Elem().FieldByName(fieldName).Set(fv)
+			st.fail("cannot hold %s", tn)
+			return reflect.Value{}, false
 		}
 	}
+	ev, ok := initializeTypedValue(st, t, e, fieldName, setEntity)
+	if !ok {
+		log.Println("initializeMapElement:", st.at(), "cannot hold", reflect.TypeOf(e))
+		st.fail("cannot hold %T", e)
+	}
+	return ev, ok
 }
 
 // InitializeBaseTypeValue converts an arbitrary value into a reflect.Value appropriate
@@ -389,6 +684,8 @@ func InitializeBaseTypeValue(t reflect.Type, value interface{}) reflect.Value {
 		return reflect.ValueOf(uint(ToInt(value)))
 	case reflect.Uint8:
 		return reflect.ValueOf(uint8(ToInt(value)))
+	case reflect.Uint16:
+		return reflect.ValueOf(uint16(ToInt(value)))
 	case reflect.Uint32:
 		return reflect.ValueOf(uint32(ToInt(value)))
 	case reflect.Uint64:
@@ -398,21 +695,105 @@ func InitializeBaseTypeValue(t reflect.Type, value interface{}) reflect.Value {
 	case reflect.Float64:
 		return reflect.ValueOf(float64(ToNumeric(value)))
 	case reflect.Array:
-		log.Println("InitializeBaseTypeValue: unexpected array kind")
-		return reflect.Value{}
+		v, err := arrayValue(t, value, func(et reflect.Type, ev interface{}) (reflect.Value, error) {
+			if v := InitializeBaseTypeValue(et, ev); v.IsValid() {
+				return v, nil
+			}
+			return reflect.Value{}, fmt.Errorf("cannot convert %T to %v", ev, et)
+		})
+		if err != nil {
+			log.Println("InitializeBaseTypeValue:", err)
+			return reflect.Value{}
+		}
+		return v
 	default:
 		log.Printf("InitializeBaseTypeValue: unexpected type %v\n", t)
 		return reflect.Value{}
 	}
 }
 
+// arrayValue converts value into an array of type t: a list of exactly t.Len()
+// elements converted by elem, or for byte arrays also a []byte or a hex,
+// UUID, base64 or raw string of the right length.
+func arrayValue(t reflect.Type, value interface{}, elem func(reflect.Type, interface{}) (reflect.Value, error)) (reflect.Value, error) {
+	out := reflect.New(t).Elem()
+	if t.Elem().Kind() == reflect.Uint8 {
+		var b []byte
+		switch x := value.(type) {
+		case string:
+			if b = decodeByteString(x, t.Len()); b == nil {
+				return reflect.Value{}, fmt.Errorf("cannot decode %q into %v", x, t)
+			}
+		case []byte:
+			b = x
+		}
+		if b != nil {
+			if len(b) != t.Len() {
+				return reflect.Value{}, fmt.Errorf("expected %d bytes, got %d", t.Len(), len(b))
+			}
+			reflect.Copy(out, reflect.ValueOf(b))
+			return out, nil
+		}
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return reflect.Value{}, fmt.Errorf("cannot convert %T to %v", value, t)
+	}
+	if rv.Len() != t.Len() {
+		return reflect.Value{}, fmt.Errorf("expected %d elements, got %d", t.Len(), rv.Len())
+	}
+	for i := 0; i < rv.Len(); i++ {
+		raw := rv.Index(i).Interface()
+		if raw == nil {
+			continue
+		}
+		ev, err := elem(t.Elem(), raw)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("[%d]: %w", i, err)
+		}
+		if ev.IsValid() {
+			out.Index(i).Set(ev.Convert(t.Elem()))
+		}
+	}
+	return out, nil
+}
+
+// decodeByteString decodes s into n bytes, trying hex, the UUID form
+// (n = 16), base64 and finally the raw bytes of s; nil when none fits.
+func decodeByteString(s string, n int) []byte {
+	if len(s) == 2*n {
+		if b, err := hex.DecodeString(s); err == nil {
+			return b
+		}
+	}
+	if n == 16 && len(s) == 36 && strings.Count(s, "-") == 4 {
+		if b, err := hex.DecodeString(strings.ReplaceAll(s, "-", "")); err == nil {
+			return b
+		}
+	}
+	for _, enc := range []*b64.Encoding{b64.StdEncoding, b64.URLEncoding, b64.RawStdEncoding, b64.RawURLEncoding} {
+		if b, err := enc.DecodeString(s); err == nil && len(b) == n {
+			return b
+		}
+	}
+	if len(s) == n {
+		return []byte(s)
+	}
+	return nil
+}
+
 // ---------------------------
 // Dynamic function management
 // ---------------------------
 
 // RegisterFunction stores a function under a name for dynamic lookup/call.
-func RegisterFunction(name string, fct interface{}) {
+// Optional metadata (description, parameter names) is exposed by DescribeFunc.
+func RegisterFunction(name string, fct interface{}, meta ...FuncMeta) {
 	DefaultTypeManager().RegisterFunc(name, fct)
+	if len(meta) > 0 {
+		DefaultTypeManager().SetFuncMeta(name, meta[0])
+	}
 }
 
 // GetFunction retrieves a function by name (or nil if not found).
@@ -432,46 +813,21 @@ func CallFunction(name string, params ...interface{}) (result []reflect.Value, e
 	}
 
 	fv := reflect.ValueOf(fn)
-	ft := fv.Type()
+	plan := funcArgPlan(fv.Type())
 
 	// Arity check for non-variadic functions
-	if !ft.IsVariadic() && len(params) != ft.NumIn() {
+	if !plan.arity(len(params)) {
 		return nil, errors.New("Wrong number of parameter for " + name +
 			" got " + strconv.Itoa(len(params)) +
-			" but expect " + strconv.Itoa(ft.NumIn()))
+			" but expect " + strconv.Itoa(plan.numIn))
 	}
 
 	// Build arguments with best-effort conversion
-	in := make([]reflect.Value, len(params))
-	for i, p := range params {
-		if p == nil {
-			// nil → zero of parameter type when not variadic; or a nil interface value for variadic tail
-			if !ft.IsVariadic() || i < ft.NumIn()-1 {
-				in[i] = reflect.Zero(ft.In(i))
-			} else {
-				var nilVal interface{}
-				in[i] = reflect.ValueOf(&nilVal).Elem()
-			}
-			continue
-		}
-
-		v := reflect.ValueOf(p)
-		// If types don't match but are convertible, convert.
-		var target reflect.Type
-		if !ft.IsVariadic() || i < ft.NumIn()-1 {
-			target = ft.In(i)
-		} else {
-			// For variadic, the final param expects elements of the slice element type.
-			target = ft.In(ft.NumIn() - 1).Elem()
-		}
-		if v.Type() != target && v.CanConvert(target) {
-			v = v.Convert(target)
-		}
-		in[i] = v
-	}
+	in := plan.values(params)
 
-	result = fv.Call(in)
-	return
+	return DefaultTypeManager().intercept(name, params, func() ([]reflect.Value, error) {
+		return fv.Call(in), nil
+	})
 }
 
 // CallMethod uses reflection to call the named method on i with params.
@@ -480,8 +836,17 @@ func CallFunction(name string, params ...interface{}) (result []reflect.Value, e
 // Returns: (result, error). If the method returns only an error, result is nil.
 // If the method returns (T, error), both are forwarded. Panics are caught and returned as the error.
 func CallMethod(i interface{}, methodName string, params []interface{}) (interface{}, interface{}) {
+	res, errv, pe := callMethod(i, methodName, params)
+	if pe != nil {
+		return nil, pe.Value
+	}
+	return res, errv
+}
+
+// callMethod implements CallMethod; a recovered panic is returned as pe.
+func callMethod(i interface{}, methodName string, params []interface{}) (res interface{}, errv interface{}, pe *PanicError) {
 	if i == nil {
-		return "", errors.New("Nil pointer!")
+		return "", errors.New("Nil pointer!"), nil
 	}
 
 	var ptr reflect.Value
@@ -489,7 +854,7 @@ func CallMethod(i interface{}, methodName string, params []interface{}) (interfa
 
 	// In case of a nil pointer...
 	if val.Kind() == reflect.Ptr && val.IsNil() {
-		return "", errors.New("Nil pointer!")
+		return "", errors.New("Nil pointer!"), nil
 	}
 
 	// Normalize pointer/value pair
@@ -501,98 +866,76 @@ func CallMethod(i interface{}, methodName string, params []interface{}) (interfa
 		ptr.Elem().Set(val)
 	}
 
-	// Find method on value or pointer receiver
-	finalMethod := val.MethodByName(methodName)
-	if !finalMethod.IsValid() {
-		finalMethod = ptr.MethodByName(methodName)
+	// Find method on value or pointer receiver (cached per type and name)
+	plan := methodPlan(val.Type(), methodName)
+	if plan == nil {
+		return nil, errors.New("Method dosen't exist!"), nil
 	}
-
-	if !finalMethod.IsValid() {
-		return nil, errors.New("Method dosen't exist!")
+	recv := val
+	if plan.onPtr {
+		recv = ptr
 	}
-
-	mt := finalMethod.Type()
+	finalMethod := recv.Method(plan.index)
 
 	// Arity check for non-variadic methods (NumIn already excludes receiver on method values)
-	if !mt.IsVariadic() && mt.NumIn() != len(params) {
+	if !plan.args.arity(len(params)) {
 		errMsg := "Wrong number of parameter for method " + methodName +
-			" expected " + strconv.Itoa(mt.NumIn()) +
+			" expected " + strconv.Itoa(plan.args.numIn) +
 			" got " + strconv.Itoa(len(params))
-		return nil, errors.New(errMsg)
+		return nil, errors.New(errMsg), nil
 	}
 
 	// Build argument list with best-effort conversions & nil handling
-	in := make([]reflect.Value, len(params))
-	for k, p := range params {
-		// Determine target parameter type (variadic element type if applicable)
-		var target reflect.Type
-		if !mt.IsVariadic() || k < mt.NumIn()-1 {
-			target = mt.In(k)
-		} else {
-			target = mt.In(mt.NumIn() - 1).Elem()
-		}
-
-		if p == nil {
-			// nil → zero value of the expected type
-			in[k] = reflect.Zero(target)
-			continue
-		}
+	in := plan.args.values(params)
 
-		vp := reflect.ValueOf(p)
-		if vp.Type() != target && vp.CanConvert(target) {
-			vp = vp.Convert(target)
+	// Recover from panics, returning them as pe
+	defer func() {
+		if r := recover(); r != nil {
+			res, errv, pe = nil, nil, newPanicError(plan.name, r)
 		}
-		in[k] = vp
-	}
+	}()
+	res, errv = callMethodResults(plan, finalMethod, params, in)
+	return res, errv, nil
+}
 
-	// Call in a goroutine and recover from panics, returning them as the error value
-	wait := make(chan []interface{})
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				wait <- []interface{}{nil, r}
-			}
-		}()
-
-		results := finalMethod.Call(in)
-
-		switch len(results) {
-		case 0:
-			// No returns
-			wait <- []interface{}{"", nil}
-		case 1:
-			// Single return; if it's an error, map accordingly
-			r0 := results[0]
-			if !r0.IsZero() {
-				if err, ok := r0.Interface().(error); ok && err != nil {
-					wait <- []interface{}{nil, err}
-					return
-				}
-				wait <- []interface{}{r0.Interface(), nil}
-				return
-			}
-			wait <- []interface{}{nil, nil}
-		default:
-			// Two or more returns: use first as result, second as error if it's an error
-			r0 := results[0]
-			var res interface{}
-			if r0.IsValid() && !r0.IsZero() {
-				res = r0.Interface()
+// callMethodResults calls method and maps its results as CallMethod documents.
+func callMethodResults(plan *callPlan, method reflect.Value, params []interface{}, in []reflect.Value) (res interface{}, errv interface{}) {
+	results, err := DefaultTypeManager().intercept(plan.name, params, func() ([]reflect.Value, error) {
+		return method.Call(in), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(results) {
+	case 0:
+		// No returns
+		return "", nil
+	case 1:
+		// Single return; if it's an error, map accordingly
+		r0 := results[0]
+		if !r0.IsZero() {
+			if err, ok := r0.Interface().(error); ok && err != nil {
+				return nil, err
 			}
+			return r0.Interface(), nil
+		}
+		return nil, nil
+	default:
+		// Two or more returns: use first as result, second as error if it's an error
+		r0 := results[0]
+		if r0.IsValid() && !r0.IsZero() {
+			res = r0.Interface()
+		}
 
-			var err interface{}
-			r1 := results[1]
-			if r1.IsValid() && !r1.IsZero() {
-				if e, ok := r1.Interface().(error); ok && e != nil {
-					err = e
-				}
+		r1 := results[1]
+		if r1.IsValid() && !r1.IsZero() {
+			if e, ok := r1.Interface().(error); ok && e != nil {
+				errv = e
 			}
-			wait <- []interface{}{res, err}
 		}
-	}()
-
-	out := <-wait
-	return out[0], out[1]
+		return res, errv
+	}
 }
 
 // --------------------
@@ -610,7 +953,7 @@ func GetProperty(ptr interface{}, field string) (interface{}, bool) {
 		return nil, false
 	}
 
-	f := rv.FieldByName(field)
+	f := structField(rv, field)
 	if !f.IsValid() {
 		return nil, false
 	}
@@ -633,7 +976,7 @@ func SetProperty(ptr interface{}, field string, val interface{}) bool {
 	if !rv.IsValid() {
 		return false
 	}
-	f := rv.FieldByName(field)
+	f := settableField(rv, field)
 	if !f.IsValid() || !f.CanSet() {
 		return false
 	}
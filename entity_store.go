@@ -0,0 +1,245 @@
+// utility/entity_store.go
+package Utility
+
+import (
+	"container/list"
+	"errors"
+	"reflect"
+	"sync"
+	"unsafe"
+	"weak"
+)
+
+// Entity store
+// ------------
+// EntityStore is the identity map behind the setEntity callback pattern: Set
+// indexes every instance MakeInstance/InitializeStructure creates by UUID,
+// and Lookup hands them back to ResolveReferences, loading the missing ones
+// on demand. Entities can be held weakly (kept only while something else
+// references them), and with a Capacity the least recently used strong
+// entries are demoted to weak ones instead of growing without bound.
+
+// ErrEntityNotFound is returned by Get for an unknown UUID.
+var ErrEntityNotFound = errors.New("entity not found")
+
+// EntityStoreOptions configures an EntityStore; the zero value keeps every
+// entity strongly and never loads.
+type EntityStoreOptions struct {
+	Capacity int                                    // strong entries kept before LRU demotion to weak, 0 = unlimited
+	Weak     bool                                   // hold every entity weakly
+	Load     func(uuid string) (interface{}, error) // fetches entities missing from the store
+}
+
+// EntityStore indexes entities by UUID.
+type EntityStore struct {
+	opts EntityStoreOptions
+
+	mu     sync.Mutex
+	strong map[string]*list.Element // values are *storeEntry
+	lru    *list.List               // most recently used first
+	weak   map[string]weakEntity
+}
+
+type storeEntry struct {
+	uuid   string
+	entity interface{}
+}
+
+// weakEntity is a weak reference to the object a pointer entity points to.
+type weakEntity struct {
+	ptr weak.Pointer[byte]
+	typ reflect.Type // pointer type of the entity
+}
+
+func (w weakEntity) value() interface{} {
+	p := w.ptr.Value()
+	if p == nil {
+		return nil
+	}
+	return reflect.NewAt(w.typ.Elem(), unsafe.Pointer(p)).Interface()
+}
+
+// NewEntityStore creates an empty store.
+func NewEntityStore(opts EntityStoreOptions) *EntityStore {
+	return &EntityStore{
+		opts:   opts,
+		strong: make(map[string]*list.Element),
+		lru:    list.New(),
+		weak:   make(map[string]weakEntity),
+	}
+}
+
+// Set indexes entity by its UUID (GetUUID or a UUID field); others are
+// ignored. It can be passed as the setEntity callback of MakeInstance.
+func (s *EntityStore) Set(entity interface{}) {
+	if s.opts.Weak {
+		s.SetWeak(entity)
+		return
+	}
+	uuid := entityUUID(entity)
+	if uuid == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setStrong(uuid, entity)
+}
+
+// SetWeak indexes entity without keeping it alive. Only pointer entities can
+// be held weakly; other values are kept strongly.
+func (s *EntityStore) SetWeak(entity interface{}) {
+	uuid := entityUUID(entity)
+	if uuid == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.setWeak(uuid, entity) {
+		s.setStrong(uuid, entity)
+	}
+}
+
+// setStrong stores entity and demotes the overflow (caller holds mu).
+func (s *EntityStore) setStrong(uuid string, entity interface{}) {
+	delete(s.weak, uuid)
+	if el, ok := s.strong[uuid]; ok {
+		el.Value.(*storeEntry).entity = entity
+		s.lru.MoveToFront(el)
+		return
+	}
+	s.strong[uuid] = s.lru.PushFront(&storeEntry{uuid: uuid, entity: entity})
+	for s.opts.Capacity > 0 && s.lru.Len() > s.opts.Capacity {
+		oldest := s.lru.Remove(s.lru.Back()).(*storeEntry)
+		delete(s.strong, oldest.uuid)
+		s.setWeak(oldest.uuid, oldest.entity) // non-pointers are simply evicted
+	}
+}
+
+// setWeak stores a weak reference to entity, reporting false when entity
+// isn't a non-nil pointer (caller holds mu).
+func (s *EntityStore) setWeak(uuid string, entity interface{}) bool {
+	rv := reflect.ValueOf(entity)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return false
+	}
+	if el, ok := s.strong[uuid]; ok {
+		s.lru.Remove(el)
+		delete(s.strong, uuid)
+	}
+	s.weak[uuid] = weakEntity{ptr: weak.Make((*byte)(rv.UnsafePointer())), typ: rv.Type()}
+	return true
+}
+
+// get returns the stored entity, promoting a live weak entry back to a
+// strong one unless the store is weak.
+func (s *EntityStore) get(uuid string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.strong[uuid]; ok {
+		s.lru.MoveToFront(el)
+		return el.Value.(*storeEntry).entity, true
+	}
+	w, ok := s.weak[uuid]
+	if !ok {
+		return nil, false
+	}
+	entity := w.value()
+	if entity == nil {
+		delete(s.weak, uuid)
+		return nil, false
+	}
+	if !s.opts.Weak {
+		s.setStrong(uuid, entity)
+	}
+	return entity, true
+}
+
+// Get returns the entity with the given UUID, loading it with Load when it
+// isn't in the store.
+func (s *EntityStore) Get(uuid string) (interface{}, error) {
+	if entity, ok := s.get(uuid); ok {
+		return entity, nil
+	}
+	if s.opts.Load == nil {
+		return nil, ErrEntityNotFound
+	}
+	entity, err := s.opts.Load(uuid)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return nil, ErrEntityNotFound
+	}
+	s.Set(entity)
+	return entity, nil
+}
+
+// Lookup is Get returning nil on failure, the lookup ResolveReferences expects.
+func (s *EntityStore) Lookup(uuid string) interface{} {
+	entity, _ := s.Get(uuid)
+	return entity
+}
+
+// Resolve wires the M_xxxPtr fields of entities from the store (see
+// ResolveReferences).
+func (s *EntityStore) Resolve(entities ...interface{}) error {
+	return ResolveReferences(entities, s.Lookup)
+}
+
+// Initialize builds the entity described by data (see InitializeStructure),
+// indexing it and its nested entities, then resolves its references. The
+// entity is returned even when some references are unresolved.
+func (s *EntityStore) Initialize(data map[string]interface{}) (interface{}, error) {
+	value, err := InitializeStructure(data, s.Set)
+	if err != nil {
+		return nil, err
+	}
+	if !value.IsValid() {
+		return nil, errors.New("entity data has no registered TYPENAME")
+	}
+	entity := value.Interface()
+	return entity, s.Resolve(entity)
+}
+
+// Evict removes uuid from the store.
+func (s *EntityStore) Evict(uuid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.strong[uuid]; ok {
+		s.lru.Remove(el)
+		delete(s.strong, uuid)
+	}
+	delete(s.weak, uuid)
+}
+
+// Clear empties the store.
+func (s *EntityStore) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.strong = make(map[string]*list.Element)
+	s.lru.Init()
+	s.weak = make(map[string]weakEntity)
+}
+
+// Entities returns every live entity, dropping the collected weak ones.
+func (s *EntityStore) Entities() []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]interface{}, 0, len(s.strong)+len(s.weak))
+	for el := s.lru.Front(); el != nil; el = el.Next() {
+		out = append(out, el.Value.(*storeEntry).entity)
+	}
+	for uuid, w := range s.weak {
+		if entity := w.value(); entity != nil {
+			out = append(out, entity)
+		} else {
+			delete(s.weak, uuid)
+		}
+	}
+	return out
+}
+
+// Len returns the number of live entities.
+func (s *EntityStore) Len() int {
+	return len(s.Entities())
+}
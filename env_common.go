@@ -2,8 +2,10 @@
 package Utility
 
 import (
-	"errors"
 	"os"
+	"runtime"
+	"sort"
+	"strings"
 )
 
 // Cross-platform environment variable helpers
@@ -23,18 +25,76 @@ func UnsetEnvironmentVariable(key string) error {
 	return os.Unsetenv(key)
 }
 
-// Windows-specific stubs — these are implemented in env_windows.go.
-// On non-Windows they just return an error.
+// EnvSnapshot is a copy of a process environment, name -> value.
+type EnvSnapshot map[string]string
 
-func SetWindowsEnvironmentVariable(key string, value string) error {
-	return errors.New("SetWindowsEnvironmentVariable is available on windows only")
+// SnapshotEnv captures the current process environment.
+func SnapshotEnv() EnvSnapshot {
+	return parseEnv(os.Environ())
 }
 
-func GetWindowsEnvironmentVariable(key string) (string, error) {
-	return "", errors.New("GetWindowsEnvironmentVariable is available on windows only")
+// RestoreEnv makes the process environment equal to snapshot again: variables
+// set since are removed and changed ones get their old value back.
+func RestoreEnv(snapshot EnvSnapshot) error {
+	for key := range SnapshotEnv() {
+		if _, ok := snapshot[key]; !ok {
+			if err := os.Unsetenv(key); err != nil {
+				return err
+			}
+		}
+	}
+	for key, value := range snapshot {
+		if cur, ok := os.LookupEnv(key); !ok || cur != value {
+			if err := os.Setenv(key, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
-func UnsetWindowsEnvironmentVariable(key string) error {
-	return errors.New("UnsetWindowsEnvironmentVariable is available on windows only")
+// BuildChildEnv returns base with overrides applied, as the sorted KEY=VALUE
+// list expected by exec.Cmd.Env. A nil base gives an environment holding only
+// the overrides; pass SnapshotEnv() to inherit the current one. Names are
+// case-insensitive on Windows.
+func BuildChildEnv(base map[string]string, overrides map[string]string) []string {
+	merged := make(map[string]string, len(base)+len(overrides))
+	names := make(map[string]string) // canonical name -> name used
+	set := func(key, value string) {
+		canon := key
+		if runtime.GOOS == "windows" {
+			canon = strings.ToUpper(key)
+		}
+		if prev, ok := names[canon]; ok {
+			delete(merged, prev)
+		}
+		names[canon] = key
+		merged[key] = value
+	}
+	for key, value := range base {
+		set(key, value)
+	}
+	for key, value := range overrides {
+		set(key, value)
+	}
+
+	env := make([]string, 0, len(merged))
+	for key, value := range merged {
+		env = append(env, key+"="+value)
+	}
+	sort.Strings(env)
+	return env
 }
 
+// parseEnv turns KEY=VALUE entries into a map. Windows' hidden per-drive
+// entries ("=C:=C:\dir") keep their leading '='.
+func parseEnv(entries []string) EnvSnapshot {
+	env := make(EnvSnapshot, len(entries))
+	for _, e := range entries {
+		if i := strings.IndexByte(e[min(1, len(e)):], '='); i >= 0 {
+			i += min(1, len(e))
+			env[e[:i]] = e[i+1:]
+		}
+	}
+	return env
+}
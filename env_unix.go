@@ -0,0 +1,22 @@
+// utility/env_unix.go
+//go:build !windows
+
+package Utility
+
+import "errors"
+
+// Windows-specific stubs — these are implemented in env_windows.go.
+// On non-Windows they just return an error.
+
+func SetWindowsEnvironmentVariable(key string, value string) error {
+	return errors.New("SetWindowsEnvironmentVariable is available on windows only")
+}
+
+func GetWindowsEnvironmentVariable(key string) (string, error) {
+	return "", errors.New("GetWindowsEnvironmentVariable is available on windows only")
+}
+
+func UnsetWindowsEnvironmentVariable(key string) error {
+	return errors.New("UnsetWindowsEnvironmentVariable is available on windows only")
+}
+
@@ -3,63 +3,23 @@
 
 package Utility
 
-import (
-	"errors"
-	// Uncomment to enable registry access:
-	"golang.org/x/sys/windows/registry"
-)
+// The system environment lives in the registry; changes apply to processes
+// started afterwards.
+const windowsEnvironmentKey = `SYSTEM\ControlSet001\Control\Session Manager\Environment`
 
 // Windows-specific environment variable helpers
 func SetWindowsEnvironmentVariable(key string, value string) error {
-
-		k, err := registry.OpenKey(registry.LOCAL_MACHINE,
-			`SYSTEM\ControlSet001\Control\Session Manager\Environment`, registry.ALL_ACCESS)
-		if err != nil {
-			return err
-		}
-		defer k.Close()
-
-		err = k.SetStringValue(key, value)
-		if err != nil {
-			return err
-		}
-		return nil
-
-	return errors.New("SetWindowsEnvironmentVariable requires registry access (unimplemented stub)")
+	return WriteRegistryValue(RegistryLocalMachine, windowsEnvironmentKey, key, value)
 }
 
 func GetWindowsEnvironmentVariable(key string) (string, error) {
-
-		k, err := registry.OpenKey(registry.LOCAL_MACHINE,
-			`SYSTEM\ControlSet001\Control\Session Manager\Environment`, registry.ALL_ACCESS)
-		if err != nil {
-			return "", err
-		}
-		defer k.Close()
-
-		value, _, err := k.GetStringValue(key)
-		if err != nil {
-			return "", err
-		}
-		return value, nil
-
-	return "", errors.New("GetWindowsEnvironmentVariable requires registry access (unimplemented stub)")
+	v, err := ReadRegistryValue(RegistryLocalMachine, windowsEnvironmentKey, key)
+	if err != nil {
+		return "", err
+	}
+	return ToStringE(v)
 }
 
 func UnsetWindowsEnvironmentVariable(key string) error {
-
-		k, err := registry.OpenKey(registry.LOCAL_MACHINE,
-			`SYSTEM\ControlSet001\Control\Session Manager\Environment`, registry.ALL_ACCESS)
-		if err != nil {
-			return err
-		}
-		defer k.Close()
-
-		err = k.DeleteValue(key)
-		if err != nil {
-			return err
-		}
-		return nil
-
+	return DeleteRegistryKey(RegistryLocalMachine, windowsEnvironmentKey, key)
 }
-
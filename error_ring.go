@@ -0,0 +1,181 @@
+// utility/error_ring.go
+package Utility
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Recent errors ring buffer
+// -------------------------
+// ErrorRing keeps the last N errors in memory so a node can answer "what
+// failed recently" without anyone grepping its logs. Mount it on a mux
+// (it is an http.Handler) to expose the entries as JSON.
+
+// ErrorEntry is one recorded error.
+type ErrorEntry struct {
+	Time     time.Time              `json:"time"`
+	Message  string                 `json:"message"`
+	Source   string                 `json:"source,omitempty"` // subsystem or operation
+	Location string                 `json:"location,omitempty"`
+	Host     string                 `json:"host,omitempty"`
+	Fields   map[string]interface{} `json:"fields,omitempty"`
+}
+
+// ErrorRing is a fixed-capacity, concurrency-safe ring of ErrorEntry.
+type ErrorRing struct {
+	mu    sync.RWMutex
+	buf   []ErrorEntry
+	next  int
+	full  bool
+	total uint64
+}
+
+// NewErrorRing creates a ring keeping the last size entries (default 100).
+func NewErrorRing(size int) *ErrorRing {
+	if size <= 0 {
+		size = 100
+	}
+	return &ErrorRing{buf: make([]ErrorEntry, size)}
+}
+
+var (
+	defaultErrorRing     *ErrorRing
+	defaultErrorRingOnce sync.Once
+)
+
+// DefaultErrorRing returns the process-wide ring used by RecordError.
+func DefaultErrorRing() *ErrorRing {
+	defaultErrorRingOnce.Do(func() { defaultErrorRing = NewErrorRing(100) })
+	return defaultErrorRing
+}
+
+// Add stores an entry, overwriting the oldest one when the ring is full.
+func (r *ErrorRing) Add(e ErrorEntry) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buf[r.next] = e
+	r.next = (r.next + 1) % len(r.buf)
+	if r.next == 0 {
+		r.full = true
+	}
+	r.total++
+}
+
+// Record stores err with its source and optional key/value fields. The
+// caller's file:line is captured. Nil errors are ignored.
+func (r *ErrorRing) Record(source string, err error, fields map[string]interface{}) {
+	if err == nil {
+		return
+	}
+	r.Add(newErrorEntry(source, err, fields, 2))
+}
+
+func newErrorEntry(source string, err error, fields map[string]interface{}, skip int) ErrorEntry {
+	e := ErrorEntry{Time: time.Now(), Message: err.Error(), Source: source, Fields: fields}
+	if _, file, line, ok := runtime.Caller(skip); ok {
+		e.Location = fmt.Sprintf("%s:%d", file, line)
+	}
+	e.Host, _ = os.Hostname()
+	return e
+}
+
+// Entries returns the stored entries, oldest first.
+func (r *ErrorRing) Entries() []ErrorEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if !r.full {
+		return append([]ErrorEntry(nil), r.buf[:r.next]...)
+	}
+	out := make([]ErrorEntry, 0, len(r.buf))
+	out = append(out, r.buf[r.next:]...)
+	return append(out, r.buf[:r.next]...)
+}
+
+// Last returns up to n of the most recent entries, newest first.
+func (r *ErrorRing) Last(n int) []ErrorEntry {
+	all := r.Entries()
+	if n <= 0 || n > len(all) {
+		n = len(all)
+	}
+	out := make([]ErrorEntry, n)
+	for i := 0; i < n; i++ {
+		out[i] = all[len(all)-1-i]
+	}
+	return out
+}
+
+// Len returns the number of stored entries.
+func (r *ErrorRing) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.full {
+		return len(r.buf)
+	}
+	return r.next
+}
+
+// Total returns the number of errors recorded since creation (or Clear).
+func (r *ErrorRing) Total() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.total
+}
+
+// Clear drops every entry.
+func (r *ErrorRing) Clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	clear(r.buf)
+	r.next, r.full, r.total = 0, false, 0
+}
+
+// ServeHTTP writes the most recent entries as JSON, newest first.
+// ?limit=N bounds the number of entries and ?source=x filters by source.
+func (r *ErrorRing) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	limit, _ := strconv.Atoi(req.URL.Query().Get("limit"))
+	source := req.URL.Query().Get("source")
+
+	entries := r.Last(0)
+	if source != "" {
+		filtered := entries[:0]
+		for _, e := range entries {
+			if e.Source == source {
+				filtered = append(filtered, e)
+			}
+		}
+		entries = filtered
+	}
+	if limit > 0 && limit < len(entries) {
+		entries = entries[:limit]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"total":    r.Total(),
+		"capacity": len(r.buf),
+		"errors":   entries,
+	})
+}
+
+// RecordError stores err in the default ring.
+func RecordError(source string, err error, fields map[string]interface{}) {
+	if err == nil {
+		return
+	}
+	DefaultErrorRing().Add(newErrorEntry(source, err, fields, 2))
+}
+
+// RecentErrors returns up to n of the most recent errors of the default ring.
+func RecentErrors(n int) []ErrorEntry {
+	return DefaultErrorRing().Last(n)
+}
@@ -0,0 +1,129 @@
+// utility/errors.go
+package Utility
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Stack-carrying errors
+// ---------------------
+// WrapWithStack always records the caller's stack. NewError only does so when
+// stack capture is enabled: built with -tags utility_debug, UTILITY_DEBUG set
+// to a true value in the environment, or SetCaptureStacks(true). Stacks are
+// printed with %+v and kept out of Error() so messages stay unchanged.
+
+var captureStacks atomic.Bool
+
+func init() {
+	on := debugBuild
+	if v, ok := os.LookupEnv("UTILITY_DEBUG"); ok {
+		if b, err := strconv.ParseBool(v); err == nil {
+			on = b
+		} else {
+			on = v != ""
+		}
+	}
+	captureStacks.Store(on)
+}
+
+// SetCaptureStacks turns automatic stack capture in NewError on or off.
+func SetCaptureStacks(on bool) {
+	captureStacks.Store(on)
+}
+
+// CaptureStacks reports whether NewError records stacks.
+func CaptureStacks() bool {
+	return captureStacks.Load()
+}
+
+// StackError is an error annotated with the stack where it was created/wrapped.
+type StackError struct {
+	err   error
+	stack []uintptr
+}
+
+func newStackError(err error, skip int) *StackError {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(skip, pcs)
+	return &StackError{err: err, stack: pcs[:n]}
+}
+
+func (e *StackError) Error() string { return e.err.Error() }
+
+// Unwrap returns the wrapped error.
+func (e *StackError) Unwrap() error { return e.err }
+
+// StackTrace returns the recorded stack, one "function\n\tfile:line" per frame.
+func (e *StackError) StackTrace() string {
+	var sb strings.Builder
+	frames := runtime.CallersFrames(e.stack)
+	for {
+		f, more := frames.Next()
+		fmt.Fprintf(&sb, "%s\n\t%s:%d\n", f.Function, f.File, f.Line)
+		if !more {
+			break
+		}
+	}
+	return sb.String()
+}
+
+// Format prints the stack after the message for %+v.
+func (e *StackError) Format(s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		io.WriteString(s, e.Error()+"\n"+e.StackTrace())
+	case verb == 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	default:
+		io.WriteString(s, e.Error())
+	}
+}
+
+// WrapWithStack annotates err with the caller's stack. Errors that already
+// carry a stack are returned unchanged; nil stays nil.
+func WrapWithStack(err error) error {
+	if err == nil {
+		return nil
+	}
+	var se *StackError
+	if errors.As(err, &se) {
+		return err
+	}
+	return newStackError(err, 3)
+}
+
+// NewError creates an error from a message (formatted when args are given),
+// with a stack when capture is enabled.
+func NewError(msg string, args ...interface{}) error {
+	var err error
+	if len(args) > 0 {
+		err = fmt.Errorf(msg, args...)
+	} else {
+		err = errors.New(msg)
+	}
+	if !CaptureStacks() {
+		return err
+	}
+	return newStackError(err, 3)
+}
+
+// ErrorStack returns the stack recorded in err's chain (by a StackError or
+// a PanicError), or "".
+func ErrorStack(err error) string {
+	var se *StackError
+	if errors.As(err, &se) {
+		return se.StackTrace()
+	}
+	var pe *PanicError
+	if errors.As(err, &pe) {
+		return pe.Stack
+	}
+	return ""
+}
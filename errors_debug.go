@@ -0,0 +1,7 @@
+// utility/errors_debug.go
+//go:build utility_debug
+
+package Utility
+
+// debugBuild enables stack capture by default in utility_debug builds.
+const debugBuild = true
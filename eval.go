@@ -0,0 +1,633 @@
+// utility/eval.go
+package Utility
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expression evaluator
+// --------------------
+// Eval evaluates small expressions such as
+//
+//	cpu.load > 0.9 && !contains(host.name, "test")
+//	price * (1 + taxRate) >= limit
+//
+// for user-defined rules (alerts, filters). The language has number, string
+// ('...' or "..."), bool and nil literals; variables from vars with member
+// (a.b) and index (a[0], m["k"]) access; the operators ! - * / % + - < <= >
+// >= == != && || with the usual precedence; and calls to functions
+// registered with RegisterFunction. Nothing else is reachable, so rules can
+// be evaluated safely. Integer arithmetic stays int64 unless it would
+// overflow, division and mixed arithmetic give float64, and + concatenates
+// when one side is a string.
+
+// Expr is a parsed expression, safe for concurrent evaluation.
+type Expr struct {
+	src  string
+	root exprNode
+}
+
+// Eval parses and evaluates expr against vars.
+func Eval(expr string, vars map[string]interface{}) (interface{}, error) {
+	e, err := CompileExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	return e.Eval(vars)
+}
+
+// CompileExpr parses expr once for repeated evaluation.
+func CompileExpr(expr string) (*Expr, error) {
+	p := &exprParser{src: expr}
+	if err := p.lex(); err != nil {
+		return nil, err
+	}
+	root, err := p.parse(0)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	return &Expr{src: expr, root: root}, nil
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string { return e.src }
+
+// Eval evaluates the expression against vars.
+func (e *Expr) Eval(vars map[string]interface{}) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("eval %q: %v", e.src, r)
+		}
+	}()
+	return e.root.eval(vars)
+}
+
+// --- lexer -------------------------------------------------------------
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokKind
+	text string
+	val  interface{} // literal value of numbers and strings
+	pos  int
+}
+
+type exprParser struct {
+	src   string
+	toks  []token
+	i     int
+	depth int
+}
+
+func (p *exprParser) errorf(t token, format string, args ...interface{}) error {
+	return fmt.Errorf("eval: "+format+" at offset %d", append(args, t.pos)...)
+}
+
+var exprOps = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "+", "-", "*", "/", "%", "!", "(", ")", "[", "]", ".", ","}
+
+func (p *exprParser) lex() error {
+	s := p.src
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(s) && (s[j] >= '0' && s[j] <= '9' || s[j] == '.' || s[j] == 'e' || s[j] == 'E' ||
+				((s[j] == '+' || s[j] == '-') && (s[j-1] == 'e' || s[j-1] == 'E'))) {
+				j++
+			}
+			text := s[i:j]
+			var val interface{}
+			if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+				val = n
+			} else if f, err := strconv.ParseFloat(text, 64); err == nil {
+				val = f
+			} else {
+				return p.errorf(token{pos: i}, "invalid number %q", text)
+			}
+			p.toks = append(p.toks, token{kind: tokNumber, text: text, val: val, pos: i})
+			i = j
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(s) && s[j] != s[i] {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				return p.errorf(token{pos: i}, "unterminated string")
+			}
+			body := s[i+1 : j]
+			if c == '\'' {
+				body = strings.ReplaceAll(strings.ReplaceAll(body, `\'`, `'`), `"`, `\"`)
+			}
+			str, err := strconv.Unquote(`"` + body + `"`)
+			if err != nil {
+				return p.errorf(token{pos: i}, "invalid string %s", s[i:j+1])
+			}
+			p.toks = append(p.toks, token{kind: tokString, text: s[i : j+1], val: str, pos: i})
+			i = j + 1
+		case c == '_' || unicode.IsLetter(c):
+			j := i
+			for j < len(s) && (s[j] == '_' || unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j]))) {
+				j++
+			}
+			p.toks = append(p.toks, token{kind: tokIdent, text: s[i:j], pos: i})
+			i = j
+		default:
+			matched := false
+			for _, op := range exprOps {
+				if strings.HasPrefix(s[i:], op) {
+					p.toks = append(p.toks, token{kind: tokOp, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return p.errorf(token{pos: i}, "unexpected character %q", c)
+			}
+		}
+	}
+	p.toks = append(p.toks, token{kind: tokEOF, pos: len(s)})
+	return nil
+}
+
+// --- parser ------------------------------------------------------------
+
+const maxExprDepth = 64
+
+var binaryPrecedence = map[string]int{
+	"||": 1, "&&": 2,
+	"==": 3, "!=": 3,
+	"<": 4, "<=": 4, ">": 4, ">=": 4,
+	"+": 5, "-": 5,
+	"*": 6, "/": 6, "%": 6,
+}
+
+func (p *exprParser) peek() token { return p.toks[p.i] }
+
+func (p *exprParser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *exprParser) expect(op string) error {
+	if t := p.next(); t.kind != tokOp || t.text != op {
+		return p.errorf(t, "expected %q, got %q", op, t.text)
+	}
+	return nil
+}
+
+// parse parses a binary expression whose operators bind tighter than minPrec.
+func (p *exprParser) parse(minPrec int) (exprNode, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxExprDepth {
+		return nil, p.errorf(p.peek(), "expression too deeply nested")
+	}
+
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		prec, ok := binaryPrecedence[t.text]
+		if t.kind != tokOp || !ok || prec <= minPrec {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parse(prec)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: t.text, left: left, right: right}
+	}
+}
+
+func (p *exprParser) unary() (exprNode, error) {
+	if t := p.peek(); t.kind == tokOp && (t.text == "!" || t.text == "-") {
+		p.next()
+		p.depth++
+		defer func() { p.depth-- }()
+		if p.depth > maxExprDepth {
+			return nil, p.errorf(t, "expression too deeply nested")
+		}
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: t.text, operand: operand}, nil
+	}
+	return p.postfix()
+}
+
+func (p *exprParser) postfix() (exprNode, error) {
+	node, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokOp {
+			return node, nil
+		}
+		switch t.text {
+		case ".":
+			p.next()
+			name := p.next()
+			if name.kind != tokIdent {
+				return nil, p.errorf(name, "expected a member name after '.'")
+			}
+			node = &memberNode{target: node, key: &literalNode{name.text}}
+		case "[":
+			p.next()
+			key, err := p.parse(0)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			node = &memberNode{target: node, key: key}
+		default:
+			return node, nil
+		}
+	}
+}
+
+func (p *exprParser) primary() (exprNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber, tokString:
+		return &literalNode{t.val}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{true}, nil
+		case "false":
+			return &literalNode{false}, nil
+		case "nil", "null":
+			return &literalNode{nil}, nil
+		}
+		if n := p.peek(); n.kind == tokOp && n.text == "(" {
+			p.next()
+			call := &callNode{name: t.text}
+			if n := p.peek(); n.kind == tokOp && n.text == ")" {
+				p.next()
+				return call, nil
+			}
+			for {
+				arg, err := p.parse(0)
+				if err != nil {
+					return nil, err
+				}
+				call.args = append(call.args, arg)
+				sep := p.next()
+				if sep.kind == tokOp && sep.text == ")" {
+					return call, nil
+				}
+				if sep.kind != tokOp || sep.text != "," {
+					return nil, p.errorf(sep, "expected ',' or ')' in call to %s", t.text)
+				}
+			}
+		}
+		return &varNode{t.text}, nil
+	case tokOp:
+		if t.text == "(" {
+			node, err := p.parse(0)
+			if err != nil {
+				return nil, err
+			}
+			return node, p.expect(")")
+		}
+	case tokEOF:
+		return nil, p.errorf(t, "unexpected end of expression")
+	}
+	return nil, p.errorf(t, "unexpected %q", t.text)
+}
+
+// --- evaluation --------------------------------------------------------
+
+type exprNode interface {
+	eval(vars map[string]interface{}) (interface{}, error)
+}
+
+type literalNode struct{ val interface{} }
+
+func (n *literalNode) eval(map[string]interface{}) (interface{}, error) { return n.val, nil }
+
+type varNode struct{ name string }
+
+func (n *varNode) eval(vars map[string]interface{}) (interface{}, error) {
+	v, ok := vars[n.name]
+	if !ok {
+		return nil, fmt.Errorf("eval: undefined variable %s", n.name)
+	}
+	return v, nil
+}
+
+type memberNode struct {
+	target, key exprNode
+}
+
+func (n *memberNode) eval(vars map[string]interface{}) (interface{}, error) {
+	target, err := n.target.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	key, err := n.key.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	v := indirectValue(reflect.ValueOf(target))
+	if !v.IsValid() {
+		return nil, fmt.Errorf("eval: cannot access %v of nil", key)
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		name, _ := key.(string)
+		f := structField(v, name)
+		if !f.IsValid() || !f.CanInterface() {
+			return nil, fmt.Errorf("eval: %v has no field %v", v.Type(), key)
+		}
+		return f.Interface(), nil
+	case reflect.Map:
+		k := reflect.ValueOf(key)
+		if !k.IsValid() || !k.Type().ConvertibleTo(v.Type().Key()) {
+			return nil, fmt.Errorf("eval: invalid key %v for %v", key, v.Type())
+		}
+		e := v.MapIndex(k.Convert(v.Type().Key()))
+		if !e.IsValid() {
+			return nil, nil
+		}
+		return e.Interface(), nil
+	case reflect.Slice, reflect.Array, reflect.String:
+		i, err := ToIntE(key)
+		if err != nil || i < 0 || i >= v.Len() {
+			return nil, fmt.Errorf("eval: index %v out of range", key)
+		}
+		return v.Index(i).Interface(), nil
+	}
+	return nil, fmt.Errorf("eval: cannot access %v of %v", key, v.Type())
+}
+
+type callNode struct {
+	name string
+	args []exprNode
+}
+
+func (n *callNode) eval(vars map[string]interface{}) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, a := range n.args {
+		v, err := a.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	results, err := CallFunction(n.name, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+	last := results[len(results)-1]
+	if last.Type() == reflect.TypeOf((*error)(nil)).Elem() {
+		if !last.IsNil() {
+			return nil, last.Interface().(error)
+		}
+		results = results[:len(results)-1]
+		if len(results) == 0 {
+			return nil, nil
+		}
+	}
+	return results[0].Interface(), nil
+}
+
+type unaryNode struct {
+	op      string
+	operand exprNode
+}
+
+func (n *unaryNode) eval(vars map[string]interface{}) (interface{}, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "!" {
+		b, err := exprBool(v)
+		return !b, err
+	}
+	if i, ok := exprInt(v); ok && i != math.MinInt64 {
+		return -i, nil
+	}
+	f, err := exprNumber(v)
+	return -f, err
+}
+
+type binaryNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n *binaryNode) eval(vars map[string]interface{}) (interface{}, error) {
+	l, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	// && and || short-circuit.
+	if n.op == "&&" || n.op == "||" {
+		lb, err := exprBool(l)
+		if err != nil || lb == (n.op == "||") {
+			return lb, err
+		}
+		r, err := n.right.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		return exprBool(r)
+	}
+	r, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return exprEqual(l, r), nil
+	case "!=":
+		return !exprEqual(l, r), nil
+	case "<", "<=", ">", ">=":
+		c, err := exprCompare(l, r)
+		if err != nil {
+			return nil, err
+		}
+		switch n.op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		}
+		return c >= 0, nil
+	case "+":
+		_, ls := l.(string)
+		_, rs := r.(string)
+		if ls || rs {
+			return ToString(l) + ToString(r), nil
+		}
+	}
+	return exprArith(n.op, l, r)
+}
+
+func exprArith(op string, l, r interface{}) (interface{}, error) {
+	li, lok := exprInt(l)
+	ri, rok := exprInt(r)
+	if lok && rok && op != "/" {
+		// Results that overflow int64 fall through to float64.
+		switch op {
+		case "+":
+			if s := li + ri; (s > li) == (ri > 0) {
+				return s, nil
+			}
+		case "-":
+			if d := li - ri; (d < li) == (ri > 0) {
+				return d, nil
+			}
+		case "*":
+			if li == 0 || ((li != -1 || ri != math.MinInt64) && li*ri/li == ri) {
+				return li * ri, nil
+			}
+		case "%":
+			if ri == 0 {
+				return nil, errors.New("eval: modulo by zero")
+			}
+			return li % ri, nil
+		}
+	}
+	lf, err := exprNumber(l)
+	if err != nil {
+		return nil, err
+	}
+	rf, err := exprNumber(r)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, errors.New("eval: division by zero")
+		}
+		return lf / rf, nil
+	case "%":
+		if rf == 0 {
+			return nil, errors.New("eval: modulo by zero")
+		}
+		return math.Mod(lf, rf), nil
+	}
+	return nil, fmt.Errorf("eval: unknown operator %s", op)
+}
+
+// exprInt returns v as int64 when it has an integer kind.
+func exprInt(v interface{}) (int64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() <= math.MaxInt64 {
+			return int64(rv.Uint()), true
+		}
+	}
+	return 0, false
+}
+
+// exprNumber converts a numeric operand; strings and bools are rejected so
+// that "2" * 3 and true * 2 are errors rather than surprises ("1" + 1 is a
+// concatenation).
+func exprNumber(v interface{}) (float64, error) {
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.String, reflect.Bool, reflect.Invalid:
+		return 0, fmt.Errorf("eval: %v is not a number", v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(reflect.ValueOf(v).Uint()), nil
+	}
+	return ToNumericE(v)
+}
+
+func exprBool(v interface{}) (bool, error) {
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("eval: %v is not a boolean", v)
+	}
+	return b, nil
+}
+
+func exprEqual(l, r interface{}) bool {
+	if l == nil || r == nil {
+		return l == nil && r == nil
+	}
+	if lf, err := exprNumber(l); err == nil {
+		if rf, err := exprNumber(r); err == nil {
+			return lf == rf
+		}
+	}
+	return reflect.DeepEqual(l, r)
+}
+
+func exprCompare(l, r interface{}) (int, error) {
+	ls, lok := l.(string)
+	rs, rok := r.(string)
+	if lok && rok {
+		return strings.Compare(ls, rs), nil
+	}
+	lf, err := exprNumber(l)
+	if err != nil {
+		return 0, err
+	}
+	rf, err := exprNumber(r)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case lf < rf:
+		return -1, nil
+	case lf > rf:
+		return 1, nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,122 @@
+// utility/field_cache.go
+package Utility
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// Field descriptor cache
+// ----------------------
+// Dynamic initialization looks fields up through descriptors rather than
+// FieldByName. Descriptors (index path, type, kind, whether a converter
+// targets the field type) are built once per struct type and reused; the
+// converter flag is refreshed when converters of the default TypeManager
+// change. Fields promoted from embedded structs are found under their own
+// name ("Id" for Base.Id), as in Go source, and setting one allocates the
+// nil embedded pointers on its path.
+
+type fieldDescriptor struct {
+	Name         string
+	Index        []int
+	Type         reflect.Type
+	Kind         reflect.Kind
+	Tag          reflect.StructTag
+	HasConverter bool
+}
+
+type typeDescriptor struct {
+	fields map[string]*fieldDescriptor
+	gen    uint64 // converter generation the HasConverter flags were computed for
+}
+
+var fieldCache sync.Map // reflect.Type -> *typeDescriptor
+
+// cachedFields returns the descriptors of struct type t, keyed by the names
+// FieldByName accepts (including promoted fields).
+func cachedFields(t reflect.Type) map[string]*fieldDescriptor {
+	tm := DefaultTypeManager()
+	gen := atomic.LoadUint64(&tm.convGen)
+	if d, ok := fieldCache.Load(t); ok && d.(*typeDescriptor).gen == gen {
+		return d.(*typeDescriptor).fields
+	}
+
+	fields := make(map[string]*fieldDescriptor)
+	for _, sf := range reflect.VisibleFields(t) {
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+		if _, ok := t.FieldByName(sf.Name); !ok {
+			continue // ambiguous promoted name
+		}
+		fd := &fieldDescriptor{Name: sf.Name, Index: sf.Index, Type: sf.Type, Kind: sf.Type.Kind(), Tag: sf.Tag}
+		fd.HasConverter = tm.hasConverterTo(sf.Type)
+		if cur, exists := fields[sf.Name]; !exists || len(sf.Index) < len(cur.Index) {
+			fields[sf.Name] = fd
+		}
+	}
+	fieldCache.Store(t, &typeDescriptor{fields: fields, gen: gen})
+	return fields
+}
+
+// lookupField returns the cached descriptor of a field of struct type t.
+func lookupField(t reflect.Type, name string) (*fieldDescriptor, bool) {
+	fd, ok := cachedFields(t)[name]
+	return fd, ok
+}
+
+// structField is a cached equivalent of v.FieldByName(name); it returns the
+// zero Value when the field is missing or behind a nil embedded pointer.
+func structField(v reflect.Value, name string) reflect.Value {
+	fd, ok := lookupField(v.Type(), name)
+	if !ok {
+		return reflect.Value{}
+	}
+	if len(fd.Index) == 1 {
+		return v.Field(fd.Index[0])
+	}
+	f, err := v.FieldByIndexErr(fd.Index)
+	if err != nil {
+		return reflect.Value{}
+	}
+	return f
+}
+
+// settableField is structField for writing: nil embedded pointers on the way
+// to a promoted field are allocated. It returns the zero Value when one of
+// them cannot be set (an unexported embedded pointer).
+func settableField(v reflect.Value, name string) reflect.Value {
+	fd, ok := lookupField(v.Type(), name)
+	if !ok {
+		return reflect.Value{}
+	}
+	for i, x := range fd.Index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// hasConverterTo reports whether any converter targets t.
+func (tm *TypeManager) hasConverterTo(t reflect.Type) bool {
+	if _, ok := builtinFieldConverters[t]; ok {
+		return true
+	}
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	for k := range tm.converters {
+		if k.to == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+// utility/field_cache_test.go
+package Utility
+
+import (
+	"reflect"
+	"testing"
+)
+
+type benchBase struct {
+	Id   string
+	Name string
+}
+
+type benchRecord struct {
+	benchBase
+	Title    string
+	Path     string
+	Size     int64
+	Width    int
+	Height   int
+	Duration float64
+	Public   bool
+	Tags     []string
+	Owner    string
+	Created  int64
+}
+
+func benchRecordData() map[string]interface{} {
+	return map[string]interface{}{
+		"TYPENAME": typeNameOf(reflect.TypeOf(benchRecord{})),
+		"Id":       "42", "Name": "clip", "Title": "A clip", "Path": "/videos/clip.mp4",
+		"Size": int64(1 << 20), "Width": 1920, "Height": 1080, "Duration": 12.5,
+		"Public": true, "Tags": []interface{}{"a", "b"}, "Owner": "sa", "Created": int64(1700000000),
+	}
+}
+
+// BenchmarkInitializeStructure measures a whole dynamic initialization, then
+// the field lookups it does per key through the descriptor cache and
+// through reflect.Value.FieldByName.
+func BenchmarkInitializeStructure(b *testing.B) {
+	RegisterType((*benchRecord)(nil))
+	data := benchRecordData()
+
+	b.Run("InitializeStructure", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := InitializeStructure(data, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		v := reflect.New(reflect.TypeOf(benchRecord{})).Elem()
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for name := range data {
+				settableField(v, name)
+			}
+		}
+	})
+	b.Run("FieldByName", func(b *testing.B) {
+		v := reflect.New(reflect.TypeOf(benchRecord{})).Elem()
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for name := range data {
+				v.FieldByName(name)
+			}
+		}
+	})
+}
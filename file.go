@@ -3,6 +3,7 @@ package Utility
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,7 +11,6 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"runtime"
 	"sort"
@@ -239,24 +239,46 @@ func FunctionName() string {
 }
 
 // DownloadFile fetches a remote URL and writes it to fileName.
+//
+// Deprecated: use DownloadFileCtx, which can be cancelled.
 func DownloadFile(URL, fileName string) error {
-	resp, err := http.Get(URL)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+	return DownloadFileCtx(context.Background(), URL, fileName)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return errors.New("received non 200 response code")
-	}
-	file, err := os.Create(fileName)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
+// DownloadFileCtx is DownloadFile bounded by ctx. It honors WithTimeout,
+// WithRetry and WithFS.
+func DownloadFileCtx(ctx context.Context, URL, fileName string, opts ...Option) (err error) {
+	defer endOperation(startOperation("download", map[string]interface{}{"url": URL, "file": fileName}), &err)
 
-	_, err = io.Copy(file, resp.Body)
-	return err
+	o := NewOptions(opts...)
+	ctx, cancel := o.Context(ctx)
+	defer cancel()
+
+	return o.Do(ctx, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, URL, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := httpClient().Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return errors.New("received non 200 response code")
+		}
+		file, err := o.FS.Create(fileName)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if _, err := io.Copy(file, resp.Body); err != nil {
+			return err
+		}
+		return file.Close()
+	})
 }
 
 // JsonErrorStr marshals a simple error descriptor (kept here for convenience).
@@ -273,8 +295,11 @@ func JsonErrorStr(functionName string, fileLine string, err error) string {
  * Read movie file metadata...
  */
 func ReadMetadata(path string) (map[string]interface{}, error) {
-	cmd := exec.Command(`ffprobe`, `-hide_banner`, `-loglevel`, `fatal`, `-show_format`, `-print_format`, `json`, `-i`, path)
-	cmd.Dir = os.TempDir()
+	if err := RequireTool("ffprobe"); err != nil {
+		return nil, err
+	}
+	cmd := toolCommand(context.Background(), `ffprobe`, `-hide_banner`, `-loglevel`, `fatal`, `-show_format`, `-print_format`, `json`, `-i`, path)
+	cmd.Dir = tempDir()
 
 	var out bytes.Buffer
 	var stderr bytes.Buffer
@@ -419,7 +444,7 @@ func ReadAudioMetadata(path string, thumnailHeight, thumbnailWidth int) (map[str
 				fileName += ".png"
 			}
 
-			imagePath := os.TempDir() + "/" + fileName
+			imagePath := tempDir() + "/" + fileName
 			defer os.Remove(imagePath)
 
 			os.WriteFile(imagePath, m.Picture().Data, 0664)
@@ -506,7 +531,10 @@ func ExtractTextFromJpeg(path string) (string, error) {
 	outputPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".txt"
 
 	// Run the Tesseract command-line tool
-	cmd := exec.Command("tesseract", path, strings.TrimSuffix(outputPath, ".txt"))
+	if err := RequireTool("tesseract"); err != nil {
+		return "", err
+	}
+	cmd := toolCommand(context.Background(), "tesseract", path, strings.TrimSuffix(outputPath, ".txt"))
 	cmd.Stderr = os.Stderr // Redirect errors to standard error
 	if err := cmd.Run(); err != nil {
 		return "", fmt.Errorf("failed to run tesseract: %w", err)
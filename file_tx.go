@@ -0,0 +1,333 @@
+// utility/file_tx.go
+package Utility
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// File transactions
+// -----------------
+// FileTransaction queues Copy/Move/Delete/Write operations and applies them
+// in order. Anything a step would overwrite or delete is first renamed to a
+// backup next to it, and every step is recorded in a journal file before and
+// after it runs. When a step fails, the applied steps are undone in reverse
+// order; after a crash, RecoverFileTransaction replays the journal backwards.
+
+type fileOpKind string
+
+const (
+	fileOpCopy   fileOpKind = "copy"
+	fileOpMove   fileOpKind = "move"
+	fileOpDelete fileOpKind = "delete"
+	fileOpWrite  fileOpKind = "write"
+)
+
+type fileOp struct {
+	kind fileOpKind
+	src  string
+	dst  string
+	data []byte
+	perm os.FileMode
+}
+
+// journalEntry is one line of the transaction journal.
+type journalEntry struct {
+	Seq    int        `json:"seq"`
+	Op     fileOpKind `json:"op"`
+	Src    string     `json:"src,omitempty"`
+	Dst    string     `json:"dst"`
+	Backup string     `json:"backup,omitempty"`
+	Done   bool       `json:"done"`
+}
+
+// FileTransaction is a batch of file operations applied all-or-nothing.
+type FileTransaction struct {
+	// JournalPath is where steps are logged; empty uses a file in the temp dir.
+	JournalPath string
+
+	id      string
+	ops     []fileOp
+	applied []journalEntry
+	journal *os.File
+}
+
+// NewFileTransaction creates an empty transaction.
+func NewFileTransaction() *FileTransaction {
+	return &FileTransaction{id: RandomUUID()}
+}
+
+// Copy queues a copy of the file or directory src to dst.
+func (tx *FileTransaction) Copy(src, dst string) *FileTransaction {
+	tx.ops = append(tx.ops, fileOp{kind: fileOpCopy, src: src, dst: dst})
+	return tx
+}
+
+// Move queues a move of src to dst.
+func (tx *FileTransaction) Move(src, dst string) *FileTransaction {
+	tx.ops = append(tx.ops, fileOp{kind: fileOpMove, src: src, dst: dst})
+	return tx
+}
+
+// Delete queues the removal of a file or directory.
+func (tx *FileTransaction) Delete(path string) *FileTransaction {
+	tx.ops = append(tx.ops, fileOp{kind: fileOpDelete, dst: path})
+	return tx
+}
+
+// Write queues writing data to path.
+func (tx *FileTransaction) Write(path string, data []byte, perm os.FileMode) *FileTransaction {
+	tx.ops = append(tx.ops, fileOp{kind: fileOpWrite, dst: path, data: data, perm: perm})
+	return tx
+}
+
+// Execute applies the queued operations. On failure the applied steps are
+// rolled back and the step error is returned (joined with any rollback
+// error). On success backups and the journal are removed.
+func (tx *FileTransaction) Execute() (err error) {
+	defer endOperation(startOperation("file.transaction", map[string]interface{}{"id": tx.id, "ops": len(tx.ops)}), &err)
+
+	if tx.JournalPath == "" {
+		tx.JournalPath = filepath.Join(tempDir(), "filetx-"+tx.id+".journal")
+	}
+	tx.journal, err = os.OpenFile(tx.JournalPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+
+	for i, op := range tx.ops {
+		entry := journalEntry{Seq: i, Op: op.kind, Src: op.src, Dst: op.dst}
+		if _, err := os.Lstat(op.dst); err == nil {
+			// per step: a later step on the same path must not overwrite
+			// the backup of the original
+			entry.Backup = fmt.Sprintf("%s.txbak-%s-%d", op.dst, tx.id[:8], i)
+		}
+		if err = tx.log(entry); err != nil {
+			return tx.abort(fmt.Errorf("journal: %w", err))
+		}
+		// recorded before applying so a partial step is undone as well
+		tx.applied = append(tx.applied, entry)
+		if err = applyFileOp(op, entry.Backup); err != nil {
+			return tx.abort(fmt.Errorf("%s %s: %w", op.kind, op.dst, err))
+		}
+		entry.Done = true
+		if err = tx.log(entry); err != nil {
+			return tx.abort(fmt.Errorf("journal: %w", err))
+		}
+	}
+	return tx.commit()
+}
+
+// Rollback undoes the applied steps in reverse order and removes the journal.
+func (tx *FileTransaction) Rollback() error {
+	var errs []error
+	for i := len(tx.applied) - 1; i >= 0; i-- {
+		if err := undoFileOp(tx.applied[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	tx.applied = nil
+	tx.closeJournal(len(errs) == 0)
+	return errors.Join(errs...)
+}
+
+func (tx *FileTransaction) abort(err error) error {
+	if rbErr := tx.Rollback(); rbErr != nil {
+		return errors.Join(err, fmt.Errorf("rollback: %w", rbErr))
+	}
+	return err
+}
+
+func (tx *FileTransaction) commit() error {
+	for _, e := range tx.applied {
+		if e.Backup != "" {
+			os.RemoveAll(e.Backup)
+		}
+	}
+	tx.applied = nil
+	tx.closeJournal(true)
+	return nil
+}
+
+func (tx *FileTransaction) log(e journalEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.journal.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	return tx.journal.Sync()
+}
+
+func (tx *FileTransaction) closeJournal(remove bool) {
+	if tx.journal != nil {
+		tx.journal.Close()
+		tx.journal = nil
+	}
+	if remove {
+		os.Remove(tx.JournalPath)
+	}
+}
+
+// RecoverFileTransaction undoes the steps recorded in the journal of a
+// transaction interrupted by a crash, then removes the journal.
+func RecoverFileTransaction(journalPath string) error {
+	f, err := os.Open(journalPath)
+	if err != nil {
+		return err
+	}
+	entries := make(map[int]journalEntry)
+	order := make([]int, 0)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e journalEntry
+		if json.Unmarshal(sc.Bytes(), &e) != nil {
+			continue // torn final line
+		}
+		if _, seen := entries[e.Seq]; !seen {
+			order = append(order, e.Seq)
+		}
+		entries[e.Seq] = e
+	}
+	f.Close()
+
+	var errs []error
+	for i := len(order) - 1; i >= 0; i-- {
+		if err := undoFileOp(entries[order[i]]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		os.Remove(journalPath)
+	}
+	return errors.Join(errs...)
+}
+
+// ---------------------------
+// Steps
+// ---------------------------
+
+// applyFileOp moves whatever is at op.dst to backup (when set), then applies op.
+func applyFileOp(op fileOp, backup string) error {
+	if backup != "" {
+		if _, err := os.Lstat(op.dst); err == nil {
+			if err := os.Rename(op.dst, backup); err != nil {
+				return err
+			}
+		}
+	}
+	switch op.kind {
+	case fileOpDelete:
+		return nil // the backup rename was the deletion
+	case fileOpWrite:
+		if err := os.MkdirAll(filepath.Dir(op.dst), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(op.dst, op.data, op.perm)
+	case fileOpCopy:
+		return copyTree(op.src, op.dst)
+	case fileOpMove:
+		if err := os.MkdirAll(filepath.Dir(op.dst), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(op.src, op.dst); err == nil {
+			return nil
+		}
+		// cross-device: copy then remove the source
+		if err := copyTree(op.src, op.dst); err != nil {
+			return err
+		}
+		return os.RemoveAll(op.src)
+	}
+	return fmt.Errorf("unknown operation %q", op.kind)
+}
+
+// undoFileOp reverts one (possibly partially) applied step. A step with a
+// backup whose backup isn't there never got past moving the original aside
+// (or was already undone), so its destination is left alone.
+func undoFileOp(e journalEntry) error {
+	if e.Backup != "" {
+		if _, err := os.Lstat(e.Backup); err != nil {
+			return nil
+		}
+	}
+	switch e.Op {
+	case fileOpMove:
+		if _, err := os.Lstat(e.Src); os.IsNotExist(err) {
+			if _, err := os.Lstat(e.Dst); err == nil {
+				if err := os.Rename(e.Dst, e.Src); err != nil {
+					if err := copyTree(e.Dst, e.Src); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		os.RemoveAll(e.Dst)
+	case fileOpCopy, fileOpWrite:
+		os.RemoveAll(e.Dst)
+	}
+	if e.Backup != "" {
+		return os.Rename(e.Backup, e.Dst)
+	}
+	return nil
+}
+
+// copyTree copies a file or directory recursively, preserving modes.
+func copyTree(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return copyFileMode(src, dst, info.Mode().Perm())
+	}
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		target := filepath.Join(dst, rel)
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(target, fi.Mode().Perm())
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		}
+		return copyFileMode(path, target, fi.Mode().Perm())
+	})
+}
+
+func copyFileMode(src, dst string, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,90 @@
+// utility/filelock.go
+package Utility
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// File locks
+// ----------
+// LockFile serializes access to a file shared by several processes (and
+// goroutines) with an advisory lock: flock on Unix, LockFileEx on Windows.
+// The lock is taken on a companion file, path+".lock", so writers may still
+// replace path atomically (write a temporary file, then rename) and readers
+// on Windows aren't blocked by a byte-range lock. Only code that locks is
+// kept out; the lock is released if the process dies. Systems without flock
+// (Solaris, AIX, ...) only have fcntl locks, which are released when any
+// descriptor of the file is closed and so can't keep goroutines apart; there
+// LockFile and TryLock fail with errors.ErrUnsupported.
+//
+//	unlock, err := LockFile(configPath)
+//	if err != nil {
+//		return err
+//	}
+//	defer unlock()
+
+// ErrLocked is returned by TryLock when the lock is still held by someone
+// else once the timeout expired.
+var ErrLocked = errors.New("file is locked")
+
+// LockFile takes the exclusive lock of path, waiting for as long as it is
+// held. The returned function releases it; calling it again does nothing.
+func LockFile(path string) (unlock func(), err error) {
+	defer endOperation(startOperation("fs.lock", map[string]interface{}{"path": path}), &err)
+	f, err := openLockFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f, true); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return unlocker(f), nil
+}
+
+// TryLock is LockFile giving up after timeout with ErrLocked; a zero
+// timeout tries once.
+func TryLock(path string, timeout time.Duration) (unlock func(), err error) {
+	defer endOperation(startOperation("fs.trylock", map[string]interface{}{"path": path}), &err)
+	f, err := openLockFile(path)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(timeout)
+	delay := 5 * time.Millisecond
+	for {
+		err := lockFile(f, false)
+		if err == nil {
+			return unlocker(f), nil
+		}
+		if !errors.Is(err, ErrLocked) {
+			f.Close()
+			return nil, err
+		}
+		left := time.Until(deadline)
+		if left <= 0 {
+			f.Close()
+			return nil, ErrLocked
+		}
+		time.Sleep(min(delay, left))
+		delay = min(2*delay, 100*time.Millisecond)
+	}
+}
+
+func openLockFile(path string) (*os.File, error) {
+	return os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0644)
+}
+
+// unlocker releases the lock held on f and closes it, once.
+func unlocker(f *os.File) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			unlockFile(f)
+			f.Close()
+		})
+	}
+}
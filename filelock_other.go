@@ -0,0 +1,17 @@
+// utility/filelock_other.go
+//go:build !windows && !darwin && !dragonfly && !freebsd && !illumos && !linux && !netbsd && !openbsd
+
+package Utility
+
+import (
+	"errors"
+	"os"
+)
+
+func lockFile(f *os.File, wait bool) error {
+	return &os.PathError{Op: "lock", Path: f.Name(), Err: errors.ErrUnsupported}
+}
+
+func unlockFile(f *os.File) error {
+	return errors.ErrUnsupported
+}
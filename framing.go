@@ -0,0 +1,103 @@
+// utility/framing.go
+package Utility
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"sync"
+)
+
+// Length-prefixed message framing
+// -------------------------------
+// A frame is an 8-byte header followed by the payload:
+//
+//	[ length uint32 BE ][ crc32 (Castagnoli) of payload uint32 BE ][ payload ]
+//
+// ReadFrame refuses frames larger than maxSize before allocating, so a corrupt
+// or hostile peer cannot make the reader allocate arbitrary memory.
+
+// DefaultMaxFrameSize is the payload limit used when none is given.
+const DefaultMaxFrameSize = 16 << 20
+
+const frameHeaderSize = 8
+
+var (
+	// ErrFrameTooLarge is returned when a frame exceeds the size limit.
+	ErrFrameTooLarge = errors.New("frame exceeds maximum size")
+	// ErrFrameChecksum is returned when a payload does not match its checksum.
+	ErrFrameChecksum = errors.New("frame checksum mismatch")
+)
+
+var frameTable = crc32.MakeTable(crc32.Castagnoli)
+
+// WriteFrame writes payload as a single frame.
+func WriteFrame(w io.Writer, payload []byte) error {
+	if uint64(len(payload)) > 0xFFFFFFFF {
+		return ErrFrameTooLarge
+	}
+	buf := make([]byte, frameHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.Checksum(payload, frameTable))
+	copy(buf[frameHeaderSize:], payload)
+	_, err := w.Write(buf)
+	return err
+}
+
+// ReadFrame reads one frame and returns its payload. maxSize <= 0 uses
+// DefaultMaxFrameSize. io.EOF is returned only on a clean frame boundary.
+func ReadFrame(r io.Reader, maxSize int) ([]byte, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxFrameSize
+	}
+	var hdr [frameHeaderSize]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(hdr[0:4])
+	if uint64(size) > uint64(maxSize) {
+		return nil, fmt.Errorf("%w: %d > %d bytes", ErrFrameTooLarge, size, maxSize)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if crc32.Checksum(payload, frameTable) != binary.BigEndian.Uint32(hdr[4:8]) {
+		return nil, ErrFrameChecksum
+	}
+	return payload, nil
+}
+
+// FrameConn exchanges frames over a net.Conn. Writes are serialized, so
+// several goroutines may send concurrently; reads must come from one goroutine.
+type FrameConn struct {
+	net.Conn
+	MaxFrameSize int
+
+	r  *bufio.Reader
+	wm sync.Mutex
+}
+
+// NewFrameConn wraps conn with DefaultMaxFrameSize.
+func NewFrameConn(conn net.Conn) *FrameConn {
+	return &FrameConn{Conn: conn, MaxFrameSize: DefaultMaxFrameSize, r: bufio.NewReader(conn)}
+}
+
+// WriteFrame sends payload as one frame.
+func (c *FrameConn) WriteFrame(payload []byte) error {
+	c.wm.Lock()
+	defer c.wm.Unlock()
+	return WriteFrame(c.Conn, payload)
+}
+
+// ReadFrame receives the next frame.
+func (c *FrameConn) ReadFrame() ([]byte, error) {
+	return ReadFrame(c.r, c.MaxFrameSize)
+}
@@ -2,19 +2,23 @@
 package Utility
 
 import (
-	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"hash"
 	"io"
-	"io/ioutil"
 	"os"
-	"os/exec"
+	"path"
 	"path/filepath"
-	"runtime"
 	"strings"
+	"time"
 )
 
 // Copy copies src file to dst, overwriting dst if it exists.
-func Copy(src, dst string) error {
+func Copy(src, dst string) (err error) {
+	defer endOperation(startOperation("copy", map[string]interface{}{"src": src, "dst": dst}), &err)
+
 	in, err := os.Open(src)
 	if err != nil {
 		return err
@@ -34,163 +38,523 @@ func Copy(src, dst string) error {
 	return out.Close()
 }
 
-// CopyFile copies one file to another using `cp` command.
+// CopyFile copies one file to another, like cp: when dest is a directory
+// the copy is made inside it. Permissions and modification time are kept.
 func CopyFile(source string, dest string) (err error) {
-	cmd := exec.Command("cp", source, dest)
-	var out, stderr bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &stderr
-	err = cmd.Run()
+	defer endOperation(startOperation("copy.file", map[string]interface{}{"src": source, "dst": dest}), &err)
+	if fi, err := os.Stat(dest); err == nil && fi.IsDir() {
+		dest = filepath.Join(dest, filepath.Base(source))
+	}
+	info, err := os.Stat(source)
 	if err != nil {
-		fmt.Println(fmt.Sprint(err) + ": " + stderr.String())
+		return err
 	}
-	return err
+	return copyRegularFile(source, dest, info)
+}
+
+// CopyDir recursively copies one directory into another, like `cp -R`.
+//
+// Deprecated: use CopyDirCtx, which can be cancelled.
+func CopyDir(source string, dest string) error {
+	return CopyDirCtx(context.Background(), source, dest)
+}
+
+// CopyDirCtx is CopyDir bounded by ctx: source is copied to
+// dest/<base name of source>, dest being created if needed. It honors
+// WithTimeout. CopyTree copies the contents of a directory with filters.
+func CopyDirCtx(ctx context.Context, source string, dest string, opts ...Option) (err error) {
+	defer endOperation(startOperation("copy.dir", map[string]interface{}{"src": source, "dst": dest}), &err)
+	o := NewOptions(opts...)
+	ctx, cancel := o.Context(ctx)
+	defer cancel()
+
+	abs, err := filepath.Abs(source)
+	if err != nil {
+		return err
+	}
+	return CopyTree(ctx, source, filepath.Join(dest, filepath.Base(abs)), CopyTreeOptions{})
 }
 
-// CopyDir recursively copies one directory to another using `cp -R`.
-func CopyDir(source string, dest string) (err error) {
-	CreateDirIfNotExist(dest)
-	cmd := exec.Command("cp", "-R", source, dest)
-	var out, stderr bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &stderr
-	err = cmd.Run()
+// CopyTreeOptions configures CopyTree. Patterns are globs (see CompileGlob)
+// matched against the slash-separated path relative to the source
+// directory, or against the base name when they contain no '/'.
+type CopyTreeOptions struct {
+	Include        []string // only files matching one of them are copied
+	Exclude        []string // files and directories left out
+	FollowSymlinks bool     // copy what symbolic links point to rather than the links
+	PreserveOwner  bool     // give the copies the owner of the originals, when allowed (Unix)
+}
+
+// CopyTree copies the contents of the directory src into dst, creating it,
+// until ctx is done. Files keep their permissions and modification time and
+// symbolic links are recreated as they are (see CopySymLink); other special
+// files (devices, sockets, pipes) are skipped. Directories holding nothing
+// to copy are only created when Include is empty.
+func CopyTree(ctx context.Context, src, dst string, opts CopyTreeOptions) (err error) {
+	defer endOperation(startOperation("copy.tree", map[string]interface{}{"src": src, "dst": dst}), &err)
+	info, err := os.Stat(src)
 	if err != nil {
-		fmt.Println(fmt.Sprint(err) + ": " + stderr.String())
-	} else {
-		fmt.Println("Result: " + out.String())
+		return err
+	}
+	if !info.IsDir() {
+		return &GuardError{Field: "src", Reason: "not a directory: " + src}
+	}
+	c := &treeCopier{ctx: ctx, opts: opts, visiting: make(map[string]bool)}
+	if c.include, err = compileGlobs(opts.Include); err != nil {
+		return err
+	}
+	if c.exclude, err = compileGlobs(opts.Exclude); err != nil {
+		return err
 	}
+	if within(resolvePath(dst), resolvePath(src)) {
+		return &GuardError{Field: "dst", Reason: dst + " is inside the source " + src}
+	}
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	_, err = c.dir(src, dst, "", info)
 	return err
 }
 
-// Move copies and removes a file or directory. Uses rsync/mv depending on OS.
-func Move(source string, dest string) (err error) {
-	CreateDirIfNotExist(dest)
-	var out, stderr bytes.Buffer
-
-	if runtime.GOOS == "windows" {
-		rsync := exec.Command("mv", source, dest)
-		rsync.Stdout = &out
-		rsync.Stderr = &stderr
-		err = rsync.Run()
-		if err != nil {
-			fmt.Println(fmt.Sprint(err) + ": " + stderr.String())
-			return
-		}
-	} else {
-		rsync := exec.Command("rsync", "-a", source, dest)
-		rsync.Stdout = &out
-		rsync.Stderr = &stderr
-		err = rsync.Run()
-		if err != nil {
-			fmt.Println(fmt.Sprint(err) + ": " + stderr.String())
-			return
+type treeCopier struct {
+	ctx      context.Context
+	opts     CopyTreeOptions
+	include  []*GlobMatcher
+	exclude  []*GlobMatcher
+	visiting map[string]bool // resolved directories being copied, against symlink loops
+}
+
+// dir copies the entries of src into dst and reports whether dst exists
+// afterwards.
+func (c *treeCopier) dir(src, dst, rel string, info os.FileInfo) (bool, error) {
+	if real, err := filepath.EvalSymlinks(src); err == nil {
+		if c.visiting[real] {
+			return false, &GuardError{Field: "src", Reason: "symbolic link loop at " + src}
+		}
+		c.visiting[real] = true
+		defer delete(c.visiting, real)
+	}
+	_, err := os.Stat(dst)
+	made := err == nil
+	mkdir := func() error {
+		if made {
+			return nil
+		}
+		made = true
+		return os.MkdirAll(dst, 0700) // the real permissions once filled
+	}
+	if len(c.include) == 0 {
+		if err := mkdir(); err != nil {
+			return false, err
 		}
 	}
 
-	rm := exec.Command("rm", "-rf", source)
-	rm.Stdout = &out
-	rm.Stderr = &stderr
-	err = rm.Run()
+	entries, err := os.ReadDir(src)
 	if err != nil {
-		fmt.Println(fmt.Sprint(err) + ": " + stderr.String())
-		return
+		return made, err
 	}
-	fmt.Println("Result: " + out.String())
-	return nil
+	for _, e := range entries {
+		if err := c.ctx.Err(); err != nil {
+			return made, err
+		}
+		s, d, r := filepath.Join(src, e.Name()), filepath.Join(dst, e.Name()), path.Join(rel, e.Name())
+		if matchGlobs(c.exclude, r) {
+			continue
+		}
+		fi, err := os.Lstat(s)
+		if err != nil {
+			return made, err
+		}
+		if fi.Mode()&os.ModeSymlink != 0 && c.opts.FollowSymlinks {
+			if fi, err = os.Stat(s); err != nil {
+				return made, err
+			}
+		}
+
+		switch {
+		case fi.IsDir():
+			if len(c.include) == 0 {
+				if err := mkdir(); err != nil {
+					return made, err
+				}
+			}
+			sub, err := c.dir(s, d, r, fi)
+			if err != nil {
+				return made, err
+			}
+			made = made || sub
+			continue
+		case len(c.include) > 0 && !matchGlobs(c.include, r):
+			continue
+		case fi.Mode()&os.ModeSymlink != 0:
+			if err := mkdir(); err != nil {
+				return made, err
+			}
+			os.Remove(d)
+			if err := CopySymLink(s, d); err != nil {
+				return made, err
+			}
+		case fi.Mode().IsRegular():
+			if err := mkdir(); err != nil {
+				return made, err
+			}
+			if err := copyRegularFile(s, d, fi); err != nil {
+				return made, err
+			}
+		default:
+			continue
+		}
+		if c.opts.PreserveOwner {
+			copyOwner(d, fi)
+		}
+	}
+
+	if made {
+		os.Chmod(dst, info.Mode().Perm())
+		os.Chtimes(dst, time.Now(), info.ModTime())
+		if c.opts.PreserveOwner {
+			copyOwner(dst, info)
+		}
+	}
+	return made, nil
 }
 
-// MoveFile copies a file to destination then deletes the original.
-func MoveFile(source, destination string) (err error) {
-	src, err := os.Open(source)
-	if err != nil {
-		return err
+// copyRegularFile copies the file src, described by info, to dst with the
+// same permissions and modification time. It refuses to copy a file onto
+// itself, which would truncate it.
+func copyRegularFile(src, dst string, info os.FileInfo) error {
+	if fi, err := os.Stat(dst); err == nil && os.SameFile(info, fi) {
+		return &GuardError{Field: "dst", Reason: src + " and " + dst + " are the same file"}
 	}
-	defer src.Close()
-	fi, err := src.Stat()
+	in, err := os.Open(src)
 	if err != nil {
 		return err
 	}
-	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
-	perm := fi.Mode() & os.ModePerm
-	dst, err := os.OpenFile(destination, flag, perm)
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
 	if err != nil {
 		return err
 	}
-	defer dst.Close()
-	_, err = io.Copy(dst, src)
-	if err != nil {
-		dst.Close()
-		os.Remove(destination)
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
 		return err
 	}
-	if err = dst.Close(); err != nil {
+	if err := out.Close(); err != nil {
 		return err
 	}
-	if err = src.Close(); err != nil {
+	if err := os.Chmod(dst, info.Mode().Perm()); err != nil {
 		return err
 	}
-	if err = os.Remove(source); err != nil {
-		return err
+	return os.Chtimes(dst, time.Now(), info.ModTime())
+}
+
+// resolvePath returns p made absolute with the symbolic links of its longest
+// existing prefix resolved, so paths not created yet can be compared.
+func resolvePath(p string) string {
+	p, err := filepath.Abs(p)
+	if err != nil {
+		return filepath.Clean(p)
 	}
-	return nil
+	rest := ""
+	for {
+		if real, err := filepath.EvalSymlinks(p); err == nil {
+			return filepath.Join(real, rest)
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return filepath.Join(p, rest)
+		}
+		rest = filepath.Join(filepath.Base(p), rest)
+		p = parent
+	}
+}
+
+// within reports whether p is dir or below it; both must be clean and
+// absolute.
+func within(p, dir string) bool {
+	rel, err := filepath.Rel(dir, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// MoveError reports which step of a move failed. The source is removed only
+// once its copy is complete and verified, so it is left intact unless Op is
+// "remove".
+type MoveError struct {
+	Op  string // "rename", "copy", "verify" or "remove"
+	Src string
+	Dst string
+	Err error
 }
 
-// CompressDir compresses a directory into a .tar.gz written to buf.
-func CompressDir(src string, buf io.Writer) (int, error) {
-	src = strings.ReplaceAll(src, "\\", "/")
-	tmp := RandomUUID() + ".tar.gz"
-	defer os.Remove(tmp)
+func (e *MoveError) Error() string {
+	return "move " + e.Src + " to " + e.Dst + ": " + e.Op + ": " + e.Err.Error()
+}
 
-	args := []string{"-czvf", tmp, "-C", src, "."}
-	cmd := exec.Command("tar", args...)
-	cmd.Dir = os.TempDir()
+func (e *MoveError) Unwrap() error { return e.Err }
 
-	var out, stderr bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &stderr
-	err := cmd.Run()
+// Move moves a file or directory into the directory dest, creating it:
+// source ends up at dest/<base name of source>. A directory already there
+// is merged into. Across devices, source is copied with its metadata,
+// verified, then removed. Failures are returned as *MoveError.
+func Move(source string, dest string) (err error) {
+	defer endOperation(startOperation("move", map[string]interface{}{"src": source, "dst": dest}), &err)
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return &MoveError{Op: "rename", Src: source, Dst: dest, Err: err}
+	}
+	abs, err := filepath.Abs(source)
 	if err != nil {
-		fmt.Println("tar", "-czvf", tmp, "-C", src, ".")
-		fmt.Println("fail to compress file with error: ", fmt.Sprint(err)+": "+stderr.String())
-		return -1, err
+		return &MoveError{Op: "rename", Src: source, Dst: dest, Err: err}
 	}
+	return moveEntry(source, filepath.Join(dest, filepath.Base(abs)))
+}
 
-	data, err := ioutil.ReadFile(filepath.Join(os.TempDir(), tmp))
+// MoveFile moves the file source to destination, replacing it. Across
+// devices the file is copied, verified, then removed.
+func MoveFile(source, destination string) (err error) {
+	defer endOperation(startOperation("move.file", map[string]interface{}{"src": source, "dst": destination}), &err)
+	if fi, err := os.Stat(source); err == nil && fi.IsDir() {
+		return &GuardError{Field: "source", Reason: "is a directory: " + source}
+	}
+	return moveEntry(source, destination)
+}
+
+// moveEntry renames src to dst, falling back to copy, verify and remove when
+// they are on different devices or dst is a directory to merge into.
+func moveEntry(src, dst string) error {
+	info, err := os.Lstat(src)
 	if err != nil {
+		return &MoveError{Op: "rename", Src: src, Dst: dst, Err: err}
+	}
+	dstInfo, statErr := os.Lstat(dst)
+	merge := statErr == nil && dstInfo.IsDir() && info.IsDir()
+	if !merge {
+		err := os.Rename(src, dst)
+		if err == nil {
+			return nil
+		}
+		if !isCrossDevice(err) {
+			return &MoveError{Op: "rename", Src: src, Dst: dst, Err: err}
+		}
+	}
+
+	switch {
+	case info.IsDir():
+		err = CopyTree(context.Background(), src, dst, CopyTreeOptions{PreserveOwner: true})
+	case info.Mode()&os.ModeSymlink != 0:
+		os.Remove(dst)
+		err = CopySymLink(src, dst)
+	default:
+		if err = CopyFileEx(context.Background(), src, dst, CopyOptions{}); err == nil {
+			copyOwner(dst, info)
+		}
+	}
+	if err != nil {
+		if statErr != nil {
+			os.RemoveAll(dst) // only what this move created
+		}
+		return &MoveError{Op: "copy", Src: src, Dst: dst, Err: err}
+	}
+	if err := verifyCopy(src, dst); err != nil {
+		return &MoveError{Op: "verify", Src: src, Dst: dst, Err: err}
+	}
+	if err := os.RemoveAll(src); err != nil {
+		return &MoveError{Op: "remove", Src: src, Dst: dst, Err: err}
+	}
+	return nil
+}
+
+// verifyCopy checks that every regular file under src has an identical copy
+// at the same place under dst.
+func verifyCopy(src, dst string) error {
+	return filepath.Walk(src, func(p string, fi os.FileInfo, err error) error {
+		if err != nil || !fi.Mode().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		ti, err := os.Stat(target)
+		if err != nil {
+			return err
+		}
+		if ti.Size() != fi.Size() {
+			return fmt.Errorf("%s: size %d, want %d", target, ti.Size(), fi.Size())
+		}
+		want, err := hashFile(p, sha256.New)
+		if err != nil {
+			return err
+		}
+		got, err := hashFile(target, sha256.New)
+		if err != nil {
+			return err
+		}
+		if got != want {
+			return fmt.Errorf("%s differs from %s", target, p)
+		}
+		return nil
+	})
+}
+
+// CompressDir compresses a directory into a .tar.gz written to buf, with
+// entries named like those of tar -C src . (see Archive).
+func CompressDir(src string, buf io.Writer) (n int, err error) {
+	defer endOperation(startOperation("archive.compress", map[string]interface{}{"src": src}), &err)
+	cw := &countingWriter{w: buf}
+	if err := Archive(src, cw, ArchiveTarGz, ArchiveOptions{Prefix: "."}); err != nil {
 		return -1, err
 	}
-	buf.Write(data)
-	return len(data), nil
+	return int(cw.n), nil
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
 }
 
 // ExtractTarGz extracts a tar.gz archive and returns the path to the extracted dir.
+//
+// Deprecated: use ExtractTarGzCtx, which can be cancelled.
 func ExtractTarGz(r io.Reader) (string, error) {
-	tmpDir := strings.ReplaceAll(os.TempDir(), "\\", "/")
+	return ExtractTarGzCtx(context.Background(), r)
+}
 
-	buf, err := ioutil.ReadAll(r)
-	if err != nil {
+// ExtractTarGzCtx is ExtractTarGz bounded by ctx. The archive is extracted
+// into a new temporary directory without its first path element, like
+// tar --strip-components 1; Extract gives more control.
+func ExtractTarGzCtx(ctx context.Context, r io.Reader) (extracted string, err error) {
+	output := filepath.Join(tempDir(), RandomUUID())
+	if err := extractArchive(ctx, r, output, ArchiveOptions{StripComponents: 1}); err != nil {
+		os.RemoveAll(output)
 		return "", err
 	}
+	return output, nil
+}
+
+// CopyOptions configures CopyFileEx.
+type CopyOptions struct {
+	Progress *Progress // counts the bytes copied, completed once dst is in place
+	Checksum string    // expected digest of src, "algo:hex" as in DownloadOptions
+	Verify   bool      // re-read dst and compare it with what was read from src
+}
+
+// CopyWithProgress copies the file src to dst, reporting the bytes copied to
+// progress. See CopyFileEx.
+func CopyWithProgress(ctx context.Context, src, dst string, progress *Progress) error {
+	return CopyFileEx(ctx, src, dst, CopyOptions{Progress: progress})
+}
+
+// CopyFileEx copies the file src to dst, overwriting it, until ctx is done.
+// Permissions and modification time are preserved. The data goes to
+// dst+".part" first and is renamed into place once complete and verified,
+// so a cancelled or failed copy never leaves a truncated dst.
+func CopyFileEx(ctx context.Context, src, dst string, opts CopyOptions) (err error) {
+	defer endOperation(startOperation("copy.progress", map[string]interface{}{"src": src, "dst": dst}), &err)
+
+	var newHash func() hash.Hash
+	var want string
+	if opts.Checksum != "" {
+		if newHash, want, err = parseChecksum(opts.Checksum); err != nil {
+			return err
+		}
+	} else if opts.Verify {
+		newHash = sha256.New
+	}
 
-	archive := RandomUUID() + ".tar.gz"
-	err = ioutil.WriteFile(filepath.Join(tmpDir, archive), buf, 0777)
+	in, err := os.Open(src)
 	if err != nil {
-		return "", err
+		return err
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return &GuardError{Field: "src", Reason: "is a directory: " + src}
 	}
 
-	output := filepath.Join(tmpDir, RandomUUID())
-	CreateDirIfNotExist(output)
+	part := dst + ".part"
+	out, err := os.OpenFile(part, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			out.Close()
+			os.Remove(part)
+		}
+	}()
 
-	wait := make(chan error)
-	args := []string{"-xvzf", archive, "-C", output, "--strip-components", "1"}
-	RunCmd("tar", tmpDir, args, wait)
+	var h hash.Hash
+	w := io.Writer(out)
+	if newHash != nil {
+		h = newHash()
+		w = io.MultiWriter(out, h)
+	}
+	if opts.Progress != nil {
+		opts.Progress.SetTotal(fi.Size())
+	}
+	dw := &downloadWriter{w: w, ctx: ctx, opts: &DownloadOptions{Progress: opts.Progress}, start: time.Now()}
+	buf := make([]byte, 1<<20)
+	for {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		n, rerr := in.Read(buf)
+		if n > 0 {
+			if _, err = dw.Write(buf[:n]); err != nil {
+				return err
+			}
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return rerr
+		}
+	}
+	if err = out.Sync(); err != nil {
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
 
-	if err = <-wait; err != nil {
-		fmt.Println("fail to run: tar ", args)
-		return "", err
+	if h != nil {
+		got := hex.EncodeToString(h.Sum(nil))
+		if want != "" && got != want {
+			return fmt.Errorf("checksum mismatch for %s: got %s, want %s", src, got, want)
+		}
+		if opts.Verify {
+			written, err := hashFile(part, newHash)
+			if err != nil {
+				return err
+			}
+			if written != got {
+				return fmt.Errorf("verification of %s failed: the copy differs from the source", dst)
+			}
+		}
 	}
-	fmt.Println("archive is extracted at ", output, err)
-	return output, nil
+	if err = os.Chmod(part, fi.Mode().Perm()); err != nil { // not subject to the umask
+		return err
+	}
+	if err = os.Chtimes(part, time.Now(), fi.ModTime()); err != nil {
+		return err
+	}
+	if err = os.Rename(part, dst); err != nil {
+		return err
+	}
+	if opts.Progress != nil {
+		opts.Progress.Done()
+	}
+	return nil
 }
-
@@ -0,0 +1,24 @@
+// utility/fs_copy_unix.go
+//go:build !windows
+
+package Utility
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// copyOwner gives path the owner and group of info, when allowed; links
+// themselves are changed, not their targets.
+func copyOwner(path string, info os.FileInfo) {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		os.Lchown(path, int(st.Uid), int(st.Gid))
+	}
+}
+
+// isCrossDevice reports whether a rename failed because source and
+// destination are on different file systems.
+func isCrossDevice(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
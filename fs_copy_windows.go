@@ -0,0 +1,19 @@
+// utility/fs_copy_windows.go
+//go:build windows
+
+package Utility
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// copyOwner is a no-op: Windows ownership is not carried by os.FileInfo.
+func copyOwner(path string, info os.FileInfo) {}
+
+// isCrossDevice reports whether a rename failed because source and
+// destination are on different volumes.
+func isCrossDevice(err error) bool {
+	return errors.Is(err, syscall.Errno(17)) // ERROR_NOT_SAME_DEVICE
+}
@@ -0,0 +1,132 @@
+// utility/func_schema.go
+package Utility
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Typed function registration
+// ---------------------------
+// RegisterTypedFunction registers a function like RegisterFunction and
+// derives JSON Schemas for its parameters and results once, with the same
+// generator as GenerateSchema, so a console can render an invocation form
+// for it. Parameters are described as an object with one property per
+// parameter name; ParamNames gives their order for CallFunction.
+
+// FuncSchema describes how to call a function registered with
+// RegisterTypedFunction.
+type FuncSchema struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	ParamNames  []string               `json:"paramNames"`        // positional order of the parameters
+	Params      map[string]interface{} `json:"params"`            // object schema, one property per parameter
+	Returns     map[string]interface{} `json:"returns,omitempty"` // array schema of the results, error excepted
+	ReturnsErr  bool                   `json:"returnsError,omitempty"`
+	// Defs holds the struct types referenced ("#/$defs/name") by Params
+	// and Returns, which are resolved against the FuncSchema document.
+	Defs map[string]interface{} `json:"$defs,omitempty"`
+
+	fnType reflect.Type
+}
+
+// RegisterTypedFunc registers fn under name with its metadata and schemas.
+// Unnamed parameters are called arg0, arg1, ...
+func (tm *TypeManager) RegisterTypedFunc(name string, fn interface{}, meta FuncMeta) error {
+	ft := reflect.TypeOf(fn)
+	if ft == nil || ft.Kind() != reflect.Func {
+		return &GuardError{Field: "fn", Reason: "must be a function"}
+	}
+	if len(meta.ParamNames) > ft.NumIn() {
+		return &GuardError{Field: "ParamNames", Reason: "more names than the " + strconv.Itoa(ft.NumIn()) + " parameters"}
+	}
+
+	g := &schemaGenerator{tm: tm, defs: make(map[string]interface{}), seen: make(map[reflect.Type]string)}
+	s := &FuncSchema{Name: name, Description: meta.Description, ParamNames: make([]string, 0, ft.NumIn()), fnType: ft}
+	properties := make(map[string]interface{})
+	required := make([]string, 0)
+	for i := 0; i < ft.NumIn(); i++ {
+		pn := "arg" + strconv.Itoa(i)
+		if i < len(meta.ParamNames) && meta.ParamNames[i] != "" {
+			pn = meta.ParamNames[i]
+		}
+		if _, dup := properties[pn]; dup {
+			return &GuardError{Field: "ParamNames", Reason: "duplicate name " + pn}
+		}
+		s.ParamNames = append(s.ParamNames, pn)
+		pt := ft.In(i)
+		properties[pn] = g.typeSchema(pt)
+		switch {
+		case ft.IsVariadic() && i == ft.NumIn()-1:
+		case pt.Kind() == reflect.Ptr, pt.Kind() == reflect.Interface, pt.Kind() == reflect.Map:
+		default:
+			required = append(required, pn)
+		}
+	}
+	s.Params = map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		s.Params["required"] = required
+	}
+
+	var results []interface{}
+	for i := 0; i < ft.NumOut(); i++ {
+		if ft.Out(i) == errorType {
+			s.ReturnsErr = true
+			continue
+		}
+		rs := g.typeSchema(ft.Out(i))
+		if i < len(meta.ReturnNames) && meta.ReturnNames[i] != "" {
+			rs["title"] = meta.ReturnNames[i]
+		}
+		results = append(results, rs)
+	}
+	if len(results) > 0 {
+		s.Returns = map[string]interface{}{"type": "array", "prefixItems": results, "items": false}
+	}
+	if len(g.defs) > 0 {
+		s.Defs = g.defs
+	}
+
+	meta.ParamNames = s.ParamNames
+	tm.RegisterFunc(name, fn)
+	tm.SetFuncMeta(name, meta)
+	tm.mu.Lock()
+	if tm.funcSchemas == nil {
+		tm.funcSchemas = make(map[string]*FuncSchema)
+	}
+	tm.funcSchemas[name] = s
+	tm.mu.Unlock()
+	return nil
+}
+
+// ListFuncSchemas returns the schemas of the functions registered typed,
+// sorted by name. Functions replaced since by an untyped registration are
+// left out.
+func (tm *TypeManager) ListFuncSchemas() []*FuncSchema {
+	tm.mu.RLock()
+	out := make([]*FuncSchema, 0, len(tm.funcSchemas))
+	for name, s := range tm.funcSchemas {
+		if reflect.TypeOf(tm.functionRegistry[name]) == s.fnType {
+			out = append(out, s)
+		}
+	}
+	tm.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// RegisterTypedFunction registers a function with its schemas in the
+// default TypeManager.
+func RegisterTypedFunction(name string, fn interface{}, meta FuncMeta) error {
+	return DefaultTypeManager().RegisterTypedFunc(name, fn, meta)
+}
+
+// ListFunctionSchemas lists the typed functions of the default TypeManager.
+func ListFunctionSchemas() []*FuncSchema {
+	return DefaultTypeManager().ListFuncSchemas()
+}
@@ -0,0 +1,159 @@
+// utility/funcmeta.go
+package Utility
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Function registry metadata and introspection
+// --------------------------------------------
+
+// FuncMeta is optional, human-provided metadata for a registered function.
+type FuncMeta struct {
+	Description string
+	ParamNames  []string // one name per parameter, in order
+	ReturnNames []string // optional names/descriptions for results
+}
+
+// FuncParam describes one parameter or result of a registered function.
+type FuncParam struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Kind     string `json:"kind"`
+	Variadic bool   `json:"variadic,omitempty"`
+}
+
+// FuncDescriptor is the machine-readable signature of a registered function.
+type FuncDescriptor struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Params      []FuncParam `json:"params"`
+	Returns     []FuncParam `json:"returns"`
+	Variadic    bool        `json:"variadic,omitempty"`
+	ReturnsErr  bool        `json:"returnsError,omitempty"`
+}
+
+// SetFuncMeta attaches metadata to a function name.
+func (tm *TypeManager) SetFuncMeta(name string, meta FuncMeta) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.functionMeta[name] = meta
+}
+
+// GetFuncMeta returns the metadata attached to a function name.
+func (tm *TypeManager) GetFuncMeta(name string) (FuncMeta, bool) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	m, ok := tm.functionMeta[name]
+	return m, ok
+}
+
+// DescribeFunc returns the signature of a registered function, merged with
+// its metadata. Unnamed parameters are called arg0, arg1, ...
+func (tm *TypeManager) DescribeFunc(name string) (*FuncDescriptor, error) {
+	fn, ok := tm.GetFunc(name)
+	if !ok {
+		return nil, errors.New("no function was register with name " + name)
+	}
+	ft := reflect.TypeOf(fn)
+	if ft == nil || ft.Kind() != reflect.Func {
+		return nil, errors.New(name + " is not a function")
+	}
+	meta, _ := tm.GetFuncMeta(name)
+	return describeSignature(name, ft, meta), nil
+}
+
+// describeSignature describes the function type ft, naming its parameters
+// and results from meta.
+func describeSignature(name string, ft reflect.Type, meta FuncMeta) *FuncDescriptor {
+	d := &FuncDescriptor{
+		Name:        name,
+		Description: meta.Description,
+		Params:      make([]FuncParam, ft.NumIn()),
+		Returns:     make([]FuncParam, ft.NumOut()),
+		Variadic:    ft.IsVariadic(),
+	}
+	for i := 0; i < ft.NumIn(); i++ {
+		p := FuncParam{Name: "arg" + strconv.Itoa(i), Type: ft.In(i).String(), Kind: ft.In(i).Kind().String()}
+		if i < len(meta.ParamNames) && meta.ParamNames[i] != "" {
+			p.Name = meta.ParamNames[i]
+		}
+		if ft.IsVariadic() && i == ft.NumIn()-1 {
+			p.Variadic = true
+		}
+		d.Params[i] = p
+	}
+	for i := 0; i < ft.NumOut(); i++ {
+		r := FuncParam{Name: "result" + strconv.Itoa(i), Type: ft.Out(i).String(), Kind: ft.Out(i).Kind().String()}
+		if i < len(meta.ReturnNames) && meta.ReturnNames[i] != "" {
+			r.Name = meta.ReturnNames[i]
+		}
+		if ft.Out(i) == errorType {
+			d.ReturnsErr = true
+		}
+		d.Returns[i] = r
+	}
+	return d
+}
+
+// DescribeFuncs describes every registered function, sorted by name.
+func (tm *TypeManager) DescribeFuncs() []*FuncDescriptor {
+	names := tm.ListFuncs()
+	sort.Strings(names)
+	out := make([]*FuncDescriptor, 0, len(names))
+	for _, n := range names {
+		if d, err := tm.DescribeFunc(n); err == nil {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// DescribeFunction describes a function registered in the default TypeManager.
+func DescribeFunction(name string) (*FuncDescriptor, error) {
+	return DefaultTypeManager().DescribeFunc(name)
+}
+
+// DescribeMethods describes the exported methods CallMethod can call on
+// instance (those of T and *T), sorted by name; nil for a nil instance.
+func DescribeMethods(instance interface{}) []*FuncDescriptor {
+	t := reflect.TypeOf(instance)
+	if t == nil {
+		return nil
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	recv := reflect.New(t)
+	out := make([]*FuncDescriptor, 0, recv.NumMethod())
+	for i := 0; i < recv.NumMethod(); i++ {
+		out = append(out, describeSignature(recv.Type().Method(i).Name, recv.Method(i).Type(), FuncMeta{}))
+	}
+	return out
+}
+
+// HasMethod reports whether the type of instance has a method name (on T
+// or *T) accepting argCount arguments; a negative argCount accepts any
+// number.
+func HasMethod(instance interface{}, name string, argCount int) bool {
+	t := reflect.TypeOf(instance)
+	if t == nil {
+		return false
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	plan := methodPlan(t, name)
+	switch {
+	case plan == nil:
+		return false
+	case argCount < 0:
+		return true
+	case plan.args.variadic:
+		return argCount >= plan.args.numIn-1
+	}
+	return argCount == plan.args.numIn
+}
@@ -0,0 +1,91 @@
+// utility/generics.go
+package Utility
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Generics-based typed registry API
+// ---------------------------------
+// Thin, compile-time checked wrappers over the reflect-based registry.
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// TypeNameOf returns the registry name RegisterType would use for T.
+func TypeNameOf[T any]() string {
+	return typeNameOf(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+// Register registers T with the TypeManager and gob (see RegisterType).
+func Register[T any]() {
+	RegisterType((*T)(nil))
+}
+
+// NewInstance returns a new *T, with TYPENAME set when the field exists.
+func NewInstance[T any]() *T {
+	v := new(T)
+	SetProperty(v, conventionsOfType(reflect.TypeOf(v)).TypeKey, TypeNameOf[T]())
+	return v
+}
+
+// FromBytesAs decodes data produced by ToBytes (same codec) into a *T.
+func FromBytesAs[T any](data []byte) (*T, error) {
+	name := TypeNameOf[T]()
+	if _, ok := DefaultTypeManager().GetType(name); ok {
+		v, err := FromBytes(data, name)
+		if err != nil {
+			return nil, err
+		}
+		out, ok := v.(*T)
+		if !ok {
+			return nil, fmt.Errorf("FromBytesAs: registered type %s decoded as %T", name, v)
+		}
+		return out, nil
+	}
+
+	out := new(T)
+	if err := DefaultCodec().Decode(data, out); err != nil {
+		return nil, err
+	}
+	return out, relinkReferences(out, nil)
+}
+
+// CallFunctionAs calls a registered function and returns its first result as R.
+// A non-nil trailing error result is returned as the error.
+func CallFunctionAs[R any](name string, params ...interface{}) (R, error) {
+	var zero R
+	results, err := CallFunction(name, params...)
+	if err != nil {
+		return zero, err
+	}
+	if n := len(results); n > 0 && results[n-1].Type().Implements(errorType) {
+		if e := results[n-1]; !e.IsNil() {
+			return zero, e.Interface().(error)
+		}
+		results = results[:n-1]
+	}
+	if len(results) == 0 {
+		if reflect.TypeOf((*R)(nil)).Elem().Kind() == reflect.Interface {
+			return zero, nil
+		}
+		return zero, errors.New("function " + name + " returned no value")
+	}
+
+	rt := reflect.TypeOf((*R)(nil)).Elem()
+	v := results[0]
+	if v.Type() != rt {
+		if v.Kind() == reflect.Interface && !v.IsNil() {
+			v = v.Elem()
+		}
+		if !v.Type().AssignableTo(rt) {
+			if !v.CanConvert(rt) {
+				return zero, fmt.Errorf("function %s returned %v, not %v", name, v.Type(), rt)
+			}
+			v = v.Convert(rt)
+		}
+	}
+	out, _ := v.Interface().(R)
+	return out, nil
+}
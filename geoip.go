@@ -0,0 +1,187 @@
+// utility/geoip.go
+package Utility
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// GeoIP
+// -----
+// ForeignIP asks the GeoIPProvider of the package settings, ipinfo.io by
+// default. MMDBGeoIP answers from local MaxMind databases (GeoLite2-City,
+// GeoLite2-ASN...) so lookups keep working offline and aren't rate-limited:
+//
+//	geo, err := OpenGeoIPDatabases("GeoLite2-City.mmdb", "GeoLite2-ASN.mmdb")
+//	s := CurrentSettings()
+//	s.GeoIP = geo
+//	Configure(s)
+
+// GeoIPProvider locates IP addresses. An empty ip means this machine's
+// public address.
+type GeoIPProvider interface {
+	Lookup(ctx context.Context, ip string) (*IPInfo, error)
+}
+
+// IPInfoGeoIP queries the ipinfo.io API (or a compatible one).
+type IPInfoGeoIP struct {
+	BaseURL string // default "https://ipinfo.io"
+	Token   string // optional API token
+}
+
+// Lookup implements GeoIPProvider.
+func (p *IPInfoGeoIP) Lookup(ctx context.Context, ip string) (*IPInfo, error) {
+	base := p.BaseURL
+	if base == "" {
+		base = "https://ipinfo.io"
+	}
+	u := strings.TrimSuffix(base, "/")
+	if ip != "" {
+		u += "/" + url.PathEscape(ip)
+	}
+	u += "/json"
+	if p.Token != "" {
+		u += "?token=" + url.QueryEscape(p.Token)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ipinfo: %s", resp.Status)
+	}
+
+	var body struct {
+		IP       string          `json:"ip"`
+		Hostname string          `json:"hostname"`
+		City     string          `json:"city"`
+		Region   string          `json:"region"`
+		Country  string          `json:"country"`
+		Loc      string          `json:"loc"`
+		Org      string          `json:"org"`
+		Postal   string          `json:"postal"`
+		Timezone string          `json:"timezone"`
+		ASN      json.RawMessage `json:"asn"` // {"asn": "AS15169", ...} on paid plans
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return nil, err
+	}
+	info := &IPInfo{
+		IP: body.IP, Hostname: body.Hostname, City: body.City, Region: body.Region,
+		Country: body.Country, Loc: body.Loc, Org: body.Org, Postal: body.Postal, Timezone: body.Timezone,
+	}
+	var asn struct {
+		ASN string `json:"asn"`
+	}
+	if json.Unmarshal(body.ASN, &asn) == nil && asn.ASN != "" {
+		info.ASN = asn.ASN
+	} else if as, _, ok := strings.Cut(body.Org, " "); ok && strings.HasPrefix(as, "AS") {
+		info.ASN = as // "AS15169 Google LLC"
+	}
+	return info, nil
+}
+
+// MMDBGeoIP answers from local MaxMind databases; the records of every
+// database are combined (e.g. a City and an ASN database).
+type MMDBGeoIP struct {
+	DBs []*MMDB
+}
+
+// OpenGeoIPDatabases opens the MaxMind databases at paths.
+func OpenGeoIPDatabases(paths ...string) (*MMDBGeoIP, error) {
+	if len(paths) == 0 {
+		return nil, &GuardError{Field: "paths", Reason: "must not be empty"}
+	}
+	p := &MMDBGeoIP{}
+	for _, path := range paths {
+		db, err := OpenMMDB(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		p.DBs = append(p.DBs, db)
+	}
+	return p, nil
+}
+
+// Lookup implements GeoIPProvider. Without ip, this machine's public IPv4
+// address is looked up first, which needs the network.
+func (p *MMDBGeoIP) Lookup(ctx context.Context, ip string) (*IPInfo, error) {
+	if ip == "" {
+		v4, v6, err := MyPublicIP(ctx)
+		if v4 == "" && v6 == "" {
+			return nil, fmt.Errorf("geoip: cannot determine the public IP: %w", err)
+		}
+		if ip = v4; ip == "" {
+			ip = v6
+		}
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return nil, &GuardError{Field: "ip", Reason: "not an IP address: " + ip}
+	}
+
+	info := &IPInfo{IP: ip}
+	found := false
+	for _, db := range p.DBs {
+		rec, err := db.Lookup(addr)
+		if err != nil {
+			return nil, err
+		}
+		if m, ok := rec.(map[string]interface{}); ok {
+			found = true
+			fillIPInfo(info, m)
+		}
+	}
+	if !found {
+		return nil, errors.New("geoip: no record for " + ip)
+	}
+	return info, nil
+}
+
+// fillIPInfo copies the GeoIP2/GeoLite2 record fields into info.
+func fillIPInfo(info *IPInfo, rec map[string]interface{}) {
+	get := func(path string) interface{} {
+		v, _ := GetPropertyByPath(rec, path)
+		return v
+	}
+	if s, ok := get(`city.names["en"]`).(string); ok {
+		info.City = s
+	}
+	if s, ok := get(`subdivisions[0].names["en"]`).(string); ok {
+		info.Region = s
+	}
+	if s, ok := get("country.iso_code").(string); ok {
+		info.Country = s
+	}
+	if s, ok := get("postal.code").(string); ok {
+		info.Postal = s
+	}
+	if s, ok := get("location.time_zone").(string); ok {
+		info.Timezone = s
+	}
+	lat, okLat := get("location.latitude").(float64)
+	lon, okLon := get("location.longitude").(float64)
+	if okLat && okLon {
+		info.Loc = strconv.FormatFloat(lat, 'f', 4, 64) + "," + strconv.FormatFloat(lon, 'f', 4, 64)
+	}
+	if n, ok := get("autonomous_system_number").(uint64); ok {
+		info.ASN = "AS" + strconv.FormatUint(n, 10)
+		info.Org = info.ASN
+		if org, ok := get("autonomous_system_organization").(string); ok {
+			info.Org += " " + org // same form as ipinfo.io
+		}
+	}
+}
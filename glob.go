@@ -0,0 +1,199 @@
+// utility/glob.go
+package Utility
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Wildcard matching
+// -----------------
+// Glob patterns are translated to anchored regular expressions:
+//
+//	*      any run of characters except the separator
+//	**     any run of characters, separators included ("a/**/b" also matches "a/b")
+//	?      one character other than the separator
+//	[abc]  a character class; [!abc] or [^abc] negates it, ranges are allowed
+//	\x     the literal character x
+//
+// The separator is '/' for paths; event topics use CompileGlobSep with '.'.
+
+// GlobMatcher is a compiled glob pattern, safe for concurrent use.
+type GlobMatcher struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+// CompileGlob compiles a path pattern ('/' separated).
+func CompileGlob(pattern string) (*GlobMatcher, error) {
+	return CompileGlobSep(pattern, '/')
+}
+
+// CompileGlobSep compiles pattern with sep as the segment separator.
+func CompileGlobSep(pattern string, sep byte) (*GlobMatcher, error) {
+	expr, err := globToRegexp(pattern, sep)
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+	}
+	return &GlobMatcher{pattern: pattern, re: re}, nil
+}
+
+// Match reports whether s matches the whole pattern.
+func (g *GlobMatcher) Match(s string) bool { return g.re.MatchString(s) }
+
+// String returns the source pattern.
+func (g *GlobMatcher) String() string { return g.pattern }
+
+var globCache sync.Map // pattern -> *GlobMatcher (nil when invalid)
+
+// compileGlobs compiles path patterns.
+func compileGlobs(patterns []string) ([]*GlobMatcher, error) {
+	out := make([]*GlobMatcher, 0, len(patterns))
+	for _, p := range patterns {
+		g, err := CompileGlob(p)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, g)
+	}
+	return out, nil
+}
+
+// matchGlobs reports whether rel, a slash-separated relative path, matches
+// one of globs. Patterns without a '/' are matched against the base name,
+// the others against the whole path.
+func matchGlobs(globs []*GlobMatcher, rel string) bool {
+	base := path.Base(rel)
+	for _, g := range globs {
+		if strings.Contains(g.String(), "/") {
+			if g.Match(rel) {
+				return true
+			}
+		} else if g.Match(base) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchWildcard reports whether s matches the path pattern. Compiled patterns
+// are cached; an invalid pattern matches nothing.
+func MatchWildcard(pattern, s string) bool {
+	cached, ok := globCache.Load(pattern)
+	if !ok {
+		g, _ := CompileGlob(pattern)
+		cached, _ = globCache.LoadOrStore(pattern, g)
+	}
+	g := cached.(*GlobMatcher)
+	return g != nil && g.Match(s)
+}
+
+// ExpandBraces expands the brace alternatives of pattern, nested ones
+// included: "a.{jpg,png}" gives "a.jpg" and "a.png". Braces without a comma
+// and escaped braces are kept as they are.
+func ExpandBraces(pattern string) []string {
+	depth, open := 0, -1
+	var commas []int
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '{':
+			if depth == 0 {
+				open, commas = i, commas[:0]
+			}
+			depth++
+		case ',':
+			if depth == 1 {
+				commas = append(commas, i)
+			}
+		case '}':
+			if depth == 0 {
+				continue
+			}
+			if depth--; depth > 0 || len(commas) == 0 {
+				continue
+			}
+			var out []string
+			start := open + 1
+			for _, end := range append(commas, i) {
+				for _, alt := range ExpandBraces(pattern[start:end]) {
+					for _, rest := range ExpandBraces(pattern[i+1:]) {
+						out = append(out, pattern[:open]+alt+rest)
+					}
+				}
+				start = end + 1
+			}
+			return out
+		}
+	}
+	return []string{pattern}
+}
+
+// globToRegexp translates a glob pattern into an anchored regular expression.
+func globToRegexp(pattern string, sep byte) (string, error) {
+	notSep := "[^" + regexp.QuoteMeta(string(sep)) + "]"
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				atStart := i == 1 || pattern[i-2] == sep
+				if atStart && i+1 < len(pattern) && pattern[i+1] == sep {
+					// "**/" matches zero or more whole segments.
+					i++
+					b.WriteString("(?:.*" + regexp.QuoteMeta(string(sep)) + ")?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString(notSep + "*")
+			}
+		case '?':
+			b.WriteString(notSep)
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end == 0 && i+2 < len(pattern) {
+				// "[]...]" : a leading ']' is literal
+				end = strings.IndexByte(pattern[i+2:], ']') + 1
+			}
+			if end <= 0 {
+				return "", fmt.Errorf("invalid glob %q: unterminated character class", pattern)
+			}
+			class := pattern[i+1 : i+1+end]
+			i += end + 1
+			b.WriteByte('[')
+			if class[0] == '!' || class[0] == '^' {
+				b.WriteString("^" + regexp.QuoteMeta(string(sep)))
+				class = class[1:]
+			}
+			for j := 0; j < len(class); j++ {
+				if class[j] == '\\' || class[j] == '[' || class[j] == ']' {
+					b.WriteByte('\\')
+				}
+				b.WriteByte(class[j])
+			}
+			b.WriteByte(']')
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				c = pattern[i]
+			}
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String(), nil
+}
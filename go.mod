@@ -3,20 +3,30 @@ module github.com/globulario/utility
 go 1.24.5
 
 require (
+	github.com/Microsoft/go-winio v0.6.2
 	github.com/chai2010/webp v1.4.0
+	github.com/fsnotify/fsnotify v1.9.0
 	github.com/glendc/go-external-ip v0.1.0
+	github.com/gorilla/websocket v1.5.3
 	github.com/mitchellh/go-ps v1.0.0
 	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646
 	github.com/polds/imgbase64 v0.0.0-20140820003345-cb7bf37298b7
 	github.com/srwiley/oksvg v0.0.0-20221011165216-be6e8873101c
 	github.com/srwiley/rasterx v0.0.0-20220730225603-2ab79fcdd4ef
 	github.com/txn2/txeh v1.5.5
+	github.com/vmihailenco/msgpack/v5 v5.4.1
 	golang.org/x/net v0.43.0
 	golang.org/x/sys v0.35.0
 	golang.org/x/text v0.28.0
+	google.golang.org/grpc v1.76.0
 )
 
-require github.com/twmb/murmur3 v1.1.5 // indirect
+require (
+	github.com/twmb/murmur3 v1.1.5 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250804133106-a7a43d27e69b // indirect
+	google.golang.org/protobuf v1.36.6 // indirect
+)
 
 require (
 	github.com/dhowden/tag v0.0.0-20240417053706-3d75831295e8
@@ -0,0 +1,233 @@
+// utility/grpcutil/interceptors.go
+
+// Package grpcutil holds the gRPC helpers of the utility module, kept out of
+// the root package so that importing it doesn't pull in grpc-go.
+package grpcutil
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+	"time"
+
+	Utility "github.com/globulario/utility"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// gRPC interceptor helpers
+// ------------------------
+// Unary and stream server interceptors for logging, panic recovery, metrics,
+// rate limiting and request-id propagation. Combine them with
+// grpc.ChainUnaryInterceptor / grpc.ChainStreamInterceptor.
+
+// RequestIDKey is the metadata key carrying the request id.
+const RequestIDKey = "x-request-id"
+
+type requestIDCtxKey struct{}
+
+// RequestIDFromContext returns the request id attached by the request-id interceptors.
+func RequestIDFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDCtxKey{}).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// ContextWithRequestID attaches a request id to ctx.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDCtxKey{}, id)
+}
+
+// RPCObserver receives the outcome of every RPC (for metrics).
+type RPCObserver func(method string, code codes.Code, elapsed time.Duration)
+
+// wrappedServerStream lets stream interceptors replace the stream context.
+type wrappedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (w *wrappedServerStream) Context() context.Context { return w.ctx }
+
+// ---------------------------
+// Logging
+// ---------------------------
+
+// LoggingUnaryInterceptor logs method, duration, status code and request id.
+// A nil logger uses the standard logger.
+func LoggingUnaryInterceptor(logger *log.Logger) grpc.UnaryServerInterceptor {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logRPC(logger, ctx, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// LoggingStreamInterceptor is the stream counterpart of LoggingUnaryInterceptor.
+func LoggingStreamInterceptor(logger *log.Logger) grpc.StreamServerInterceptor {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logRPC(logger, ss.Context(), info.FullMethod, start, err)
+		return err
+	}
+}
+
+func logRPC(logger *log.Logger, ctx context.Context, method string, start time.Time, err error) {
+	id := RequestIDFromContext(ctx)
+	if err != nil {
+		logger.Printf("grpc %s id=%s code=%s duration=%s err=%v", method, id, status.Code(err), time.Since(start), err)
+		return
+	}
+	logger.Printf("grpc %s id=%s code=OK duration=%s", method, id, time.Since(start))
+}
+
+// ---------------------------
+// Panic recovery
+// ---------------------------
+
+// RecoveryUnaryInterceptor turns handler panics into codes.Internal errors.
+func RecoveryUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoveredError(info.FullMethod, r)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// RecoveryStreamInterceptor turns stream handler panics into codes.Internal errors.
+func RecoveryStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoveredError(info.FullMethod, r)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+func recoveredError(method string, r interface{}) error {
+	log.Printf("grpc %s panic: %v\n%s", method, r, debug.Stack())
+	return status.Errorf(codes.Internal, "panic in %s: %v", method, r)
+}
+
+// ---------------------------
+// Metrics
+// ---------------------------
+
+// MetricsUnaryInterceptor reports every unary RPC to observe.
+func MetricsUnaryInterceptor(observe RPCObserver) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		if observe != nil {
+			observe(info.FullMethod, status.Code(err), time.Since(start))
+		}
+		return resp, err
+	}
+}
+
+// MetricsStreamInterceptor reports every stream RPC to observe.
+func MetricsStreamInterceptor(observe RPCObserver) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		if observe != nil {
+			observe(info.FullMethod, status.Code(err), time.Since(start))
+		}
+		return err
+	}
+}
+
+// ---------------------------
+// Rate limiting
+// ---------------------------
+
+// RateLimitUnaryInterceptor rejects calls with codes.ResourceExhausted when
+// the limiter has no token available.
+func RateLimitUnaryInterceptor(limiter *Utility.RateLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if limiter != nil && !limiter.Allow() {
+			return nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded for %s", info.FullMethod)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// RateLimitStreamInterceptor applies the limiter when a stream is opened.
+func RateLimitStreamInterceptor(limiter *Utility.RateLimiter) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if limiter != nil && !limiter.Allow() {
+			return status.Errorf(codes.ResourceExhausted, "rate limit exceeded for %s", info.FullMethod)
+		}
+		return handler(srv, ss)
+	}
+}
+
+// ---------------------------
+// Request id propagation
+// ---------------------------
+
+// incomingRequestID returns the id from incoming metadata, generating one if absent.
+func incomingRequestID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(RequestIDKey); len(ids) > 0 && ids[0] != "" {
+			return ids[0]
+		}
+	}
+	return Utility.RandomUUID()
+}
+
+// RequestIDUnaryInterceptor reads (or creates) the request id, stores it in the
+// context and echoes it back in the response header.
+func RequestIDUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		id := incomingRequestID(ctx)
+		grpc.SetHeader(ctx, metadata.Pairs(RequestIDKey, id))
+		return handler(ContextWithRequestID(ctx, id), req)
+	}
+}
+
+// RequestIDStreamInterceptor is the stream counterpart of RequestIDUnaryInterceptor.
+func RequestIDStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		id := incomingRequestID(ss.Context())
+		ss.SetHeader(metadata.Pairs(RequestIDKey, id))
+		return handler(srv, &wrappedServerStream{ServerStream: ss, ctx: ContextWithRequestID(ss.Context(), id)})
+	}
+}
+
+// RequestIDUnaryClientInterceptor forwards the context's request id to the callee.
+func RequestIDUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(outgoingRequestID(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// RequestIDStreamClientInterceptor forwards the context's request id on new streams.
+func RequestIDStreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(outgoingRequestID(ctx), desc, cc, method, opts...)
+	}
+}
+
+func outgoingRequestID(ctx context.Context) context.Context {
+	if id := RequestIDFromContext(ctx); id != "" {
+		return metadata.AppendToOutgoingContext(ctx, RequestIDKey, id)
+	}
+	return ctx
+}
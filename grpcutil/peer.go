@@ -0,0 +1,41 @@
+// utility/grpcutil/peer.go
+package grpcutil
+
+import (
+	"context"
+	"errors"
+
+	Utility "github.com/globulario/utility"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// GetPeerIPFromContext returns the client IP of a gRPC call, honoring
+// x-forwarded-for / x-real-ip metadata set by trusted proxies (see
+// Utility.SetTrustedProxies).
+func GetPeerIPFromContext(ctx context.Context) (string, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "", errors.New("no peer information in context")
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	var realIP string
+	if v := md.Get("x-real-ip"); len(v) > 0 {
+		realIP = v[0]
+	}
+	return Utility.ClientIPFromHeaders(p.Addr.String(), md.Get("x-forwarded-for"), realIP), nil
+}
+
+// GetTLSClientCertInfoFromContext returns the client certificate details of a gRPC call.
+func GetTLSClientCertInfoFromContext(ctx context.Context) (*Utility.TLSClientCertInfo, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, errors.New("no peer information in context")
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil, Utility.ErrNoClientCert
+	}
+	return Utility.TLSClientCertInfoOf(&tlsInfo.State)
+}
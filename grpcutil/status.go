@@ -0,0 +1,68 @@
+// utility/grpcutil/status.go
+package grpcutil
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	Utility "github.com/globulario/utility"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Guard errors as gRPC statuses
+// -----------------------------
+// Utility.GuardError only knows its HTTP status. GuardStatus maps it to the
+// matching gRPC code, and the GuardError interceptors apply that mapping to
+// handler errors so clients see InvalidArgument, NotFound, ... instead of
+// Unknown.
+
+// GuardCode returns the gRPC code matching the HTTP status of e.
+func GuardCode(e *Utility.GuardError) codes.Code {
+	switch e.StatusCode() {
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	}
+	return codes.InvalidArgument
+}
+
+// GuardStatus converts err to a gRPC status error when it wraps a
+// *Utility.GuardError; other errors, including status errors, are returned
+// unchanged.
+func GuardStatus(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	var ge *Utility.GuardError
+	if errors.As(err, &ge) {
+		return status.Error(GuardCode(ge), err.Error())
+	}
+	return err
+}
+
+// GuardErrorUnaryInterceptor returns guard errors of handlers as gRPC statuses.
+func GuardErrorUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		return resp, GuardStatus(err)
+	}
+}
+
+// GuardErrorStreamInterceptor is the stream counterpart of GuardErrorUnaryInterceptor.
+func GuardErrorStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return GuardStatus(handler(srv, ss))
+	}
+}
@@ -0,0 +1,96 @@
+// utility/guard.go
+package Utility
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// Input guards
+// ------------
+// Guards return a *GuardError instead of panicking, so services can map bad
+// input to a 4xx HTTP status (StatusCode) without inspecting messages; the
+// grpcutil package maps the same status to a gRPC code.
+
+// GuardError is a client-side input error.
+type GuardError struct {
+	Field  string // argument or field at fault, may be empty
+	Reason string
+	Status int // HTTP status, 400 when zero
+}
+
+func (e *GuardError) Error() string {
+	if e.Field == "" {
+		return e.Reason
+	}
+	return e.Field + ": " + e.Reason
+}
+
+// StatusCode returns the HTTP status for the error.
+func (e *GuardError) StatusCode() int {
+	if e.Status == 0 {
+		return http.StatusBadRequest
+	}
+	return e.Status
+}
+
+// Require returns a GuardError with msg when cond is false.
+func Require(cond bool, msg string) error {
+	if cond {
+		return nil
+	}
+	return &GuardError{Reason: msg}
+}
+
+// RequireNonNil returns a GuardError when v is nil (including typed nil
+// pointers, maps, slices, funcs and channels).
+func RequireNonNil(v interface{}, name string) error {
+	if v == nil {
+		return &GuardError{Field: name, Reason: "must not be nil"}
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		if rv.IsNil() {
+			return &GuardError{Field: name, Reason: "must not be nil"}
+		}
+	}
+	return nil
+}
+
+// RequireNonEmpty returns a GuardError when s is empty or only whitespace.
+func RequireNonEmpty(s, name string) error {
+	if strings.TrimSpace(s) == "" {
+		return &GuardError{Field: name, Reason: "must not be empty"}
+	}
+	return nil
+}
+
+// ValidateArgs runs the given guard results and joins every failure, e.g.
+//
+//	if err := ValidateArgs(RequireNonEmpty(id, "id"), Require(n > 0, "n must be positive")); err != nil { ... }
+func ValidateArgs(checks ...error) error {
+	var errs []error
+	for _, err := range checks {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return errors.Join(errs...)
+}
+
+// GuardStatus returns the HTTP status carried by err (500 for non-guard errors).
+func GuardStatus(err error) int {
+	var ge *GuardError
+	if errors.As(err, &ge) {
+		return ge.StatusCode()
+	}
+	return http.StatusInternalServerError
+}
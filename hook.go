@@ -0,0 +1,121 @@
+// utility/hook.go
+package Utility
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Telemetry hooks
+// ---------------
+// Copy, download, exec, archive and image helpers report each operation to
+// the registered hooks, so services can attach metrics or tracing. With no
+// hook registered the cost is a single atomic load.
+
+// Operation describes one instrumented call. Hooks may keep per-operation
+// state (e.g. a span) in Data between start and end.
+type Operation struct {
+	ID    uint64
+	Name  string                 // e.g. "copy.file", "download", "archive.compress"
+	Attrs map[string]interface{} // operation arguments (paths, URLs, ...)
+	Start time.Time
+	Data  sync.Map
+}
+
+// Hook observes utility operations.
+type Hook interface {
+	OnOperationStart(op *Operation)
+	OnOperationEnd(op *Operation, duration time.Duration, err error)
+}
+
+// HookFuncs adapts plain functions to Hook; nil members are skipped.
+type HookFuncs struct {
+	Start func(op *Operation)
+	End   func(op *Operation, duration time.Duration, err error)
+}
+
+func (h HookFuncs) OnOperationStart(op *Operation) {
+	if h.Start != nil {
+		h.Start(op)
+	}
+}
+
+func (h HookFuncs) OnOperationEnd(op *Operation, d time.Duration, err error) {
+	if h.End != nil {
+		h.End(op, d, err)
+	}
+}
+
+type hookEntry struct {
+	id   uint64
+	hook Hook
+}
+
+var (
+	hooksMu sync.Mutex
+	hooks   atomic.Pointer[[]hookEntry]
+	hookSeq uint64
+	opSeq   uint64
+)
+
+// AddHook registers a hook and returns a function removing it.
+func AddHook(h Hook) (remove func()) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hookSeq++
+	id := hookSeq
+	var cur []hookEntry
+	if p := hooks.Load(); p != nil {
+		cur = *p
+	}
+	next := append(append([]hookEntry(nil), cur...), hookEntry{id: id, hook: h})
+	hooks.Store(&next)
+
+	return func() {
+		hooksMu.Lock()
+		defer hooksMu.Unlock()
+		p := hooks.Load()
+		if p == nil {
+			return
+		}
+		kept := make([]hookEntry, 0, len(*p))
+		for _, e := range *p {
+			if e.id != id {
+				kept = append(kept, e)
+			}
+		}
+		hooks.Store(&kept)
+	}
+}
+
+// startOperation notifies hooks that name started; it returns nil when no
+// hook is registered. Use as: defer endOperation(startOperation(...), &err).
+func startOperation(name string, attrs map[string]interface{}) *Operation {
+	p := hooks.Load()
+	if p == nil || len(*p) == 0 {
+		return nil
+	}
+	op := &Operation{ID: atomic.AddUint64(&opSeq, 1), Name: name, Attrs: attrs, Start: time.Now()}
+	for _, e := range *p {
+		e.hook.OnOperationStart(op)
+	}
+	return op
+}
+
+// endOperation notifies hooks that op finished with *errp.
+func endOperation(op *Operation, errp *error) {
+	if op == nil {
+		return
+	}
+	var err error
+	if errp != nil {
+		err = *errp
+	}
+	d := time.Since(op.Start)
+	if p := hooks.Load(); p != nil {
+		for _, e := range *p {
+			e.hook.OnOperationEnd(op, d, err)
+		}
+	}
+}
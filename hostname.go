@@ -0,0 +1,112 @@
+// utility/hostname.go
+package Utility
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strings"
+)
+
+// Host names
+// ----------
+// Reverse lookups, the fully qualified name of this machine and RFC 1123
+// host name validation.
+
+// LookupHostnames returns the names ip resolves back to (PTR records),
+// without trailing dots, giving up after a few seconds.
+func LookupHostnames(ip string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultNetTimeout)
+	defer cancel()
+	return LookupHostnamesCtx(ctx, ip)
+}
+
+// LookupHostnamesCtx is LookupHostnames bounded by ctx.
+func LookupHostnamesCtx(ctx context.Context, ip string) ([]string, error) {
+	if net.ParseIP(ip) == nil {
+		return nil, &GuardError{Field: "ip", Reason: "not an IP address: " + ip}
+	}
+	names, err := net.DefaultResolver.LookupAddr(ctx, ip)
+	if err != nil {
+		return nil, err
+	}
+	for i, n := range names {
+		names[i] = strings.TrimSuffix(n, ".")
+	}
+	return names, nil
+}
+
+// GetFQDN returns the fully qualified domain name of this machine. Like
+// "hostname -f", it falls back to the short host name when no domain can be
+// found.
+func GetFQDN() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(hostname, ".") {
+		return hostname, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultNetTimeout)
+	defer cancel()
+	if cname, err := net.DefaultResolver.LookupCNAME(ctx, hostname); err == nil {
+		if cname = strings.TrimSuffix(cname, "."); strings.HasPrefix(cname, hostname+".") {
+			return cname, nil
+		}
+	}
+	ips, err := LookupIP(ctx, hostname)
+	if err != nil {
+		return hostname, nil
+	}
+	for _, ip := range ips {
+		names, err := LookupHostnamesCtx(ctx, ip.String())
+		if err != nil {
+			continue
+		}
+		for _, n := range names {
+			if strings.HasPrefix(n, hostname+".") {
+				return n, nil
+			}
+		}
+	}
+	return hostname, nil
+}
+
+// ValidateHostname checks name against RFC 1123: at most 253 characters,
+// dot-separated labels of 1 to 63 letters, digits and hyphens, not starting
+// or ending with a hyphen. A single trailing dot is accepted.
+func ValidateHostname(name string) error {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" {
+		return &GuardError{Field: "hostname", Reason: "must not be empty"}
+	}
+	if len(name) > 253 {
+		return &GuardError{Field: "hostname", Reason: "longer than 253 characters"}
+	}
+	for _, label := range strings.Split(name, ".") {
+		if err := validateHostLabel(label); err != nil {
+			return &GuardError{Field: "hostname", Reason: err.Error()}
+		}
+	}
+	return nil
+}
+
+func validateHostLabel(label string) error {
+	switch {
+	case label == "":
+		return errors.New("empty label")
+	case len(label) > 63:
+		return errors.New("label " + label + " longer than 63 characters")
+	case label[0] == '-' || label[len(label)-1] == '-':
+		return errors.New("label " + label + " starts or ends with a hyphen")
+	}
+	for i := 0; i < len(label); i++ {
+		c := label[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+			return errors.New("label " + label + " contains " + string(rune(c)))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// utility/httpclient.go
+package Utility
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// HTTP client
+// -----------
+// Every network helper (MyIP, ForeignIP, DownloadFile, UploadFile...) sends
+// its requests through the client of the package settings. By default that
+// is http.DefaultClient, which honors HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+// SetHTTPClientOptions replaces it with one built for a given proxy and TLS
+// setup:
+//
+//	err := SetHTTPClientOptions(HTTPClientOptions{
+//		Proxy:   "http://proxy.lab:3128",
+//		RootCAs: []string{"/etc/lab/ca.pem"},
+//	})
+
+// HTTPClientOptions configures the client built by NewHTTPClient.
+type HTTPClientOptions struct {
+	// Proxy is the proxy URL; empty uses HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+	Proxy string
+	// NoProxy connects directly, whatever the environment says.
+	NoProxy bool
+	// RootCAs are PEM files of certificate authorities trusted in addition
+	// to the system ones.
+	RootCAs []string
+	// ClientCert and ClientKey are the PEM files of a client certificate.
+	ClientCert string
+	ClientKey  string
+	// InsecureSkipVerify disables server certificate checks. For lab setups
+	// only.
+	InsecureSkipVerify bool
+	// Timeout bounds whole requests; zero means none (contexts still apply).
+	Timeout time.Duration
+}
+
+// NewHTTPClient builds a client from opts.
+func NewHTTPClient(opts HTTPClientOptions) (*http.Client, error) {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+
+	switch {
+	case opts.NoProxy:
+		tr.Proxy = nil
+	case opts.Proxy != "":
+		u, err := url.Parse(opts.Proxy)
+		if err != nil {
+			return nil, &GuardError{Field: "Proxy", Reason: err.Error()}
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return nil, &GuardError{Field: "Proxy", Reason: "must be an absolute URL: " + opts.Proxy}
+		}
+		tr.Proxy = http.ProxyURL(u)
+	default:
+		tr.Proxy = http.ProxyFromEnvironment
+	}
+
+	cfg := &tls.Config{InsecureSkipVerify: opts.InsecureSkipVerify}
+	if len(opts.RootCAs) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		for _, path := range opts.RootCAs {
+			pem, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, errors.New(path + ": no PEM certificate found")
+			}
+		}
+		cfg.RootCAs = pool
+	}
+	if opts.ClientCert != "" || opts.ClientKey != "" {
+		if opts.ClientCert == "" || opts.ClientKey == "" {
+			return nil, &GuardError{Field: "ClientCert", Reason: "ClientCert and ClientKey go together"}
+		}
+		cert, err := tls.LoadX509KeyPair(opts.ClientCert, opts.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	tr.TLSClientConfig = cfg
+
+	return &http.Client{Transport: tr, Timeout: opts.Timeout}, nil
+}
+
+// SetHTTPClientOptions makes the network helpers use a client built from
+// opts. The other settings are left untouched.
+func SetHTTPClientOptions(opts HTTPClientOptions) error {
+	client, err := NewHTTPClient(opts)
+	if err != nil {
+		return err
+	}
+	settingsMu.Lock()
+	settings.HTTPClient = client
+	settingsMu.Unlock()
+	return nil
+}
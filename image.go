@@ -20,7 +20,9 @@ import (
 )
 
 // SvgToPng converts an SVG file into a PNG at the given dimensions.
-func SvgToPng(input, output string, w, h int) error {
+func SvgToPng(input, output string, w, h int) (err error) {
+	defer endOperation(startOperation("image.svg2png", map[string]interface{}{"src": input, "dst": output}), &err)
+
 	in, err := os.Open(input)
 	if err != nil {
 		return err
@@ -42,7 +44,9 @@ func SvgToPng(input, output string, w, h int) error {
 }
 
 // CreateThumbnail resizes an image and returns its base64 representation.
-func CreateThumbnail(path string, thumbnailMaxHeight int, thumbnailMaxWidth int) (string, error) {
+func CreateThumbnail(path string, thumbnailMaxHeight int, thumbnailMaxWidth int) (thumbnail string, err error) {
+	defer endOperation(startOperation("image.thumbnail", map[string]interface{}{"src": path}), &err)
+
 	file, err := os.Open(path)
 	if err != nil {
 		return "", err
@@ -0,0 +1,329 @@
+// utility/init_options.go
+package Utility
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Initialization options and errors
+// ---------------------------------
+// MakeInstance and InitializeStructure are best-effort: a value that cannot
+// be converted leaves its field zero. Their ...WithOptions variants take
+// InitOptions; in strict mode every field that could not be set is reported
+// in an *InitError with its path and the reason. Panics raised while setting
+// a field are recovered in every mode, so one bad value never takes the
+// whole initialization down.
+
+// InitOptions configures dynamic initialization.
+type InitOptions struct {
+	// Strict reports fields that could not be set as an *InitError, and uses
+	// the checked ToXxxE conversions (so "abc" no longer becomes 0, and 1e19
+	// no longer wraps around in an int32).
+	Strict bool
+	// DisallowUnknownKeys, in strict mode, reports keys matching no field
+	// instead of ignoring them.
+	DisallowUnknownKeys bool
+	// RequireRegisteredTypes, in strict mode, reports objects whose TYPENAME
+	// is not registered instead of keeping them as plain maps.
+	RequireRegisteredTypes bool
+	// Report, when set, receives what happened to every key of the data,
+	// in strict mode or not.
+	Report *InitReport
+	// Base64 tells how strings and bytes given for []byte fields are read;
+	// a `base64:"auto|off|guess"` field tag overrides it.
+	Base64 Base64Mode
+}
+
+// Base64Mode tells whether values for []byte fields are base64 decoded.
+type Base64Mode int
+
+const (
+	// Base64Auto decodes strings as standard base64, the way encoding/json
+	// writes []byte, and keeps byte slices as they are.
+	Base64Auto Base64Mode = iota
+	// Base64Off keeps strings and byte slices as raw bytes.
+	Base64Off
+	// Base64Guess decodes strings and byte slices that IsStdBase64 accepts
+	// and keeps the others; binary data can be mistaken for base64.
+	Base64Guess
+)
+
+// StrictInit returns the options rejecting anything initialization cannot
+// take exactly: conversion failures, unknown keys and unregistered TYPENAMEs.
+func StrictInit() InitOptions {
+	return InitOptions{Strict: true, DisallowUnknownKeys: true, RequireRegisteredTypes: true}
+}
+
+// InitReport lists, by path, the fields an initialization set, the keys it
+// skipped because the type has no such field, and the values it failed to
+// convert. Nested fields and elements are reported individually
+// ("Address.City", "Tags[2]").
+type InitReport struct {
+	Set     []string     `json:"set"`
+	Skipped []string     `json:"skipped"`
+	Failed  []FieldError `json:"failed"`
+}
+
+// FieldError describes one field that could not be set.
+type FieldError struct {
+	Path   string `json:"path"` // e.g. "Address.Zip", "Tags[2]", "Meta[key]"
+	Reason string `json:"reason"`
+}
+
+func (e FieldError) Error() string {
+	if e.Path == "" {
+		return e.Reason
+	}
+	return e.Path + ": " + e.Reason
+}
+
+// InitError aggregates the field errors of a strict initialization.
+type InitError struct {
+	TypeName string
+	Fields   []FieldError
+}
+
+func (e *InitError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Error()
+	}
+	return "cannot initialize " + e.TypeName + ": " + strings.Join(msgs, "; ")
+}
+
+// initState carries the options and the errors of one initialization down
+// the recursive initializers, along with the path of the current field.
+type initState struct {
+	opts  InitOptions
+	conv  Conventions
+	path  []string
+	errs  []FieldError
+	calls int // st.field calls so far, to tell leaves from containers
+}
+
+// newInitState starts an initialization reading data with conv; registered
+// types switch to the conventions of their own manager (see
+// initializeStructureValue).
+func newInitState(conv Conventions, opts InitOptions) *initState {
+	return &initState{opts: opts, conv: conv}
+}
+
+func (st *initState) push(seg string) { st.path = append(st.path, seg) }
+
+func (st *initState) pop() { st.path = st.path[:len(st.path)-1] }
+
+// at returns the current path ("A.B[3].C").
+func (st *initState) at() string {
+	var b strings.Builder
+	for i, seg := range st.path {
+		if i > 0 && !strings.HasPrefix(seg, "[") {
+			b.WriteByte('.')
+		}
+		b.WriteString(seg)
+	}
+	return b.String()
+}
+
+// fail records a field error at the current path.
+func (st *initState) fail(format string, args ...interface{}) {
+	st.errs = append(st.errs, FieldError{Path: st.at(), Reason: fmt.Sprintf(format, args...)})
+}
+
+// skip records a key of the current object that matches no field.
+func (st *initState) skip(key string) {
+	if key == st.conv.TypeKey || key == st.conv.VersionKey {
+		return
+	}
+	st.push(key)
+	if st.opts.Report != nil {
+		st.opts.Report.Skipped = append(st.opts.Report.Skipped, st.at())
+	}
+	if st.opts.Strict && st.opts.DisallowUnknownKeys {
+		st.fail("no such field")
+	}
+	st.pop()
+}
+
+// unregistered records an object whose TYPENAME is not registered.
+func (st *initState) unregistered(typeName string) {
+	if st.opts.Strict && st.opts.RequireRegisteredTypes {
+		st.fail("type %q is not registered", typeName)
+	}
+}
+
+// field runs fn for the field (or element) seg, recovering from panics. A
+// leaf set without errors is recorded in the report.
+func (st *initState) field(seg string, fn func()) {
+	st.push(seg)
+	depth := len(st.path)
+	errs, calls := len(st.errs), st.calls
+	st.calls++
+	defer func() {
+		if r := recover(); r != nil {
+			st.path = st.path[:depth]
+			if !st.opts.Strict {
+				log.Printf("initialization of %s recovered from panic: %v\n", st.at(), r)
+			}
+			st.fail("panic: %v", r)
+		}
+		if st.opts.Report != nil && len(st.errs) == errs && st.calls == calls+1 {
+			st.opts.Report.Set = append(st.opts.Report.Set, st.at())
+		}
+		st.path = st.path[:depth-1]
+	}()
+	fn()
+}
+
+// set assigns v to dst, converting when the kinds allow it; an invalid v
+// means the conversion already failed and was recorded.
+func (st *initState) set(dst, v reflect.Value) {
+	if !v.IsValid() || !dst.IsValid() {
+		return
+	}
+	if v.Type() != dst.Type() && !v.Type().AssignableTo(dst.Type()) {
+		if !v.CanConvert(dst.Type()) {
+			st.fail("cannot assign %v to %v", v.Type(), dst.Type())
+			return
+		}
+		v = v.Convert(dst.Type())
+	}
+	dst.Set(v)
+}
+
+// baseValue converts value for a field of base type t.
+func (st *initState) baseValue(t reflect.Type, value interface{}) reflect.Value {
+	if st.opts.Strict {
+		v, err := baseValueE(t, value)
+		if err != nil {
+			st.fail("%v", err)
+			return reflect.Value{}
+		}
+		return v
+	}
+	v := InitializeBaseTypeValue(t, value)
+	if !v.IsValid() && value != nil {
+		st.fail("cannot convert %T to %v", value, t)
+	} else if st.opts.Report != nil {
+		// The lenient value is kept, but the report tells it was a guess.
+		if _, err := baseValueE(t, value); err != nil {
+			st.fail("%v", err)
+		}
+	}
+	return v
+}
+
+// base64Mode returns the Base64Mode of field name of struct type t.
+func (st *initState) base64Mode(t reflect.Type, name string) Base64Mode {
+	if fd, ok := lookupField(t, name); ok {
+		switch fd.Tag.Get("base64") {
+		case "auto":
+			return Base64Auto
+		case "off":
+			return Base64Off
+		case "guess":
+			return Base64Guess
+		}
+	}
+	return st.opts.Base64
+}
+
+// bytesFieldValue reads a string or []byte value for a []byte field.
+func bytesFieldValue(value interface{}, mode Base64Mode) ([]byte, error) {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+		if mode == Base64Auto {
+			b, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid base64: %v", err)
+			}
+			return b, nil
+		}
+	case []byte:
+		if mode != Base64Guess {
+			return append([]byte(nil), v...), nil
+		}
+		s = string(v)
+	}
+	if mode == Base64Guess && s != "" && IsStdBase64(s) {
+		if b, err := base64.StdEncoding.DecodeString(s); err == nil {
+			return b, nil
+		}
+	}
+	return []byte(s), nil
+}
+
+// result returns the aggregated error of a strict initialization, or nil.
+// It also completes the report.
+func (st *initState) result(typeName string) error {
+	sort.SliceStable(st.errs, func(i, j int) bool { return st.errs[i].Path < st.errs[j].Path })
+	if r := st.opts.Report; r != nil {
+		sort.Strings(r.Set)
+		sort.Strings(r.Skipped)
+		r.Failed = append(r.Failed, st.errs...)
+	}
+	if !st.opts.Strict || len(st.errs) == 0 {
+		return nil
+	}
+	return &InitError{TypeName: typeName, Fields: st.errs}
+}
+
+// baseValueE is the checked counterpart of InitializeBaseTypeValue.
+func baseValueE(t reflect.Type, value interface{}) (reflect.Value, error) {
+	if value == nil {
+		return reflect.Value{}, nil
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		return reflect.ValueOf(value), nil
+	case reflect.String:
+		s, err := ToStringE(value)
+		return reflect.ValueOf(s), err
+	case reflect.Bool:
+		switch b := value.(type) {
+		case bool:
+			return reflect.ValueOf(b), nil
+		case string:
+			v, err := strconv.ParseBool(b)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("invalid boolean %q", b)
+			}
+			return reflect.ValueOf(v), nil
+		}
+		return reflect.Value{}, fmt.Errorf("cannot convert %T to bool", value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := toSignedE(value, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(n).Convert(t), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := toUnsignedE(value, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(n).Convert(t), nil
+	case reflect.Float32:
+		f, err := ToFloat32E(value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(f).Convert(t), nil
+	case reflect.Float64:
+		f, err := ToNumericE(value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(f).Convert(t), nil
+	case reflect.Array:
+		return arrayValue(t, value, baseValueE)
+	}
+	return reflect.Value{}, fmt.Errorf("cannot convert %T to %v", value, t)
+}
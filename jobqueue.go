@@ -0,0 +1,412 @@
+// utility/jobqueue.go
+package Utility
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Persistent job queue
+// --------------------
+// JobQueue is a priority queue of background jobs (thumbnail generation,
+// checksum scans, ...) persisted in a KVStore. A dequeued job is leased for
+// VisibilityTimeout: if its worker dies, the job becomes visible again once
+// the lease expires, including after a process restart. Each lease has its
+// own token, so a worker whose lease expired can't ack or nack the job once
+// another worker got it. Failed jobs are retried with exponential backoff and
+// moved to the dead-letter list after MaxAttempts.
+
+// JobState is the lifecycle state of a job.
+type JobState string
+
+const (
+	JobPending JobState = "pending"
+	JobRunning JobState = "running"
+	JobDead    JobState = "dead"
+)
+
+// Job is a unit of work stored in a JobQueue.
+type Job struct {
+	ID         string    `json:"id"`
+	Payload    []byte    `json:"payload"`
+	Priority   int       `json:"priority"` // higher runs first
+	State      JobState  `json:"state"`
+	Attempts   int       `json:"attempts"`
+	LastError  string    `json:"lastError,omitempty"`
+	Created    time.Time `json:"created"`
+	NotBefore  time.Time `json:"notBefore,omitempty"`  // backoff: not visible before
+	LeaseUntil time.Time `json:"leaseUntil,omitempty"` // visibility timeout of a running job
+	Lease      string    `json:"lease,omitempty"`      // token of the current lease, for Ack, Nack and Extend
+}
+
+// JobHandler processes one job; a non-nil error schedules a retry.
+type JobHandler func(ctx context.Context, job *Job) error
+
+// JobQueueOptions configures a JobQueue; zero values use the defaults.
+type JobQueueOptions struct {
+	Name              string        // key prefix in the store (default "jobs")
+	VisibilityTimeout time.Duration // default 5m; Run extends it while a handler runs
+	MaxAttempts       int           // default 5
+	BaseBackoff       time.Duration // default 1s, doubled per attempt
+	MaxBackoff        time.Duration // default 10m
+	PollInterval      time.Duration // default 1s
+}
+
+var (
+	// ErrJobNotFound is returned for unknown job IDs.
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobLeaseLost is returned when acknowledging a job that is no longer
+	// held under the given lease: it expired and the job was dequeued again,
+	// or it was already acknowledged.
+	ErrJobLeaseLost = errors.New("job lease lost")
+)
+
+// JobQueue is a persistent, concurrency-safe priority job queue.
+type JobQueue struct {
+	store KVStore
+	opts  JobQueueOptions
+
+	mu     sync.Mutex
+	jobs   map[string]*Job
+	notify chan struct{}
+}
+
+// NewJobQueue opens the queue persisted in store, reloading its jobs.
+func NewJobQueue(store KVStore, opts JobQueueOptions) (*JobQueue, error) {
+	if err := RequireNonNil(store, "store"); err != nil {
+		return nil, err
+	}
+	if opts.Name == "" {
+		opts.Name = "jobs"
+	}
+	if opts.VisibilityTimeout <= 0 {
+		opts.VisibilityTimeout = 5 * time.Minute
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.BaseBackoff <= 0 {
+		opts.BaseBackoff = time.Second
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 10 * time.Minute
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+
+	q := &JobQueue{store: store, opts: opts, jobs: make(map[string]*Job), notify: make(chan struct{}, 1)}
+	stored, err := store.List(q.prefix())
+	if err != nil {
+		return nil, err
+	}
+	for key, data := range stored {
+		job := new(Job)
+		if err := json.Unmarshal(data, job); err != nil {
+			return nil, fmt.Errorf("job queue %s: corrupt entry %s: %w", opts.Name, key, err)
+		}
+		q.jobs[job.ID] = job
+	}
+	return q, nil
+}
+
+func (q *JobQueue) prefix() string { return q.opts.Name + "/" }
+
+// save persists job; the caller holds q.mu.
+func (q *JobQueue) save(job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return q.store.Put(q.prefix()+job.ID, data)
+}
+
+func (q *JobQueue) wake() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Enqueue adds a job and returns its ID.
+func (q *JobQueue) Enqueue(payload []byte, priority int) (string, error) {
+	job := &Job{ID: RandomUUID(), Payload: payload, Priority: priority, State: JobPending, Created: time.Now()}
+	q.mu.Lock()
+	if err := q.save(job); err != nil {
+		q.mu.Unlock()
+		return "", err
+	}
+	q.jobs[job.ID] = job
+	q.mu.Unlock()
+	q.wake()
+	return job.ID, nil
+}
+
+// visible reports whether job can be dequeued at now.
+func visible(job *Job, now time.Time) bool {
+	switch job.State {
+	case JobPending:
+		return !now.Before(job.NotBefore)
+	case JobRunning:
+		return now.After(job.LeaseUntil)
+	}
+	return false
+}
+
+// Dequeue leases the highest-priority visible job (oldest first among equal
+// priorities); the returned copy carries the lease token to pass to Ack,
+// Nack and Extend. It returns false when nothing is ready. A job whose last
+// allowed attempt timed out is moved to the dead-letter list instead.
+func (q *JobQueue) Dequeue() (*Job, bool, error) {
+	now := time.Now()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var best *Job
+	for _, job := range q.jobs {
+		if !visible(job, now) {
+			continue
+		}
+		if job.State == JobRunning && job.Attempts >= q.opts.MaxAttempts {
+			if err := q.update(job, func(j *Job) {
+				j.State, j.LeaseUntil, j.Lease = JobDead, time.Time{}, ""
+				j.LastError = "lease expired"
+			}); err != nil {
+				return nil, false, err
+			}
+			continue
+		}
+		if best == nil || job.Priority > best.Priority ||
+			(job.Priority == best.Priority && job.Created.Before(best.Created)) {
+			best = job
+		}
+	}
+	if best == nil {
+		return nil, false, nil
+	}
+	if err := q.update(best, func(j *Job) {
+		j.State = JobRunning
+		j.Attempts++
+		j.LeaseUntil = now.Add(q.opts.VisibilityTimeout)
+		j.Lease = RandomUUID()
+	}); err != nil {
+		return nil, false, err
+	}
+	job := *best
+	return &job, true, nil
+}
+
+// update applies change to job and persists it, restoring the previous
+// state when it can't be saved; the caller holds q.mu.
+func (q *JobQueue) update(job *Job, change func(*Job)) error {
+	prev := *job
+	change(job)
+	if err := q.save(job); err != nil {
+		*job = prev
+		return err
+	}
+	return nil
+}
+
+// leased returns the job id held under lease; the caller holds q.mu.
+func (q *JobQueue) leased(id, lease string) (*Job, error) {
+	job, ok := q.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	if job.State != JobRunning || job.Lease != lease {
+		return nil, ErrJobLeaseLost
+	}
+	return job, nil
+}
+
+// Ack marks a job leased by Dequeue as done and removes it.
+func (q *JobQueue) Ack(id, lease string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, err := q.leased(id, lease); err != nil {
+		return err
+	}
+	if err := q.store.Delete(q.prefix() + id); err != nil {
+		return err
+	}
+	delete(q.jobs, id)
+	return nil
+}
+
+// Nack records a failure of a leased job: it is retried after a backoff, or
+// moved to the dead-letter list once it has used MaxAttempts.
+func (q *JobQueue) Nack(id, lease string, cause error) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, err := q.leased(id, lease)
+	if err != nil {
+		return err
+	}
+	return q.update(job, func(j *Job) {
+		if cause != nil {
+			j.LastError = cause.Error()
+		}
+		j.LeaseUntil, j.Lease = time.Time{}, ""
+		if j.Attempts >= q.opts.MaxAttempts {
+			j.State = JobDead
+		} else {
+			j.State = JobPending
+			j.NotBefore = time.Now().Add(q.backoff(j.Attempts))
+		}
+	})
+}
+
+// Extend pushes back the lease of a running job by the visibility timeout.
+func (q *JobQueue) Extend(id, lease string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, err := q.leased(id, lease)
+	if err != nil {
+		return err
+	}
+	return q.update(job, func(j *Job) { j.LeaseUntil = time.Now().Add(q.opts.VisibilityTimeout) })
+}
+
+func (q *JobQueue) backoff(attempts int) time.Duration {
+	d := q.opts.BaseBackoff
+	for i := 1; i < attempts && d < q.opts.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > q.opts.MaxBackoff {
+		d = q.opts.MaxBackoff
+	}
+	return d
+}
+
+// Len returns the number of live (pending or running) jobs.
+func (q *JobQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, job := range q.jobs {
+		if job.State != JobDead {
+			n++
+		}
+	}
+	return n
+}
+
+// DeadLetters returns copies of the jobs that exhausted their attempts.
+func (q *JobQueue) DeadLetters() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var out []Job
+	for _, job := range q.jobs {
+		if job.State == JobDead {
+			out = append(out, *job)
+		}
+	}
+	return out
+}
+
+// Requeue moves a dead job back to the queue with a fresh attempt count.
+func (q *JobQueue) Requeue(id string) error {
+	q.mu.Lock()
+	job, ok := q.jobs[id]
+	if !ok || job.State != JobDead {
+		q.mu.Unlock()
+		return ErrJobNotFound
+	}
+	err := q.update(job, func(j *Job) { j.State, j.Attempts, j.NotBefore = JobPending, 0, time.Time{} })
+	q.mu.Unlock()
+	q.wake()
+	return err
+}
+
+// Run starts workers goroutines (default Settings.MaxParallelism) processing
+// jobs with handler and blocks until ctx is cancelled and every worker has
+// returned. Handler panics count as failures. The lease of a job is extended
+// every half VisibilityTimeout while its handler runs, so long jobs are not
+// handed to a second worker.
+func (q *JobQueue) Run(ctx context.Context, workers int, handler JobHandler) {
+	if workers <= 0 {
+		workers = CurrentSettings().MaxParallelism
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx, handler)
+		}()
+	}
+	wg.Wait()
+}
+
+func (q *JobQueue) work(ctx context.Context, handler JobHandler) {
+	ticker := time.NewTicker(q.opts.PollInterval)
+	defer ticker.Stop()
+	for ctx.Err() == nil {
+		job, ok, err := q.Dequeue()
+		if err != nil {
+			RecordError("jobqueue", err, map[string]interface{}{"queue": q.opts.Name})
+		}
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-q.notify:
+			case <-ticker.C:
+			}
+			continue
+		}
+
+		if err := q.handle(ctx, handler, job); err != nil {
+			err = q.Nack(job.ID, job.Lease, err)
+		} else {
+			err = q.Ack(job.ID, job.Lease)
+		}
+		if err != nil && !errors.Is(err, ErrJobNotFound) {
+			RecordError("jobqueue", err, map[string]interface{}{"queue": q.opts.Name, "job": job.ID})
+		}
+	}
+}
+
+func (q *JobQueue) handle(ctx context.Context, handler JobHandler, job *Job) (err error) {
+	stop := make(chan struct{})
+	var keeper sync.WaitGroup
+	keeper.Add(1)
+	go func() {
+		defer keeper.Done()
+		q.keepLeased(job, stop)
+	}()
+	defer func() {
+		close(stop)
+		keeper.Wait() // done extending before the job is acked or nacked
+	}()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job %s panicked: %v", job.ID, r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// keepLeased extends the lease of job every half visibility timeout until
+// stop is closed or the lease is lost.
+func (q *JobQueue) keepLeased(job *Job, stop <-chan struct{}) {
+	ticker := time.NewTicker(q.opts.VisibilityTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := q.Extend(job.ID, job.Lease); err != nil {
+				RecordError("jobqueue", err, map[string]interface{}{"queue": q.opts.Name, "job": job.ID})
+				if errors.Is(err, ErrJobLeaseLost) || errors.Is(err, ErrJobNotFound) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -4,6 +4,7 @@ package Utility
 import (
 	"bytes"
 	"encoding/json"
+	"reflect"
 )
 
 // PrettyPrint indents a JSON byte slice.
@@ -39,3 +40,78 @@ func ToMap(in interface{}) (map[string]interface{}, error) {
 	return out, nil
 }
 
+// MarshalDynamic marshals v to JSON, injecting TYPENAME into every registered
+// struct (top-level and nested) so the receiver can rebuild it with
+// UnmarshalDynamic or InitializeStructure.
+func MarshalDynamic(v interface{}) ([]byte, error) {
+	m := &structMapper{opts: MapperOptions{IncludeZero: true, KeepNumericKinds: true}, visiting: make(map[uintptr]bool)}
+	out, err := m.value(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalDynamic decodes JSON produced by MarshalDynamic. Objects carrying a
+// registered TYPENAME come back as *T (via InitializeStructure), arrays of them
+// as []*T; anything else is returned as decoded by encoding/json.
+func UnmarshalDynamic(data []byte, setEntity func(interface{})) (interface{}, error) {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	return initializeDynamic(raw, setEntity)
+}
+
+func initializeDynamic(raw interface{}, setEntity func(interface{})) (interface{}, error) {
+	switch x := raw.(type) {
+	case map[string]interface{}:
+		if _, ok := x[conventions().TypeKey]; !ok {
+			return x, nil
+		}
+		v, err := InitializeStructure(x, setEntity)
+		if err != nil {
+			return nil, err
+		}
+		return v.Interface(), nil
+
+	case []interface{}:
+		if tn := commonTypeName(x); tn != "" {
+			v, err := InitializeStructures(x, tn, setEntity)
+			if err != nil {
+				return nil, err
+			}
+			return v.Interface(), nil
+		}
+		out := make([]interface{}, len(x))
+		for i, e := range x {
+			v, err := initializeDynamic(e, setEntity)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = v
+		}
+		return out, nil
+	}
+	return raw, nil
+}
+
+// commonTypeName returns the registered TYPENAME shared by every element, or "".
+func commonTypeName(items []interface{}) string {
+	tn, typeKey := "", conventions().TypeKey
+	for _, e := range items {
+		m, ok := e.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		name, _ := m[typeKey].(string)
+		if name == "" || (tn != "" && name != tn) {
+			return ""
+		}
+		tn = name
+	}
+	if _, ok := DefaultTypeManager().GetType(tn); !ok {
+		return ""
+	}
+	return tn
+}
@@ -0,0 +1,135 @@
+// utility/kv.go
+package Utility
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Key/value persistence
+// ---------------------
+// KVStore is the minimal persistence contract used by components that must
+// survive restarts (JobQueue, ...). MemoryKV is handy for tests and
+// ephemeral use; FileKV keeps one file per key in a directory and writes
+// atomically (temp file + rename).
+
+// KVStore stores opaque values by key.
+type KVStore interface {
+	Put(key string, value []byte) error
+	Delete(key string) error
+	// List returns every key/value whose key starts with prefix.
+	List(prefix string) (map[string][]byte, error)
+}
+
+// MemoryKV is an in-memory KVStore.
+type MemoryKV struct {
+	mu   sync.RWMutex
+	data map[string][]byte
+}
+
+// NewMemoryKV creates an empty in-memory store.
+func NewMemoryKV() *MemoryKV {
+	return &MemoryKV{data: make(map[string][]byte)}
+}
+
+// Put stores a copy of value under key.
+func (s *MemoryKV) Put(key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = append([]byte(nil), value...)
+	return nil
+}
+
+// Delete removes key (no-op if absent).
+func (s *MemoryKV) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, key)
+	return nil
+}
+
+// List returns copies of the values whose key starts with prefix.
+func (s *MemoryKV) List(prefix string) (map[string][]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make(map[string][]byte)
+	for k, v := range s.data {
+		if strings.HasPrefix(k, prefix) {
+			out[k] = append([]byte(nil), v...)
+		}
+	}
+	return out, nil
+}
+
+// FileKV is a KVStore keeping one file per key under Dir.
+type FileKV struct {
+	Dir string
+}
+
+// NewFileKV creates dir if needed and returns a store rooted there.
+func NewFileKV(dir string) (*FileKV, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileKV{Dir: dir}, nil
+}
+
+func (s *FileKV) path(key string) string {
+	return filepath.Join(s.Dir, url.PathEscape(key))
+}
+
+// Put atomically writes value under key.
+func (s *FileKV) Put(key string, value []byte) error {
+	tmp, err := os.CreateTemp(s.Dir, ".kv-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(key))
+}
+
+// Delete removes key (no-op if absent).
+func (s *FileKV) Delete(key string) error {
+	if err := os.Remove(s.path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// List reads every key starting with prefix.
+func (s *FileKV) List(prefix string) (map[string][]byte, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string][]byte)
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".kv-") {
+			continue
+		}
+		key, err := url.PathUnescape(e.Name())
+		if err != nil || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.Dir, e.Name()))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		out[key] = data
+	}
+	return out, nil
+}
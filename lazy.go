@@ -0,0 +1,203 @@
+// utility/lazy.go
+package Utility
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Lazy references
+// ---------------
+// ResolveReferences wires every referenced entity up front. For large graphs
+// a struct can instead declare an M_xxxLazy companion (*Lazy[*User] or
+// []*Lazy[*User]) next to M_xxx; BindLazyReferences fills it with proxies
+// that only call the loader (e.g. EntityStore.Get) on first Get.
+//
+//	type Group struct {
+//		M_Owner       string
+//		M_OwnerLazy   *Lazy[*User]
+//		M_Members     []string
+//		M_MembersLazy []*Lazy[*User]
+//	}
+//
+// Proxies encode as their UUID (gob and JSON) so entities stay serializable.
+
+// LazyLoader fetches an entity by UUID.
+type LazyLoader func(uuid string) (interface{}, error)
+
+// Lazy is a reference to an entity of type T loaded on first access. Failed
+// loads are retried on the next Get.
+type Lazy[T any] struct {
+	mu     sync.Mutex
+	uuid   string
+	load   LazyLoader
+	loaded bool
+	value  T
+}
+
+// NewLazy creates a proxy for uuid.
+func NewLazy[T any](uuid string, load LazyLoader) *Lazy[T] {
+	return &Lazy[T]{uuid: uuid, load: load}
+}
+
+// UUID returns the referenced UUID without loading.
+func (l *Lazy[T]) UUID() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.uuid
+}
+
+// Loaded reports whether the entity was fetched already.
+func (l *Lazy[T]) Loaded() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.loaded
+}
+
+// Get returns the entity, loading it on the first call.
+func (l *Lazy[T]) Get() (T, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var zero T
+	if l.loaded {
+		return l.value, nil
+	}
+	if l.load == nil {
+		return zero, fmt.Errorf("lazy reference %s has no loader", l.uuid)
+	}
+	obj, err := l.load(l.uuid)
+	if err != nil {
+		return zero, err
+	}
+	v, ok := obj.(T)
+	if !ok {
+		return zero, fmt.Errorf("lazy reference %s: loaded %T, want %s", l.uuid, obj, reflect.TypeOf((*T)(nil)).Elem())
+	}
+	l.value, l.loaded = v, true
+	return v, nil
+}
+
+// bind points the proxy at uuid and drops any loaded value.
+func (l *Lazy[T]) bind(uuid string, load LazyLoader) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var zero T
+	l.uuid, l.load, l.loaded, l.value = uuid, load, false, zero
+}
+
+// GobEncode encodes the UUID only.
+func (l *Lazy[T]) GobEncode() ([]byte, error) { return []byte(l.UUID()), nil }
+
+// GobDecode restores the UUID; the proxy has no loader until rebound.
+func (l *Lazy[T]) GobDecode(data []byte) error {
+	l.bind(string(data), nil)
+	return nil
+}
+
+// MarshalJSON encodes the UUID as a string.
+func (l *Lazy[T]) MarshalJSON() ([]byte, error) { return json.Marshal(l.UUID()) }
+
+// UnmarshalJSON restores the UUID; the proxy has no loader until rebound.
+func (l *Lazy[T]) UnmarshalJSON(data []byte) error {
+	var uuid string
+	if err := json.Unmarshal(data, &uuid); err != nil {
+		return err
+	}
+	l.bind(uuid, nil)
+	return nil
+}
+
+type lazyBinder interface {
+	bind(uuid string, load LazyLoader)
+}
+
+var lazyBinderType = reflect.TypeOf((*lazyBinder)(nil)).Elem()
+
+// BindLazyReferences walks the entities (and the entities nested in them)
+// and fills each M_xxxLazy field with proxies for the UUIDs held in M_xxx.
+// Nothing is loaded.
+func BindLazyReferences(entities []interface{}, load LazyLoader) {
+	b := &lazyBindWalker{load: load, visited: make(map[uintptr]bool)}
+	for _, e := range entities {
+		b.walk(reflect.ValueOf(e))
+	}
+}
+
+type lazyBindWalker struct {
+	load    LazyLoader
+	visited map[uintptr]bool
+}
+
+func (b *lazyBindWalker) walk(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || v.Type().Implements(lazyBinderType) || b.visited[v.Pointer()] {
+			return
+		}
+		b.visited[v.Pointer()] = true
+		b.walk(v.Elem())
+	case reflect.Interface:
+		if !v.IsNil() {
+			b.walk(v.Elem())
+		}
+	case reflect.Struct:
+		t := v.Type()
+		refPrefix := conventionsOfType(t).RefPrefix
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if sf.PkgPath != "" {
+				continue
+			}
+			if strings.HasPrefix(sf.Name, refPrefix) && !strings.HasSuffix(sf.Name, "Ptr") && !strings.HasSuffix(sf.Name, "Lazy") {
+				if lazy := v.FieldByName(sf.Name + "Lazy"); lazy.IsValid() && lazy.CanSet() {
+					b.bindField(v.Field(i), lazy)
+				}
+			}
+			b.walk(v.Field(i))
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			b.walk(v.Index(i))
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			b.walk(iter.Value())
+		}
+	}
+}
+
+// bindField sets dst (*Lazy[T] or []*Lazy[T]) from the UUID(s) in src.
+func (b *lazyBindWalker) bindField(src, dst reflect.Value) {
+	switch {
+	case src.Kind() == reflect.String && dst.Type().Implements(lazyBinderType):
+		if src.Len() == 0 {
+			dst.Set(reflect.Zero(dst.Type()))
+			return
+		}
+		dst.Set(b.proxy(dst.Type(), src.String()))
+	case src.Kind() == reflect.Slice && src.Type().Elem().Kind() == reflect.String &&
+		dst.Kind() == reflect.Slice && dst.Type().Elem().Implements(lazyBinderType):
+		out := reflect.MakeSlice(dst.Type(), 0, src.Len())
+		for i := 0; i < src.Len(); i++ {
+			if uuid := src.Index(i).String(); uuid != "" {
+				out = reflect.Append(out, b.proxy(dst.Type().Elem(), uuid))
+			}
+		}
+		dst.Set(out)
+	}
+}
+
+func (b *lazyBindWalker) proxy(t reflect.Type, uuid string) reflect.Value {
+	p := reflect.New(t.Elem())
+	p.Interface().(lazyBinder).bind(uuid, b.load)
+	return p
+}
+
+// BindLazy binds the lazy references of the entities to the store.
+func (s *EntityStore) BindLazy(entities ...interface{}) {
+	BindLazyReferences(entities, s.Get)
+}
@@ -0,0 +1,25 @@
+// utility/local_ipc.go
+package Utility
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// Local IPC endpoints
+// -------------------
+// ListenLocal / DialLocal hide the transport used for same-host IPC: Unix
+// domain sockets everywhere but Windows, named pipes on Windows. A bare name
+// ("supervisor") maps to a socket file in the temp directory or to
+// \\.\pipe\supervisor; an absolute path or a full pipe name is used as-is.
+
+// DefaultLocalDialTimeout bounds DialLocal.
+const DefaultLocalDialTimeout = 5 * time.Second
+
+// DialLocal connects to the local endpoint created by ListenLocal(name, ...).
+func DialLocal(name string) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultLocalDialTimeout)
+	defer cancel()
+	return DialLocalContext(ctx, name)
+}
@@ -0,0 +1,109 @@
+// utility/local_ipc_unix.go
+//go:build !windows
+
+package Utility
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LocalAddress returns the socket path used for name.
+func LocalAddress(name string) string {
+	if strings.ContainsRune(name, os.PathSeparator) {
+		return name
+	}
+	return filepath.Join(os.TempDir(), name+".sock")
+}
+
+// checkLocalName accepts a bare name, which LocalAddress places in the temp
+// directory, or an absolute socket path.
+func checkLocalName(name string) error {
+	if err := RequireNonEmpty(name, "name"); err != nil {
+		return err
+	}
+	if strings.ContainsRune(name, os.PathSeparator) && !filepath.IsAbs(name) {
+		return &GuardError{Field: "name", Reason: "must be a bare name or an absolute path: " + name}
+	}
+	return nil
+}
+
+// ListenLocal listens on a Unix domain socket for name and applies perm to
+// the socket file (e.g. 0600 for owner-only, 0660 for the owner's group).
+// A stale socket left by a dead process is removed first; any other file at
+// the path is an error. The socket is bound in a private directory and only
+// renamed into place once perm is applied, so it is never reachable with
+// looser permissions.
+func ListenLocal(name string, perm os.FileMode) (net.Listener, error) {
+	if err := checkLocalName(name); err != nil {
+		return nil, err
+	}
+	path := LocalAddress(name)
+
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, &os.PathError{Op: "listen", Path: path, Err: errors.New("file exists and is not a socket")}
+		}
+		if c, err := net.DialTimeout("unix", path, 500*time.Millisecond); err == nil {
+			c.Close()
+			return nil, &net.OpError{Op: "listen", Net: "unix", Err: os.ErrExist}
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".") // 0700; short, as socket paths are limited
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	tmp := filepath.Join(dir, "s")
+	l, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, err
+	}
+	if u, ok := l.(interface{ SetUnlinkOnClose(bool) }); ok {
+		u.SetUnlinkOnClose(false) // the socket file moves; localListener removes it
+	}
+	if err := os.Chmod(tmp, perm); err != nil {
+		l.Close()
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return &localListener{Listener: l, addr: &net.UnixAddr{Name: path, Net: "unix"}}, nil
+}
+
+// localListener is a Unix listener whose socket file was renamed after
+// binding: it reports and, on Close, removes the final path.
+type localListener struct {
+	net.Listener
+	addr *net.UnixAddr
+	once sync.Once
+}
+
+func (l *localListener) Addr() net.Addr { return l.addr }
+
+func (l *localListener) Close() error {
+	err := l.Listener.Close()
+	l.once.Do(func() { os.Remove(l.addr.Name) })
+	return err
+}
+
+// DialLocalContext connects to the Unix socket for name.
+func DialLocalContext(ctx context.Context, name string) (net.Conn, error) {
+	if err := checkLocalName(name); err != nil {
+		return nil, err
+	}
+	var d net.Dialer
+	return d.DialContext(ctx, "unix", LocalAddress(name))
+}
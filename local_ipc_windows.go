@@ -0,0 +1,48 @@
+// utility/local_ipc_windows.go
+//go:build windows
+
+package Utility
+
+import (
+	"context"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/Microsoft/go-winio"
+)
+
+const pipePrefix = `\\.\pipe\`
+
+// LocalAddress returns the named pipe path used for name.
+func LocalAddress(name string) string {
+	if strings.HasPrefix(name, pipePrefix) {
+		return name
+	}
+	return pipePrefix + name
+}
+
+// pipeSDDL translates Unix-style permissions into a pipe security descriptor:
+// the owner, SYSTEM and Administrators always have full access; group or
+// other bits grant read/write to authenticated users.
+func pipeSDDL(perm os.FileMode) string {
+	sddl := "D:P(A;;GA;;;SY)(A;;GA;;;BA)(A;;GA;;;OW)"
+	if perm&0o066 != 0 {
+		sddl += "(A;;GRGW;;;AU)"
+	}
+	return sddl
+}
+
+// ListenLocal listens on the named pipe for name, restricting access per perm.
+func ListenLocal(name string, perm os.FileMode) (net.Listener, error) {
+	return winio.ListenPipe(LocalAddress(name), &winio.PipeConfig{
+		SecurityDescriptor: pipeSDDL(perm),
+		InputBufferSize:    64 * 1024,
+		OutputBufferSize:   64 * 1024,
+	})
+}
+
+// DialLocalContext connects to the named pipe for name.
+func DialLocalContext(ctx context.Context, name string) (net.Conn, error) {
+	return winio.DialPipeContext(ctx, LocalAddress(name))
+}
@@ -0,0 +1,343 @@
+// utility/mapper.go
+package Utility
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Reflection-based struct ↔ map mapper
+// ------------------------------------
+// Unlike ToMap (which round-trips through encoding/json), StructToMap keeps
+// Go types intact unless asked otherwise, and both directions understand the
+// TYPENAME convention used by InitializeStructure.
+
+// MapperOptions controls StructToMap / MapToStruct.
+type MapperOptions struct {
+	// IncludeZero keeps zero-valued fields (and nil pointers) in the output map.
+	IncludeZero bool
+	// TagName names the struct tag used to rename keys (e.g. "json").
+	// A tag value of "-" skips the field. Empty means Go field names.
+	TagName string
+	// Flatten merges nested structs into the parent map using Separator-joined keys.
+	Flatten bool
+	// Separator joins flattened keys; defaults to ".".
+	Separator string
+	// KeepNumericKinds keeps ints/uints/float32 as-is; otherwise numbers become
+	// float64, matching what encoding/json would produce.
+	KeepNumericKinds bool
+	// OmitTypeName disables TYPENAME injection for registered types.
+	OmitTypeName bool
+}
+
+func (o MapperOptions) separator() string {
+	if o.Separator == "" {
+		return "."
+	}
+	return o.Separator
+}
+
+// fieldKey returns the map key for f and whether the field is mapped at all.
+func (o MapperOptions) fieldKey(f reflect.StructField) (string, bool) {
+	if f.PkgPath != "" {
+		return "", false
+	}
+	if o.TagName == "" {
+		return f.Name, true
+	}
+	tag := f.Tag.Get(o.TagName)
+	if tag == "-" {
+		return "", false
+	}
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name, true
+	}
+	return f.Name, true
+}
+
+// StructToMap converts a struct (or pointer to struct) into a map.
+func StructToMap(v interface{}, opts MapperOptions) (map[string]interface{}, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, errors.New("StructToMap: nil pointer")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("StructToMap: expected struct, got %v", rv.Kind())
+	}
+	m := &structMapper{opts: opts, visiting: make(map[uintptr]bool)}
+	return m.structToMap(rv)
+}
+
+type structMapper struct {
+	opts     MapperOptions
+	visiting map[uintptr]bool
+}
+
+func (m *structMapper) structToMap(rv reflect.Value) (map[string]interface{}, error) {
+	out := make(map[string]interface{})
+	t := rv.Type()
+	typeKey := conventionsOfType(t).TypeKey
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key, ok := m.opts.fieldKey(f)
+		if !ok {
+			continue
+		}
+		fv := rv.Field(i)
+		if !m.opts.IncludeZero && fv.IsZero() {
+			continue
+		}
+
+		val, err := m.value(fv)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+
+		if nested, isMap := val.(map[string]interface{}); isMap && isStructLike(f.Type) {
+			promoted := f.Anonymous && (m.opts.TagName == "" || f.Tag.Get(m.opts.TagName) == "")
+			if promoted || m.opts.Flatten {
+				prefix := key + m.opts.separator()
+				if promoted {
+					prefix = "" // promoted fields keep their own names
+				}
+				for k, v := range nested {
+					if _, shadowed := out[prefix+k]; k == typeKey || (promoted && shadowed) {
+						continue
+					}
+					out[prefix+k] = v
+				}
+				continue
+			}
+		}
+		out[key] = val
+	}
+
+	if !m.opts.OmitTypeName {
+		if tn, ok := out[typeKey].(string); !ok || tn == "" {
+			if name, registered := DefaultTypeManager().nameOfType(t); registered {
+				out[typeKey] = name
+			}
+		}
+	}
+	return out, nil
+}
+
+// value converts a single field value.
+func (m *structMapper) value(v reflect.Value) (interface{}, error) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil, nil
+		}
+		addr := v.Pointer()
+		if m.visiting[addr] {
+			return nil, errors.New("cycle detected")
+		}
+		m.visiting[addr] = true
+		defer delete(m.visiting, addr)
+		return m.value(v.Elem())
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return m.value(v.Elem())
+
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return v.Interface(), nil
+		}
+		return m.structToMap(v)
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface(), nil
+		}
+		out := make([]interface{}, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			e, err := m.value(v.Index(i))
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			out[i] = e
+		}
+		return out, nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if v.Type().Key().Kind() != reflect.String {
+			return v.Interface(), nil
+		}
+		out := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			e, err := m.value(iter.Value())
+			if err != nil {
+				return nil, fmt.Errorf("[%s]: %w", iter.Key().String(), err)
+			}
+			out[iter.Key().String()] = e
+		}
+		return out, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if m.opts.KeepNumericKinds {
+			return v.Interface(), nil
+		}
+		return float64(v.Int()), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if m.opts.KeepNumericKinds {
+			return v.Interface(), nil
+		}
+		return float64(v.Uint()), nil
+
+	case reflect.Float32:
+		if m.opts.KeepNumericKinds {
+			return v.Interface(), nil
+		}
+		return v.Float(), nil
+
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return nil, fmt.Errorf("unsupported kind %v", v.Kind())
+	}
+
+	if v.CanInterface() {
+		return v.Interface(), nil
+	}
+	return nil, nil
+}
+
+// MapToStruct fills the struct pointed to by dst from m. Nested structs are
+// filled recursively whether or not the nested maps carry a TYPENAME, and
+// flattened keys are expanded when opts.Flatten is set. Fields whose value
+// can't be converted are left unset and reported, by path, in the returned
+// *InitError; the other fields are still filled.
+func MapToStruct(m map[string]interface{}, dst interface{}, opts MapperOptions) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("MapToStruct: dst must be a non-nil pointer to struct")
+	}
+	if opts.Flatten {
+		m = unflattenMap(m, opts.separator())
+	}
+	st := newInitState(conventionsOfType(rv.Type()), InitOptions{Strict: true})
+	mapToStructValue(st, m, rv, opts)
+	if len(st.errs) > 0 {
+		return &InitError{TypeName: rv.Elem().Type().String(), Fields: st.errs}
+	}
+	return nil
+}
+
+// mapToStructValue fills ptr (a *struct value) from m, recording the fields
+// it can't set in st.
+func mapToStructValue(st *initState, m map[string]interface{}, ptr reflect.Value, opts MapperOptions) {
+	conv := st.conv
+	st.conv = conventionsOfType(ptr.Type())
+	defer func() { st.conv = conv }()
+
+	t := ptr.Elem().Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key, ok := opts.fieldKey(f)
+		if !ok {
+			continue
+		}
+
+		raw, present := m[key]
+		promoted := false
+		if f.Anonymous && isStructLike(f.Type) && !present {
+			// promoted fields are stored at the parent level
+			raw, present, promoted = m, true, true
+		}
+		if !present || raw == nil {
+			continue
+		}
+
+		field := ptr.Elem().Field(i)
+		if sub, isMap := raw.(map[string]interface{}); isMap && isStructLike(f.Type) && f.Type != timeType {
+			if !promoted {
+				st.push(key)
+			}
+			if f.Type.Kind() == reflect.Ptr {
+				if field.IsNil() {
+					field.Set(reflect.New(f.Type.Elem()))
+				}
+				mapToStructValue(st, sub, field, opts)
+			} else {
+				mapToStructValue(st, sub, field.Addr(), opts)
+			}
+			if !promoted {
+				st.pop()
+			}
+			continue
+		}
+
+		if items, isSlice := raw.([]interface{}); isSlice && f.Type.Kind() == reflect.Slice && isStructLike(f.Type.Elem()) {
+			slice := reflect.MakeSlice(f.Type, len(items), len(items))
+			st.push(key)
+			for j, item := range items {
+				sub, isMap := item.(map[string]interface{})
+				if !isMap {
+					continue
+				}
+				st.push("[" + strconv.Itoa(j) + "]")
+				elem := slice.Index(j)
+				if elem.Kind() == reflect.Ptr {
+					elem.Set(reflect.New(elem.Type().Elem()))
+					mapToStructValue(st, sub, elem, opts)
+				} else {
+					mapToStructValue(st, sub, elem.Addr(), opts)
+				}
+				st.pop()
+			}
+			st.pop()
+			field.Set(slice)
+			continue
+		}
+
+		if rv := reflect.ValueOf(raw); rv.Type().AssignableTo(f.Type) {
+			field.Set(rv)
+			continue
+		}
+		st.field(key, func() { initializeStructureFieldValue(st, ptr, f.Name, f.Type, raw, nil) })
+	}
+}
+
+// unflattenMap expands "a.b.c" keys into nested maps.
+func unflattenMap(m map[string]interface{}, sep string) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		parts := strings.Split(k, sep)
+		cur := out
+		for _, p := range parts[:len(parts)-1] {
+			next, ok := cur[p].(map[string]interface{})
+			if !ok {
+				next = make(map[string]interface{})
+				cur[p] = next
+			}
+			cur = next
+		}
+		cur[parts[len(parts)-1]] = v
+	}
+	return out
+}
+
+// isStructLike reports whether t is a struct or a pointer to a struct.
+func isStructLike(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
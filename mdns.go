@@ -0,0 +1,327 @@
+// utility/mdns.go
+package Utility
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// mDNS / DNS-SD peer discovery
+// ----------------------------
+// Announce answers multicast DNS queries (RFC 6762) for a DNS-SD service
+// (RFC 6763) on the local link, and Discover browses a service type and
+// returns the peers that answered. Discover sends its queries from an
+// ephemeral port, so responders (ours, Avahi or Bonjour) answer it directly
+// and no access to port 5353 is needed to browse.
+
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+const mdnsTTL = 120 // seconds
+
+// Peer is a service instance found by Discover.
+type Peer struct {
+	Instance string            `json:"instance"` // e.g. "node1._globular._tcp.local."
+	Hostname string            `json:"hostname"` // e.g. "node1.local."
+	IPs      []string          `json:"ips"`
+	Port     int               `json:"port"`
+	TXT      map[string]string `json:"txt,omitempty"`
+}
+
+// mdnsServiceName returns the fully qualified service type
+// ("_globular._tcp" -> "_globular._tcp.local.").
+func mdnsServiceName(serviceType string) string {
+	serviceType = strings.TrimSuffix(serviceType, ".")
+	if !strings.HasSuffix(serviceType, ".local") {
+		serviceType += ".local"
+	}
+	return serviceType + "."
+}
+
+// Discover browses serviceType (e.g. "_globular._tcp") for timeout and
+// returns the peers that answered, sorted by instance name.
+func Discover(serviceType string, timeout time.Duration) ([]Peer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return DiscoverCtx(ctx, serviceType)
+}
+
+// DiscoverCtx browses serviceType until ctx is done.
+func DiscoverCtx(ctx context.Context, serviceType string) ([]Peer, error) {
+	service, err := dnsmessage.NewName(mdnsServiceName(serviceType))
+	if err != nil {
+		return nil, &GuardError{Field: "serviceType", Reason: err.Error()}
+	}
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{})
+	b.StartQuestions()
+	b.Question(dnsmessage.Question{Name: service, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET})
+	query, err := b.Finish()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.WriteToUDP(query, mdnsGroup); err != nil {
+		return nil, err
+	}
+	// Ask a second time, half-way, for answers lost on a busy network.
+	resend := time.AfterFunc(time.Until(deadlineOr(ctx, time.Second))/2, func() { conn.WriteToUDP(query, mdnsGroup) })
+	defer resend.Stop()
+
+	c := newMDNSCollector(service.String())
+	buf := make([]byte, 9000)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return nil, err
+		}
+		var msg dnsmessage.Message
+		if msg.Unpack(buf[:n]) == nil && msg.Response {
+			c.add(msg.Answers)
+			c.add(msg.Additionals)
+		}
+	}
+	return c.peers(), nil
+}
+
+// deadlineOr returns ctx's deadline, or now+d when it has none.
+func deadlineOr(ctx context.Context, d time.Duration) time.Time {
+	if dl, ok := ctx.Deadline(); ok {
+		return dl
+	}
+	return time.Now().Add(d)
+}
+
+// mdnsCollector assembles peers from the records of many responses.
+type mdnsCollector struct {
+	service   string
+	instances map[string]bool
+	srv       map[string]dnsmessage.SRVResource
+	txt       map[string][]string
+	addrs     map[string][]string
+}
+
+func newMDNSCollector(service string) *mdnsCollector {
+	return &mdnsCollector{
+		service:   strings.ToLower(service),
+		instances: make(map[string]bool),
+		srv:       make(map[string]dnsmessage.SRVResource),
+		txt:       make(map[string][]string),
+		addrs:     make(map[string][]string),
+	}
+}
+
+func (c *mdnsCollector) add(records []dnsmessage.Resource) {
+	for _, r := range records {
+		name := strings.ToLower(r.Header.Name.String())
+		switch body := r.Body.(type) {
+		case *dnsmessage.PTRResource:
+			if name == c.service && r.Header.TTL > 0 {
+				c.instances[strings.ToLower(body.PTR.String())] = true
+			}
+		case *dnsmessage.SRVResource:
+			c.srv[name] = *body
+		case *dnsmessage.TXTResource:
+			c.txt[name] = body.TXT
+		case *dnsmessage.AResource:
+			c.addHost(name, net.IP(body.A[:]).String())
+		case *dnsmessage.AAAAResource:
+			c.addHost(name, net.IP(body.AAAA[:]).String())
+		}
+	}
+}
+
+func (c *mdnsCollector) addHost(host, ip string) {
+	for _, known := range c.addrs[host] {
+		if known == ip {
+			return
+		}
+	}
+	c.addrs[host] = append(c.addrs[host], ip)
+}
+
+func (c *mdnsCollector) peers() []Peer {
+	peers := make([]Peer, 0, len(c.instances))
+	for instance := range c.instances {
+		srv, ok := c.srv[instance]
+		if !ok {
+			continue
+		}
+		p := Peer{Instance: instance, Hostname: srv.Target.String(), Port: int(srv.Port), TXT: make(map[string]string)}
+		p.IPs = append(p.IPs, c.addrs[strings.ToLower(p.Hostname)]...)
+		for _, kv := range c.txt[instance] {
+			key, value, _ := strings.Cut(kv, "=")
+			if key != "" {
+				p.TXT[key] = value
+			}
+		}
+		peers = append(peers, p)
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].Instance < peers[j].Instance })
+	return peers
+}
+
+// Announcement is a service being announced on the local link.
+type Announcement struct {
+	conn      *net.UDPConn
+	service   dnsmessage.Name
+	instance  dnsmessage.Name
+	host      dnsmessage.Name
+	port      uint16
+	txt       []string
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// Announce publishes this host as an instance of serviceName (e.g.
+// "_globular._tcp") listening on port, with txt as metadata, until Close is
+// called. The instance is named after the hostname.
+func Announce(serviceName string, port int, txt map[string]string) (*Announcement, error) {
+	if port < 1 || port > 65535 {
+		return nil, &GuardError{Field: "port", Reason: "out of range"}
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	hostname, _, _ = strings.Cut(hostname, ".")
+
+	service := mdnsServiceName(serviceName)
+	a := &Announcement{port: uint16(port), done: make(chan struct{})}
+	if a.service, err = dnsmessage.NewName(service); err != nil {
+		return nil, &GuardError{Field: "serviceName", Reason: err.Error()}
+	}
+	if a.instance, err = dnsmessage.NewName(hostname + "." + service); err != nil {
+		return nil, err
+	}
+	if a.host, err = dnsmessage.NewName(hostname + ".local."); err != nil {
+		return nil, err
+	}
+	for key, value := range txt {
+		a.txt = append(a.txt, key+"="+value)
+	}
+	sort.Strings(a.txt)
+	if len(a.txt) == 0 {
+		a.txt = []string{""} // a TXT record holds at least one string
+	}
+
+	if a.conn, err = net.ListenMulticastUDP("udp4", nil, mdnsGroup); err != nil {
+		return nil, err
+	}
+	a.send(mdnsGroup, mdnsTTL)
+	go a.serve()
+	return a, nil
+}
+
+// Close sends a goodbye packet and stops answering queries.
+func (a *Announcement) Close() error {
+	var err error
+	a.closeOnce.Do(func() {
+		a.send(mdnsGroup, 0)
+		close(a.done)
+		err = a.conn.Close()
+	})
+	return err
+}
+
+func (a *Announcement) serve() {
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := a.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-a.done:
+				return
+			default:
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		var msg dnsmessage.Message
+		if msg.Unpack(buf[:n]) != nil || msg.Response || !a.matches(msg.Questions) {
+			continue
+		}
+		// Legacy unicast queries (not from port 5353) are answered directly.
+		to := mdnsGroup
+		if from.Port != mdnsGroup.Port {
+			to = from
+		}
+		a.send(to, mdnsTTL)
+	}
+}
+
+func (a *Announcement) matches(questions []dnsmessage.Question) bool {
+	for _, q := range questions {
+		name := strings.ToLower(q.Name.String())
+		switch {
+		case name == strings.ToLower(a.service.String()) && (q.Type == dnsmessage.TypePTR || q.Type == dnsmessage.TypeALL),
+			name == strings.ToLower(a.instance.String()),
+			name == strings.ToLower(a.host.String()) && (q.Type == dnsmessage.TypeA || q.Type == dnsmessage.TypeALL),
+			name == "_services._dns-sd._udp.local." && q.Type == dnsmessage.TypePTR:
+			return true
+		}
+	}
+	return false
+}
+
+// send writes the full record set (PTR, SRV, TXT, A) to addr with ttl;
+// ttl 0 is a goodbye.
+func (a *Announcement) send(addr *net.UDPAddr, ttl uint32) {
+	hdr := func(name dnsmessage.Name) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: name, Class: dnsmessage.ClassINET, TTL: ttl}
+	}
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true, Authoritative: true})
+	b.EnableCompression()
+	b.StartAnswers()
+	b.PTRResource(hdr(a.service), dnsmessage.PTRResource{PTR: a.instance})
+	b.SRVResource(hdr(a.instance), dnsmessage.SRVResource{Port: a.port, Target: a.host})
+	b.TXTResource(hdr(a.instance), dnsmessage.TXTResource{TXT: a.txt})
+	for _, ip := range localIPv4s() {
+		var rec dnsmessage.AResource
+		copy(rec.A[:], ip)
+		b.AResource(hdr(a.host), rec)
+	}
+	if msg, err := b.Finish(); err == nil {
+		a.conn.WriteToUDP(msg, addr)
+	}
+}
+
+// localIPv4s returns the IPv4 addresses of the interfaces that are up,
+// loopback excluded.
+func localIPv4s() []net.IP {
+	var ips []net.IP
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, _ := iface.Addrs()
+		for _, addr := range addrs {
+			if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() != nil {
+				ips = append(ips, ipnet.IP.To4())
+			}
+		}
+	}
+	return ips
+}
@@ -0,0 +1,179 @@
+// utility/merge.go
+package Utility
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Struct merging
+// --------------
+// Merge applies the non-zero fields of src onto dst, which is what a
+// PATCH-style partial update of an entity needs. Zero fields of src never
+// clear anything in dst.
+
+// MergeOptions controls Merge.
+type MergeOptions struct {
+	// Overwrite replaces non-zero dst fields; otherwise only zero dst fields
+	// are filled.
+	Overwrite bool
+	// AppendSlices appends src slices to dst slices instead of replacing them.
+	AppendSlices bool
+	// Deep merges nested structs, pointers to structs and maps field by field
+	// (key by key) instead of treating them as single values.
+	Deep bool
+}
+
+// Merge copies the non-zero exported fields of src into dst. dst must be a
+// non-nil *T and src a T or *T of the same struct type.
+func Merge(dst, src interface{}, opts MergeOptions) error {
+	dv := reflect.ValueOf(dst)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() || dv.Elem().Kind() != reflect.Struct {
+		return &GuardError{Field: "dst", Reason: "must be a non-nil pointer to a struct"}
+	}
+	sv := reflect.ValueOf(src)
+	if sv.Kind() == reflect.Ptr {
+		if sv.IsNil() {
+			return nil
+		}
+		sv = sv.Elem()
+	}
+	if sv.Type() != dv.Elem().Type() {
+		return &GuardError{Field: "src", Reason: fmt.Sprintf("type %v does not match %v", sv.Type(), dv.Elem().Type())}
+	}
+	mergeStruct(dv.Elem(), sv, opts)
+	return nil
+}
+
+// MergeMap merges a partial map (e.g. a decoded PATCH body) into dst: the map
+// is first turned into a T, then merged like Merge.
+func MergeMap(dst interface{}, patch map[string]interface{}, opts MergeOptions) error {
+	dv := reflect.ValueOf(dst)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() || dv.Elem().Kind() != reflect.Struct {
+		return &GuardError{Field: "dst", Reason: "must be a non-nil pointer to a struct"}
+	}
+	src := newStructFromMap(newInitState(conventionsOfType(dv.Type()), InitOptions{}), dv.Elem().Type(), patch, nil)
+	return Merge(dst, src.Interface(), opts)
+}
+
+// MergeIntoInstance applies the keys present in data to existing, a non-nil
+// *T, leaving every other field alone. Unlike MergeMap, zero values are
+// applied too and nil clears a field. Values are converted as MakeInstance
+// does, maps are merged into nested structs that already exist, and keys may
+// be property paths ("Address.City", "Tags[2]"). Keys that could not be
+// applied are reported in an *InitError; the others are applied regardless.
+func MergeIntoInstance(existing interface{}, data map[string]interface{}) error {
+	rv := reflect.ValueOf(existing)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return &GuardError{Field: "existing", Reason: "must be a non-nil pointer to a struct"}
+	}
+	st := newInitState(conventionsOfType(rv.Type()), InitOptions{Strict: true})
+	mergeMapInto(st, rv, data)
+	return st.result(rv.Elem().Type().String())
+}
+
+// mergeMapInto applies data to the struct v points to.
+func mergeMapInto(st *initState, v reflect.Value, data map[string]interface{}) {
+	fields := cachedFields(v.Elem().Type())
+	for name, raw := range data {
+		if name == st.conv.TypeKey {
+			continue // a patch never changes the type
+		}
+		st.field(name, func() {
+			fd, ok := fields[name]
+			switch {
+			case !ok && strings.ContainsAny(name, ".["):
+				segs, err := parsePropertyPath(name)
+				if err == nil {
+					err = setPathValue(v.Elem(), segs, raw, "")
+				}
+				if err != nil {
+					st.fail("%s", strings.TrimPrefix(err.Error(), name+": "))
+				}
+			case !ok:
+				st.fail("no such field")
+			case raw == nil:
+				if field := structField(v.Elem(), name); field.IsValid() {
+					field.Set(reflect.Zero(field.Type()))
+				}
+			default:
+				if m, isMap := raw.(map[string]interface{}); isMap {
+					if target := mergeTarget(structField(v.Elem(), name)); target.IsValid() {
+						mergeMapInto(st, target, m)
+						return
+					}
+				}
+				initializeStructureFieldValue(st, v, name, fd.Type, raw, nil)
+			}
+		})
+	}
+}
+
+// mergeTarget returns a pointer to the existing struct held by field, or an
+// invalid value when there is none to merge into.
+func mergeTarget(field reflect.Value) reflect.Value {
+	switch {
+	case field.Kind() == reflect.Struct && field.CanAddr() && hasExportedFields(field.Type()):
+		return field.Addr()
+	case field.Kind() == reflect.Ptr && !field.IsNil() && field.Elem().Kind() == reflect.Struct &&
+		hasExportedFields(field.Elem().Type()):
+		return field
+	}
+	return reflect.Value{}
+}
+
+func mergeStruct(dst, src reflect.Value, opts MergeOptions) {
+	for _, fd := range cachedFields(dst.Type()) {
+		if len(fd.Index) != 1 {
+			continue // promoted fields are merged through their embedded struct
+		}
+		d, s := dst.Field(fd.Index[0]), src.Field(fd.Index[0])
+		if d.CanSet() {
+			mergeValue(d, s, opts)
+		}
+	}
+}
+
+func mergeValue(dst, src reflect.Value, opts MergeOptions) {
+	if src.IsZero() {
+		return
+	}
+	switch {
+	case opts.Deep && src.Kind() == reflect.Struct && hasExportedFields(src.Type()):
+		mergeStruct(dst, src, opts)
+		return
+
+	case opts.Deep && src.Kind() == reflect.Ptr && src.Elem().Kind() == reflect.Struct && !dst.IsNil() &&
+		hasExportedFields(src.Elem().Type()):
+		if dst.Pointer() != src.Pointer() {
+			mergeStruct(dst.Elem(), src.Elem(), opts)
+		}
+		return
+
+	case opts.Deep && src.Kind() == reflect.Map && !dst.IsNil():
+		iter := src.MapRange()
+		for iter.Next() {
+			if !opts.Overwrite && dst.MapIndex(iter.Key()).IsValid() {
+				continue
+			}
+			dst.SetMapIndex(iter.Key(), iter.Value())
+		}
+		return
+
+	case opts.AppendSlices && src.Kind() == reflect.Slice && !dst.IsNil():
+		out := reflect.MakeSlice(dst.Type(), 0, dst.Len()+src.Len())
+		dst.Set(reflect.AppendSlice(reflect.AppendSlice(out, dst), src))
+		return
+	}
+
+	if opts.Overwrite || dst.IsZero() {
+		dst.Set(src)
+	}
+}
+
+// hasExportedFields reports whether a struct type has fields Merge can see;
+// structs without (time.Time, ...) are merged as single values.
+func hasExportedFields(t reflect.Type) bool {
+	return len(cachedFields(t)) > 0
+}
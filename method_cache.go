@@ -0,0 +1,113 @@
+// utility/method_cache.go
+package Utility
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Method dispatch cache
+// ---------------------
+// CallMethod and CallFunction used to look the method up by name and work
+// out the parameter types on every call. Plans holding the method index,
+// the interceptor name and the argument conversions are built once per
+// (receiver type, method name) and per function type, then reused.
+
+type callPlan struct {
+	index int  // method index, on *T when onPtr
+	onPtr bool // the method has a pointer receiver
+	name  string
+	args  *argPlan
+}
+
+// argPlan turns []interface{} params into call arguments for one signature.
+type argPlan struct {
+	numIn    int
+	variadic bool
+	targets  []reflect.Type  // parameter types; the element type for the variadic one
+	zeros    []reflect.Value // what a nil param becomes
+}
+
+type methodKey struct {
+	t    reflect.Type
+	name string
+}
+
+var (
+	methodPlans sync.Map // methodKey -> *callPlan
+	funcPlans   sync.Map // reflect.Type -> *argPlan
+)
+
+// methodPlan returns the plan for calling name on a value of type t (not a
+// pointer type), or nil when neither T nor *T has that method. Misses aren't
+// cached: names come from callers (HasMethod, RPC requests) and would grow
+// the cache without bound.
+func methodPlan(t reflect.Type, name string) *callPlan {
+	key := methodKey{t, name}
+	if p, ok := methodPlans.Load(key); ok {
+		return p.(*callPlan)
+	}
+	var plan *callPlan
+	if m, ok := t.MethodByName(name); ok {
+		plan = &callPlan{index: m.Index, args: newArgPlan(reflect.Zero(t).Method(m.Index).Type())}
+	} else if m, ok := reflect.PointerTo(t).MethodByName(name); ok {
+		plan = &callPlan{index: m.Index, onPtr: true, args: newArgPlan(reflect.New(t).Method(m.Index).Type())}
+	}
+	if plan == nil {
+		return nil
+	}
+	plan.name = typeNameOf(t) + "." + name
+	methodPlans.Store(key, plan)
+	return plan
+}
+
+// funcArgPlan returns the argument plan of the function type ft.
+func funcArgPlan(ft reflect.Type) *argPlan {
+	if p, ok := funcPlans.Load(ft); ok {
+		return p.(*argPlan)
+	}
+	p := newArgPlan(ft)
+	funcPlans.Store(ft, p)
+	return p
+}
+
+func newArgPlan(ft reflect.Type) *argPlan {
+	p := &argPlan{numIn: ft.NumIn(), variadic: ft.IsVariadic()}
+	for i := 0; i < ft.NumIn(); i++ {
+		t := ft.In(i)
+		if p.variadic && i == ft.NumIn()-1 {
+			t = t.Elem()
+		}
+		p.targets = append(p.targets, t)
+		p.zeros = append(p.zeros, reflect.Zero(t))
+	}
+	return p
+}
+
+// arity reports whether n params fit the signature. Like the calls
+// themselves, it doesn't check the minimum of a variadic signature.
+func (p *argPlan) arity(n int) bool {
+	return p.variadic || n == p.numIn
+}
+
+// values converts params: nil gives the zero value of the parameter type,
+// and convertible values are converted to it.
+func (p *argPlan) values(params []interface{}) []reflect.Value {
+	in := make([]reflect.Value, len(params))
+	for i, v := range params {
+		k := i
+		if k >= len(p.targets) {
+			k = len(p.targets) - 1 // variadic tail
+		}
+		if v == nil {
+			in[i] = p.zeros[k]
+			continue
+		}
+		rv := reflect.ValueOf(v)
+		if target := p.targets[k]; rv.Type() != target && rv.CanConvert(target) {
+			rv = rv.Convert(target)
+		}
+		in[i] = rv
+	}
+	return in
+}
@@ -0,0 +1,290 @@
+// utility/mmdb.go
+package Utility
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"os"
+)
+
+// MaxMind DB reader
+// -----------------
+// A minimal, dependency-free reader for the MaxMind DB format used by the
+// GeoLite2/GeoIP2 databases: a binary search tree over the address bits
+// whose leaves point into a data section of self-describing values (maps,
+// arrays, strings, numbers). Values are decoded into map[string]interface{},
+// []interface{}, string, float64, uint64, int32, bool and []byte.
+
+var mmdbMetadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// MMDB is an opened MaxMind database, held in memory.
+type MMDB struct {
+	buf        []byte
+	data       []byte // data section
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	ipv4Start  uint // node reached after the 96 zero bits of ::/96
+	Metadata   map[string]interface{}
+}
+
+// OpenMMDB reads a MaxMind database file.
+func OpenMMDB(path string) (*MMDB, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewMMDB(buf)
+}
+
+// NewMMDB parses a MaxMind database held in buf.
+func NewMMDB(buf []byte) (*MMDB, error) {
+	i := bytes.LastIndex(buf, mmdbMetadataMarker)
+	if i < 0 {
+		return nil, errors.New("mmdb: metadata marker not found")
+	}
+	metaBuf := buf[i+len(mmdbMetadataMarker):]
+	meta, _, err := (&mmdbDecoder{buf: metaBuf}).decode(0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("mmdb: metadata: %w", err)
+	}
+	m, ok := meta.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("mmdb: metadata is not a map")
+	}
+	db := &MMDB{
+		buf:        buf,
+		Metadata:   m,
+		nodeCount:  mmdbUint(m["node_count"]),
+		recordSize: mmdbUint(m["record_size"]),
+		ipVersion:  mmdbUint(m["ip_version"]),
+	}
+	switch db.recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("mmdb: unsupported record size %d", db.recordSize)
+	}
+	treeSize := db.nodeCount * db.recordSize / 4
+	if treeSize+16 > uint(i) {
+		return nil, errors.New("mmdb: truncated search tree")
+	}
+	db.data = buf[treeSize+16 : i]
+
+	if db.ipVersion == 6 {
+		node := uint(0)
+		for bit := 0; bit < 96 && node < db.nodeCount; bit++ {
+			node = db.record(node, 0)
+		}
+		db.ipv4Start = node
+	}
+	return db, nil
+}
+
+// mmdbUint returns a decoded unsigned integer, or 0.
+func mmdbUint(v interface{}) uint {
+	u, _ := v.(uint64)
+	return uint(u)
+}
+
+// record returns the left (bit 0) or right (bit 1) record of node.
+func (db *MMDB) record(node, bit uint) uint {
+	b := db.buf[node*db.recordSize/4:]
+	switch db.recordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:]))
+	}
+}
+
+// Lookup returns the record of the network containing ip, or nil when the
+// database has none.
+func (db *MMDB) Lookup(ip net.IP) (interface{}, error) {
+	node, bits := uint(0), 128
+	addr := ip.To16()
+	if addr == nil {
+		return nil, errors.New("mmdb: invalid IP address")
+	}
+	if v4 := ip.To4(); v4 != nil {
+		addr, bits = v4, 32
+		if db.ipVersion == 6 {
+			node = db.ipv4Start
+		}
+	} else if db.ipVersion == 4 {
+		return nil, errors.New("mmdb: IPv6 lookup in an IPv4 database")
+	}
+
+	for i := 0; i < bits && node < db.nodeCount; i++ {
+		bit := uint(addr[i/8]>>(7-uint(i%8))) & 1
+		node = db.record(node, bit)
+	}
+	switch {
+	case node == db.nodeCount:
+		return nil, nil
+	case node < db.nodeCount:
+		return nil, errors.New("mmdb: invalid search tree")
+	}
+	offset := node - db.nodeCount - 16
+	if offset >= uint(len(db.data)) {
+		return nil, errors.New("mmdb: record points outside the data section")
+	}
+	v, _, err := (&mmdbDecoder{buf: db.data}).decode(offset, 0)
+	return v, err
+}
+
+// mmdbDecoder decodes values of a data section.
+type mmdbDecoder struct {
+	buf []byte
+}
+
+const mmdbMaxDepth = 64
+
+// decode returns the value at offset and the offset following it.
+func (d *mmdbDecoder) decode(offset uint, depth int) (interface{}, uint, error) {
+	if depth > mmdbMaxDepth {
+		return nil, 0, errors.New("mmdb: value nested too deep")
+	}
+	if offset >= uint(len(d.buf)) {
+		return nil, 0, errors.New("mmdb: unexpected end of data")
+	}
+	ctrl := d.buf[offset]
+	offset++
+	typ := uint(ctrl >> 5)
+
+	if typ == 1 { // pointer: the value lives elsewhere, decoding resumes here
+		ss, vvv := uint(ctrl>>3)&3, uint(ctrl&7)
+		n := ss + 1
+		if offset+n > uint(len(d.buf)) {
+			return nil, 0, errors.New("mmdb: truncated pointer")
+		}
+		p := uint(0)
+		for _, b := range d.buf[offset : offset+n] {
+			p = p<<8 | uint(b)
+		}
+		switch ss {
+		case 0:
+			p |= vvv << 8
+		case 1:
+			p = (p | vvv<<16) + 2048
+		case 2:
+			p = (p | vvv<<24) + 526336
+		}
+		v, _, err := d.decode(p, depth+1)
+		return v, offset + n, err
+	}
+
+	if typ == 0 { // extended type
+		if offset >= uint(len(d.buf)) {
+			return nil, 0, errors.New("mmdb: truncated type")
+		}
+		typ = 7 + uint(d.buf[offset])
+		offset++
+	}
+	size := uint(ctrl & 0x1F)
+	if size >= 29 {
+		n := size - 28
+		if offset+n > uint(len(d.buf)) {
+			return nil, 0, errors.New("mmdb: truncated size")
+		}
+		extra := uint(0)
+		for _, b := range d.buf[offset : offset+n] {
+			extra = extra<<8 | uint(b)
+		}
+		offset += n
+		switch n {
+		case 1:
+			size = 29 + extra
+		case 2:
+			size = 285 + extra
+		default:
+			size = 65821 + extra
+		}
+	}
+
+	switch typ {
+	case 7: // map
+		m := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			k, next, err := d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, 0, errors.New("mmdb: map key is not a string")
+			}
+			v, next, err := d.decode(next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[key], offset = v, next
+		}
+		return m, offset, nil
+	case 11: // array
+		a := make([]interface{}, 0, size)
+		for i := uint(0); i < size; i++ {
+			v, next, err := d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			a, offset = append(a, v), next
+		}
+		return a, offset, nil
+	case 14: // boolean, the value is the size
+		return size != 0, offset, nil
+	}
+
+	if offset+size > uint(len(d.buf)) {
+		return nil, 0, errors.New("mmdb: truncated value")
+	}
+	b := d.buf[offset : offset+size]
+	next := offset + size
+	switch typ {
+	case 2:
+		return string(b), next, nil
+	case 3:
+		if size != 8 {
+			return nil, 0, errors.New("mmdb: invalid double size")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), next, nil
+	case 4:
+		return append([]byte(nil), b...), next, nil
+	case 5, 6, 9:
+		if size > 8 {
+			return nil, 0, errors.New("mmdb: invalid integer size")
+		}
+		u := uint64(0)
+		for _, c := range b {
+			u = u<<8 | uint64(c)
+		}
+		return u, next, nil
+	case 8:
+		if size > 4 {
+			return nil, 0, errors.New("mmdb: invalid int32 size")
+		}
+		u := uint32(0)
+		for _, c := range b {
+			u = u<<8 | uint32(c)
+		}
+		return int32(u), next, nil
+	case 10:
+		return new(big.Int).SetBytes(b), next, nil
+	case 15:
+		if size != 4 {
+			return nil, 0, errors.New("mmdb: invalid float size")
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), next, nil
+	}
+	return nil, 0, fmt.Errorf("mmdb: unsupported data type %d", typ)
+}
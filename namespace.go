@@ -0,0 +1,134 @@
+// utility/namespace.go
+package Utility
+
+import (
+	"encoding/gob"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// TypeManager namespaces
+// ----------------------
+// Plugins register types and functions in their own namespace so equal names
+// ("model.User") don't clobber each other. A namespace falls back to its
+// parent for names it doesn't define, and any manager resolves qualified
+// names ("pluginA:model.User") through its own or its ancestors' namespaces.
+
+// Namespace returns the child namespace with the given name, creating it on
+// first use.
+func (tm *TypeManager) Namespace(name string) *TypeManager {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if tm.namespaces == nil {
+		tm.namespaces = make(map[string]*TypeManager)
+	}
+	child, ok := tm.namespaces[name]
+	if !ok {
+		child = NewTypeManager()
+		child.name = name
+		child.parent = tm
+		tm.namespaces[name] = child
+	}
+	return child
+}
+
+// Namespaces returns the names of the child namespaces.
+func (tm *TypeManager) Namespaces() []string {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	names := make([]string, 0, len(tm.namespaces))
+	for n := range tm.namespaces {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Name returns the namespace name ("" for a root manager).
+func (tm *TypeManager) Name() string {
+	return tm.name
+}
+
+// QualifiedName returns typeName prefixed with this namespace, or typeName
+// itself for a root manager.
+func (tm *TypeManager) QualifiedName(typeName string) string {
+	if tm.name == "" {
+		return typeName
+	}
+	return tm.name + ":" + typeName
+}
+
+// findNamespace looks a namespace up in tm, then in its ancestors.
+func (tm *TypeManager) findNamespace(name string) *TypeManager {
+	for m := tm; m != nil; m = m.parent {
+		m.mu.RLock()
+		child := m.namespaces[name]
+		m.mu.RUnlock()
+		if child != nil {
+			return child
+		}
+	}
+	return nil
+}
+
+// RegisterTypeOf registers the type of a typed nil pointer under its
+// package-qualified name (as RegisterType does) and with gob under the
+// namespace-qualified name. It returns the qualified name.
+func (tm *TypeManager) RegisterTypeOf(typedNil interface{}) string {
+	t := reflect.TypeOf(typedNil).Elem()
+	name := typeNameOf(t)
+	tm.mu.RLock()
+	_, exists := tm.typeRegistry[name]
+	tm.mu.RUnlock()
+	if !exists {
+		tm.RegisterType(name, t)
+		if err := registerGobName(tm.QualifiedName(name), typedNil); err != nil {
+			log.Println("RegisterTypeOf:", err)
+		}
+	}
+	return tm.QualifiedName(name)
+}
+
+// Names registered with gob through registerGobName, both ways, so conflicts
+// are reported before gob.RegisterName panics on them. Types are keyed by
+// their dereferenced type, as gob does.
+var (
+	gobNamesMu sync.Mutex
+	gobNames   = make(map[string]reflect.Type)
+	gobTypes   = make(map[reflect.Type]string)
+)
+
+// registerGobName registers value with gob under name. Registering the same
+// pair again is a no-op; a name already used for another type, or a type
+// already registered under another name, is an error.
+func registerGobName(name string, value interface{}) error {
+	t := reflect.TypeOf(value)
+	base := t
+	for base.Kind() == reflect.Ptr {
+		base = base.Elem()
+	}
+	gobNamesMu.Lock()
+	defer gobNamesMu.Unlock()
+	if prev, ok := gobNames[name]; ok {
+		if prev == t {
+			return nil
+		}
+		return fmt.Errorf("gob name %q is already registered for %s", name, prev)
+	}
+	if prev, ok := gobTypes[base]; ok {
+		return fmt.Errorf("type %s is already registered with gob as %q", t, prev)
+	}
+	gob.RegisterName(name, value)
+	gobNames[name] = t
+	gobTypes[base] = name
+	return nil
+}
+
+// RegisterTypeIn registers a type in a namespace of the default TypeManager
+// and returns its qualified name.
+func RegisterTypeIn(namespace string, typedNil interface{}) string {
+	return DefaultTypeManager().Namespace(namespace).RegisterTypeOf(typedNil)
+}
@@ -0,0 +1,414 @@
+// utility/nat.go
+package Utility
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NAT port mapping
+// ----------------
+// RequestPortMapping asks the home router to forward a port to this host,
+// first with NAT-PMP (RFC 6886) on the default gateway, then with UPnP IGD
+// (WANIPConnection / WANPPPConnection found through SSDP). The UPnP control
+// URL is cached once discovered.
+
+const natTimeout = 3 * time.Second
+
+// ErrNoNATGateway is returned when neither NAT-PMP nor UPnP answered.
+var ErrNoNATGateway = errors.New("no NAT-PMP or UPnP gateway found")
+
+// RequestPortMapping forwards externalPort of the gateway to internalPort of
+// this host for lease (0 asks for the longest lease the gateway allows) and
+// returns the external port actually mapped, which NAT-PMP gateways may
+// change. proto is "tcp" or "udp".
+func RequestPortMapping(externalPort, internalPort int, proto string, lease time.Duration) (int, error) {
+	proto, err := natProto(proto, externalPort, internalPort)
+	if err != nil {
+		return 0, err
+	}
+	var errs []error
+	if gw, err := natGateway(); err == nil {
+		mapped, err := natpmpMap(gw, proto, internalPort, externalPort, uint32(lease/time.Second))
+		if err == nil {
+			return mapped, nil
+		}
+		errs = append(errs, fmt.Errorf("nat-pmp: %w", err))
+	}
+	igd, err := discoverIGD()
+	if err == nil {
+		if err = igd.addPortMapping(proto, externalPort, internalPort, lease); err == nil {
+			return externalPort, nil
+		}
+		forgetIGD()
+	}
+	errs = append(errs, fmt.Errorf("upnp: %w", err))
+	return 0, errors.Join(append([]error{ErrNoNATGateway}, errs...)...)
+}
+
+// RemovePortMapping deletes a mapping made by RequestPortMapping.
+func RemovePortMapping(externalPort, internalPort int, proto string) error {
+	proto, err := natProto(proto, externalPort, internalPort)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	if gw, err := natGateway(); err == nil {
+		if _, err := natpmpMap(gw, proto, internalPort, 0, 0); err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("nat-pmp: %w", err))
+	}
+	igd, err := discoverIGD()
+	if err == nil {
+		if err = igd.deletePortMapping(proto, externalPort); err == nil {
+			return nil
+		}
+	}
+	errs = append(errs, fmt.Errorf("upnp: %w", err))
+	return errors.Join(append([]error{ErrNoNATGateway}, errs...)...)
+}
+
+// GetGatewayExternalIP returns the public address of the NAT gateway.
+func GetGatewayExternalIP() (string, error) {
+	var errs []error
+	if gw, err := natGateway(); err == nil {
+		ip, err := natpmpExternalIP(gw)
+		if err == nil {
+			return ip, nil
+		}
+		errs = append(errs, fmt.Errorf("nat-pmp: %w", err))
+	}
+	igd, err := discoverIGD()
+	if err == nil {
+		var ip string
+		if ip, err = igd.externalIP(); err == nil {
+			return ip, nil
+		}
+	}
+	errs = append(errs, fmt.Errorf("upnp: %w", err))
+	return "", errors.Join(append([]error{ErrNoNATGateway}, errs...)...)
+}
+
+func natProto(proto string, ports ...int) (string, error) {
+	proto = strings.ToLower(proto)
+	if proto != "tcp" && proto != "udp" {
+		return "", &GuardError{Field: "proto", Reason: "must be tcp or udp"}
+	}
+	for _, p := range ports {
+		if p < 1 || p > 65535 {
+			return "", &GuardError{Field: "port", Reason: fmt.Sprintf("%d out of range", p)}
+		}
+	}
+	return proto, nil
+}
+
+// natGateway returns the first IPv4 default gateway.
+func natGateway() (net.IP, error) {
+	for _, gw := range defaultGateways() {
+		if ip := net.ParseIP(gw).To4(); ip != nil {
+			return ip, nil
+		}
+	}
+	return nil, errors.New("no default gateway")
+}
+
+// --- NAT-PMP -----------------------------------------------------------
+
+// natpmpCall sends req to the gateway's NAT-PMP port and returns the answer,
+// retrying with the RFC's doubling timeout.
+func natpmpCall(gw net.IP, req []byte, size int) ([]byte, error) {
+	conn, err := net.DialUDP("udp4", nil, &net.UDPAddr{IP: gw, Port: 5351})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	resp := make([]byte, 16)
+	wait := 250 * time.Millisecond
+	for deadline := time.Now().Add(natTimeout); time.Now().Before(deadline); wait *= 2 {
+		if _, err := conn.Write(req); err != nil {
+			return nil, err
+		}
+		conn.SetReadDeadline(time.Now().Add(wait))
+		n, err := conn.Read(resp)
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				continue
+			}
+			return nil, err
+		}
+		if n < size || resp[0] != 0 || resp[1] != req[1]+128 {
+			return nil, errors.New("malformed answer")
+		}
+		if code := binary.BigEndian.Uint16(resp[2:4]); code != 0 {
+			return nil, fmt.Errorf("gateway refused the request (result %d)", code)
+		}
+		return resp[:n], nil
+	}
+	return nil, errors.New("no answer from " + gw.String())
+}
+
+func natpmpExternalIP(gw net.IP) (string, error) {
+	resp, err := natpmpCall(gw, []byte{0, 0}, 12)
+	if err != nil {
+		return "", err
+	}
+	return net.IP(resp[8:12]).String(), nil
+}
+
+// natpmpMap creates (or with lifetime 0 deletes) a mapping and returns the
+// mapped external port.
+func natpmpMap(gw net.IP, proto string, internal, external int, lifetime uint32) (int, error) {
+	req := make([]byte, 12)
+	req[1] = 2 // TCP
+	if proto == "udp" {
+		req[1] = 1
+	}
+	binary.BigEndian.PutUint16(req[4:6], uint16(internal))
+	binary.BigEndian.PutUint16(req[6:8], uint16(external))
+	if lifetime == 0 && external != 0 {
+		lifetime = 7200 // "longest allowed" is up to the gateway; ask for 2h
+	}
+	binary.BigEndian.PutUint32(req[8:12], lifetime)
+	resp, err := natpmpCall(gw, req, 16)
+	if err != nil {
+		return 0, err
+	}
+	return int(binary.BigEndian.Uint16(resp[10:12])), nil
+}
+
+// --- UPnP IGD ----------------------------------------------------------
+
+// upnpIGD is the WAN connection service of an Internet Gateway Device.
+type upnpIGD struct {
+	controlURL  string
+	serviceType string
+	localIP     string // our address on the gateway's network
+}
+
+var (
+	igdMu    sync.Mutex
+	igdCache *upnpIGD
+)
+
+func forgetIGD() {
+	igdMu.Lock()
+	igdCache = nil
+	igdMu.Unlock()
+}
+
+// discoverIGD finds the gateway with SSDP and reads its device description.
+func discoverIGD() (*upnpIGD, error) {
+	igdMu.Lock()
+	defer igdMu.Unlock()
+	if igdCache != nil {
+		return igdCache, nil
+	}
+
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	search := "M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: 239.255.255.250:1900\r\n" +
+		"ST: urn:schemas-upnp-org:device:InternetGatewayDevice:1\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: 2\r\n\r\n"
+	ssdp := &net.UDPAddr{IP: net.IPv4(239, 255, 255, 250), Port: 1900}
+	if _, err := conn.WriteToUDP([]byte(search), ssdp); err != nil {
+		return nil, err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(natTimeout))
+	buf := make([]byte, 2048)
+	tried := make(map[string]bool)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return nil, errors.New("no UPnP gateway answered")
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(buf[:n])), nil)
+		if err != nil {
+			continue
+		}
+		location := resp.Header.Get("Location")
+		if location == "" || tried[location] {
+			continue
+		}
+		tried[location] = true
+		if igd, err := readIGD(location); err == nil {
+			igdCache = igd
+			return igd, nil
+		}
+	}
+}
+
+// readIGD fetches the device description at location and picks its WAN
+// connection service.
+func readIGD(location string) (*upnpIGD, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), natTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	base, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	var service struct {
+		ServiceType string `xml:"serviceType"`
+		ControlURL  string `xml:"controlURL"`
+	}
+	dec := xml.NewDecoder(io.LimitReader(resp.Body, 1<<20))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, errors.New("no WAN connection service in " + location)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "URLBase":
+			var s string
+			if dec.DecodeElement(&s, &start) == nil {
+				if u, err := url.Parse(strings.TrimSpace(s)); err == nil && u.Host != "" {
+					base = u
+				}
+			}
+		case "service":
+			if dec.DecodeElement(&service, &start) != nil {
+				continue
+			}
+			if !strings.Contains(service.ServiceType, ":WANIPConnection:") &&
+				!strings.Contains(service.ServiceType, ":WANPPPConnection:") {
+				continue
+			}
+			control, err := base.Parse(strings.TrimSpace(service.ControlURL))
+			if err != nil {
+				return nil, err
+			}
+			local, err := net.Dial("udp4", base.Host)
+			if err != nil {
+				return nil, err
+			}
+			localIP := local.LocalAddr().(*net.UDPAddr).IP.String()
+			local.Close()
+			return &upnpIGD{controlURL: control.String(), serviceType: service.ServiceType, localIP: localIP}, nil
+		}
+	}
+}
+
+// soap calls action on the WAN service and returns the raw answer.
+func (igd *upnpIGD) soap(action string, args [][2]string) ([]byte, error) {
+	var body strings.Builder
+	body.WriteString(`<?xml version="1.0"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" ` +
+		`s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`)
+	fmt.Fprintf(&body, `<u:%s xmlns:u="%s">`, action, igd.serviceType)
+	for _, a := range args {
+		body.WriteString("<" + a[0] + ">")
+		xml.EscapeText(&body, []byte(a[1]))
+		body.WriteString("</" + a[0] + ">")
+	}
+	fmt.Fprintf(&body, `</u:%s></s:Body></s:Envelope>`, action)
+
+	ctx, cancel := context.WithTimeout(context.Background(), natTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, igd.controlURL, strings.NewReader(body.String()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", `"`+igd.serviceType+"#"+action+`"`)
+	resp, err := httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		if desc := xmlElementText(data, "errorDescription"); desc != "" {
+			return nil, fmt.Errorf("%s: %s", action, desc)
+		}
+		return nil, fmt.Errorf("%s: %s", action, resp.Status)
+	}
+	return data, nil
+}
+
+func (igd *upnpIGD) addPortMapping(proto string, external, internal int, lease time.Duration) error {
+	_, err := igd.soap("AddPortMapping", [][2]string{
+		{"NewRemoteHost", ""},
+		{"NewExternalPort", strconv.Itoa(external)},
+		{"NewProtocol", strings.ToUpper(proto)},
+		{"NewInternalPort", strconv.Itoa(internal)},
+		{"NewInternalClient", igd.localIP},
+		{"NewEnabled", "1"},
+		{"NewPortMappingDescription", "Globular"},
+		{"NewLeaseDuration", strconv.Itoa(int(lease / time.Second))},
+	})
+	return err
+}
+
+func (igd *upnpIGD) deletePortMapping(proto string, external int) error {
+	_, err := igd.soap("DeletePortMapping", [][2]string{
+		{"NewRemoteHost", ""},
+		{"NewExternalPort", strconv.Itoa(external)},
+		{"NewProtocol", strings.ToUpper(proto)},
+	})
+	return err
+}
+
+func (igd *upnpIGD) externalIP() (string, error) {
+	data, err := igd.soap("GetExternalIPAddress", nil)
+	if err != nil {
+		return "", err
+	}
+	ip := xmlElementText(data, "NewExternalIPAddress")
+	if net.ParseIP(ip) == nil {
+		return "", fmt.Errorf("GetExternalIPAddress: unexpected answer %q", ip)
+	}
+	return ip, nil
+}
+
+// xmlElementText returns the text of the first element named local in data.
+func xmlElementText(data []byte, local string) string {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return ""
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local == local {
+			var s string
+			dec.DecodeElement(&s, &start)
+			return strings.TrimSpace(s)
+		}
+	}
+}
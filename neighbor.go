@@ -0,0 +1,100 @@
+// utility/neighbor.go
+package Utility
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// Neighbor table
+// --------------
+// Neighbors reads the kernel's neighbor (ARP/NDP) table directly: netlink on
+// Linux, GetIpNetTable on Windows and the routing sysctl on macOS. Only when
+// that fails (restricted containers, other platforms) does it run `arp -a`
+// and parse its output, whose format differs between systems.
+
+// Neighbor is an entry of the neighbor table.
+type Neighbor struct {
+	IP        string `json:"ip"`
+	MAC       string `json:"mac"`                 // lower-case, colon separated
+	Interface string `json:"interface,omitempty"` // empty when unknown
+}
+
+// errNoNativeNeighbors is returned by nativeNeighbors on unsupported platforms.
+var errNoNativeNeighbors = errors.New("neighbor table not readable natively on this platform")
+
+// Neighbors returns the resolved entries of the neighbor table. It honors
+// WithTimeout and WithRunner (used by the `arp -a` fallback only).
+func Neighbors(ctx context.Context, opts ...Option) ([]Neighbor, error) {
+	o := NewOptions(opts...)
+	ctx, cancel := o.Context(ctx)
+	defer cancel()
+
+	neighbors, err := nativeNeighbors()
+	if err == nil {
+		return neighbors, nil
+	}
+	o.Logf("native neighbor table unavailable (%v), falling back to arp", err)
+
+	data, err := o.Runner.Run(ctx, "", "arp", "-a")
+	if IsToolMissing(err) {
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to execute command: %w", err)
+	}
+	return parseArpOutput(string(data)), nil
+}
+
+var (
+	arpIPRe  = regexp.MustCompile(`\b(?:[0-9]{1,3}\.){3}[0-9]{1,3}\b`)
+	arpMACRe = regexp.MustCompile(`\b(?:[0-9a-fA-F]{1,2}[:-]){5}[0-9a-fA-F]{1,2}\b`)
+)
+
+// parseArpOutput extracts IP/MAC pairs from `arp -a` on Linux, macOS or
+// Windows; incomplete entries keep an empty MAC.
+func parseArpOutput(out string) []Neighbor {
+	var neighbors []Neighbor
+	for _, line := range strings.Split(out, "\n") {
+		ip := arpIPRe.FindString(line)
+		if ip == "" || strings.HasPrefix(strings.TrimSpace(line), "Interface:") {
+			continue
+		}
+		n := Neighbor{IP: ip}
+		if mac := arpMACRe.FindString(line); mac != "" {
+			n.MAC = normalizeMAC(mac)
+		}
+		if i := strings.Index(line, " on "); i >= 0 {
+			if f := strings.Fields(line[i+4:]); len(f) > 0 {
+				n.Interface = f[0]
+			}
+		}
+		neighbors = append(neighbors, n)
+	}
+	return neighbors
+}
+
+// normalizeMAC formats "0:1A-2b:..." as "00:1a:2b:...".
+func normalizeMAC(mac string) string {
+	parts := strings.FieldsFunc(mac, func(r rune) bool { return r == ':' || r == '-' })
+	for i, p := range parts {
+		if len(p) == 1 {
+			parts[i] = "0" + p
+		}
+	}
+	return strings.ToLower(strings.Join(parts, ":"))
+}
+
+// formatMAC formats a hardware address, or returns "" for an empty or
+// all-zero one.
+func formatMAC(hw []byte) string {
+	for _, b := range hw {
+		if b != 0 {
+			return net.HardwareAddr(hw).String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,52 @@
+// utility/neighbor_darwin.go
+//go:build darwin
+
+package Utility
+
+import (
+	"net"
+	"syscall"
+
+	"golang.org/x/net/route"
+)
+
+// nativeNeighbors reads the ARP entries (RTF_LLINFO routes) through the
+// routing sysctl, as `arp -a` does.
+func nativeNeighbors() ([]Neighbor, error) {
+	rib, err := route.FetchRIB(syscall.AF_INET, route.RIBTypeRoute, syscall.RTF_LLINFO)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := route.ParseRIB(route.RIBTypeRoute, rib)
+	if err != nil {
+		return nil, err
+	}
+
+	var neighbors []Neighbor
+	for _, m := range msgs {
+		rm, ok := m.(*route.RouteMessage)
+		if !ok || len(rm.Addrs) <= syscall.RTAX_GATEWAY {
+			continue
+		}
+		dst, ok := rm.Addrs[syscall.RTAX_DST].(*route.Inet4Addr)
+		if !ok {
+			continue
+		}
+		link, ok := rm.Addrs[syscall.RTAX_GATEWAY].(*route.LinkAddr)
+		if !ok {
+			continue
+		}
+		mac := formatMAC(link.Addr)
+		if mac == "" {
+			continue
+		}
+		n := Neighbor{IP: net.IP(dst.IP[:]).String(), MAC: mac, Interface: link.Name}
+		if n.Interface == "" {
+			if iface, err := net.InterfaceByIndex(rm.Index); err == nil {
+				n.Interface = iface.Name
+			}
+		}
+		neighbors = append(neighbors, n)
+	}
+	return neighbors, nil
+}
@@ -0,0 +1,68 @@
+// utility/neighbor_linux.go
+//go:build linux
+
+package Utility
+
+import (
+	"encoding/binary"
+	"net"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// nativeNeighbors dumps the neighbor table over rtnetlink (RTM_GETNEIGH).
+func nativeNeighbors() ([]Neighbor, error) {
+	rib, err := syscall.NetlinkRIB(unix.RTM_GETNEIGH, unix.AF_UNSPEC)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := syscall.ParseNetlinkMessage(rib)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[int32]string)
+	if ifaces, err := net.Interfaces(); err == nil {
+		for _, iface := range ifaces {
+			names[int32(iface.Index)] = iface.Name
+		}
+	}
+
+	var neighbors []Neighbor
+	for _, m := range msgs {
+		if m.Header.Type != unix.RTM_NEWNEIGH || len(m.Data) < unix.SizeofNdMsg {
+			continue
+		}
+		ifindex := int32(binary.NativeEndian.Uint32(m.Data[4:8]))
+		state := binary.NativeEndian.Uint16(m.Data[8:10])
+		if state&(unix.NUD_INCOMPLETE|unix.NUD_FAILED|unix.NUD_NOARP) != 0 {
+			continue
+		}
+
+		var n Neighbor
+		for b := m.Data[unix.SizeofNdMsg:]; len(b) >= 4; {
+			l := int(binary.NativeEndian.Uint16(b[0:2]))
+			if l < 4 || l > len(b) {
+				break
+			}
+			switch binary.NativeEndian.Uint16(b[2:4]) {
+			case unix.NDA_DST:
+				n.IP = net.IP(b[4:l]).String()
+			case unix.NDA_LLADDR:
+				n.MAC = formatMAC(b[4:l])
+			}
+			l = (l + unix.NLA_ALIGNTO - 1) &^ (unix.NLA_ALIGNTO - 1)
+			if l > len(b) {
+				break
+			}
+			b = b[l:]
+		}
+		if n.IP == "" || n.MAC == "" {
+			continue
+		}
+		n.Interface = names[ifindex]
+		neighbors = append(neighbors, n)
+	}
+	return neighbors, nil
+}
@@ -0,0 +1,8 @@
+// utility/neighbor_other.go
+//go:build !linux && !windows && !darwin
+
+package Utility
+
+func nativeNeighbors() ([]Neighbor, error) {
+	return nil, errNoNativeNeighbors
+}
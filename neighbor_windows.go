@@ -0,0 +1,77 @@
+// utility/neighbor_windows.go
+//go:build windows
+
+package Utility
+
+import (
+	"encoding/binary"
+	"net"
+	"syscall"
+	"unsafe"
+)
+
+var procGetIpNetTable = syscall.NewLazyDLL("iphlpapi.dll").NewProc("GetIpNetTable")
+
+// nativeNeighbors reads the IPv4 ARP table with GetIpNetTable.
+func nativeNeighbors() ([]Neighbor, error) {
+	if err := procGetIpNetTable.Find(); err != nil {
+		return nil, err
+	}
+	size := uint32(0)
+	var buf []byte
+	for {
+		var p uintptr
+		if len(buf) > 0 {
+			p = uintptr(unsafe.Pointer(&buf[0]))
+		}
+		r, _, _ := procGetIpNetTable.Call(p, uintptr(unsafe.Pointer(&size)), 0)
+		if r == uintptr(syscall.ERROR_INSUFFICIENT_BUFFER) {
+			buf = make([]byte, size)
+			continue
+		}
+		if r == 232 { // ERROR_NO_DATA: empty table
+			return nil, nil
+		}
+		if r != 0 {
+			return nil, syscall.Errno(r)
+		}
+		break
+	}
+
+	names := make(map[uint32]string)
+	if ifaces, err := net.Interfaces(); err == nil {
+		for _, iface := range ifaces {
+			names[uint32(iface.Index)] = iface.Name
+		}
+	}
+
+	// MIB_IPNETTABLE: DWORD count, then MIB_IPNETROW rows of 24 bytes
+	// (index, physAddrLen, physAddr[8], addr, type).
+	const rowSize = 24
+	if len(buf) < 4 {
+		return nil, nil
+	}
+	count := int(binary.LittleEndian.Uint32(buf))
+	var neighbors []Neighbor
+	for i := 0; i < count && 4+(i+1)*rowSize <= len(buf); i++ {
+		row := buf[4+i*rowSize:]
+		typ := binary.LittleEndian.Uint32(row[20:24])
+		if typ == 2 { // MIB_IPNET_TYPE_INVALID
+			continue
+		}
+		hwLen := int(binary.LittleEndian.Uint32(row[4:8]))
+		if hwLen > 8 {
+			hwLen = 8
+		}
+		mac := formatMAC(row[8 : 8+hwLen])
+		if mac == "" {
+			continue
+		}
+		neighbors = append(neighbors, Neighbor{
+			IP:        net.IP(row[16:20]).String(),
+			MAC:       mac,
+			Interface: names[binary.LittleEndian.Uint32(row[0:4])],
+		})
+	}
+	return neighbors, nil
+}
@@ -3,17 +3,18 @@ package Utility
 
 import (
 	"bufio"
-	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"log"
 	"net"
 	"net/http"
 	"os"
-	"os/exec"
-	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	externalip "github.com/glendc/go-external-ip"
@@ -27,24 +28,54 @@ type IPInfo struct {
 	IP       string
 	Hostname string
 	City     string
+	Region   string
 	Country  string
 	Loc      string
 	Org      string
 	Postal   string
+	Timezone string // IANA name, e.g. "America/Toronto"
+	ASN      string // e.g. "AS15169"
 }
 
 // Ping sends an ICMP echo request to a domain and waits for a reply.
+//
+// Deprecated: use PingCtx, which can be cancelled.
 func Ping(domain string) error {
-	ipAddr, err := net.ResolveIPAddr("ip4", domain)
+	return PingCtx(context.Background(), domain)
+}
+
+// PingCtx is Ping bounded by ctx; without a deadline it waits up to 3 seconds
+// for the reply. It honors WithTimeout and WithRetry.
+func PingCtx(ctx context.Context, domain string, opts ...Option) error {
+	o := NewOptions(opts...)
+	ctx, cancel := o.Context(ctx)
+	defer cancel()
+	return o.Do(ctx, func(ctx context.Context) error { return ping(ctx, domain) })
+}
+
+func ping(ctx context.Context, domain string) error {
+	ips, err := LookupIP(ctx, domain)
 	if err != nil {
 		return fmt.Errorf("error resolving IP address: %v", err)
 	}
+	var ipAddr *net.IPAddr
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			ipAddr = &net.IPAddr{IP: ip}
+			break
+		}
+	}
+	if ipAddr == nil {
+		return fmt.Errorf("error resolving IP address: no IPv4 address for %s", domain)
+	}
 
 	conn, err := icmp.ListenPacket("ip4:icmp", "0.0.0.0")
 	if err != nil {
 		return fmt.Errorf("error listening for ICMP packets: %v", err)
 	}
 	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
 
 	message := icmp.Message{
 		Type: ipv4.ICMPTypeEcho,
@@ -66,15 +97,99 @@ func Ping(domain string) error {
 		return fmt.Errorf("error sending ICMP message: %v", err)
 	}
 
-	conn.SetReadDeadline(time.Now().Add(time.Second * 3))
+	deadline := time.Now().Add(time.Second * 3)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetReadDeadline(deadline)
 	responseBytes := make([]byte, 1500)
 	_, _, err = conn.ReadFrom(responseBytes)
 	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		return fmt.Errorf("error receiving ICMP response: %v", err)
 	}
 	return nil
 }
 
+// PingTCP checks that host accepts TCP connections on port within timeout.
+// Unlike Ping it needs no raw-socket privileges.
+func PingTCP(host string, port int, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// PingHTTP checks that url answers a GET with a non-error (< 400) status
+// within timeout.
+func PingHTTP(url string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return pingHTTP(ctx, url)
+}
+
+func pingHTTP(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return nil
+}
+
+// WaitForReachable polls addr every interval until it answers or timeout
+// expires. addr is either a URL (checked with PingHTTP) or host:port (checked
+// with PingTCP). The last probe error is returned on timeout.
+func WaitForReachable(addr string, timeout, interval time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return WaitForReachableCtx(ctx, addr, interval)
+}
+
+// WaitForReachableCtx is WaitForReachable bounded by ctx.
+func WaitForReachableCtx(ctx context.Context, addr string, interval time.Duration) error {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	probe := func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+	if strings.HasPrefix(addr, "http://") || strings.HasPrefix(addr, "https://") {
+		probe = func(ctx context.Context) error { return pingHTTP(ctx, addr) }
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		attempt, cancel := context.WithTimeout(ctx, interval)
+		err := probe(attempt)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s not reachable: %w", addr, err)
+		case <-ticker.C:
+		}
+	}
+}
+
 // MyMacAddr gets the MAC address of the local interface associated with ip.
 func MyMacAddr(ip string) (string, error) {
 	addrs, err := net.InterfaceAddrs()
@@ -119,7 +234,9 @@ func MyMacAddr(ip string) (string, error) {
 
 // DomainHasIp checks if a DNS lookup for domain resolves to ip.
 func DomainHasIp(domain string, ip string) bool {
-	ips, err := net.LookupIP(domain)
+	ctx, cancel := context.WithTimeout(context.Background(), defaultNetTimeout)
+	defer cancel()
+	ips, err := LookupIP(ctx, domain)
 	if err != nil {
 		return false
 	}
@@ -131,14 +248,153 @@ func DomainHasIp(domain string, ip string) bool {
 	return false
 }
 
-// MyIP returns the external IP as seen from outside.
+// defaultNetTimeout bounds the network helpers called without a context.
+const defaultNetTimeout = 5 * time.Second
+
+// MyIP returns the external IP as seen from outside, or "" when it cannot be
+// determined within a few seconds.
 func MyIP() string {
-	consensus := externalip.DefaultConsensus(&externalip.ConsensusConfig{Timeout: 500 * time.Millisecond}, nil)
-	ip, err := consensus.ExternalIP()
-	if err == nil {
-		return ip.String()
+	ctx, cancel := context.WithTimeout(context.Background(), defaultNetTimeout)
+	defer cancel()
+	ip, _ := MyIPCtx(ctx)
+	return ip
+}
+
+// MyIPCtx returns the external IP agreed on by several public services. Each
+// service gets at most the time left before ctx's deadline (2 seconds without
+// one). It honors WithTimeout and WithRetry.
+func MyIPCtx(ctx context.Context, opts ...Option) (string, error) {
+	o := NewOptions(opts...)
+	ctx, cancel := o.Context(ctx)
+	defer cancel()
+
+	var ip string
+	err := o.Do(ctx, func(ctx context.Context) error {
+		timeout := 2 * time.Second
+		if d, ok := ctx.Deadline(); ok {
+			timeout = time.Until(d)
+		}
+		consensus := externalip.DefaultConsensus(&externalip.ConsensusConfig{Timeout: timeout}, nil)
+		done := make(chan error, 1)
+		go func() {
+			addr, err := consensus.ExternalIP()
+			if err == nil {
+				ip = addr.String()
+			}
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	return ip, nil
+}
+
+// publicIPProviders are queried in order until one answers; each URL
+// returns the caller's address as plain text.
+var publicIPProviders = []struct{ name, v4, v6 string }{
+	{"ipify", "https://api.ipify.org", "https://api6.ipify.org"},
+	{"icanhazip", "https://ipv4.icanhazip.com", "https://ipv6.icanhazip.com"},
+	{"ipinfo", "https://ipinfo.io/ip", "https://v6.ipinfo.io/ip"},
+}
+
+var (
+	publicIPMu      sync.Mutex
+	publicIPTTL     = 5 * time.Minute
+	publicIPv4      string
+	publicIPv6      string
+	publicIPExpires time.Time
+)
+
+// SetPublicIPCacheTTL sets how long MyPublicIP reuses its result (default
+// 5 minutes; 0 disables the cache).
+func SetPublicIPCacheTTL(d time.Duration) {
+	publicIPMu.Lock()
+	publicIPTTL = d
+	publicIPExpires = time.Time{}
+	publicIPMu.Unlock()
+}
+
+// MyPublicIP returns the public IPv4 and IPv6 addresses of this host, as seen
+// by ipify, icanhazip or ipinfo (the next provider is tried when one fails).
+// An address is "" when the host has none of that family; err is set only
+// when neither could be determined.
+func MyPublicIP(ctx context.Context) (ipv4, ipv6 string, err error) {
+	publicIPMu.Lock()
+	if time.Now().Before(publicIPExpires) {
+		ipv4, ipv6 = publicIPv4, publicIPv6
+		publicIPMu.Unlock()
+		return ipv4, ipv6, nil
+	}
+	publicIPMu.Unlock()
+
+	var err4, err6 error
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); ipv4, err4 = queryPublicIP(ctx, false) }()
+	go func() { defer wg.Done(); ipv6, err6 = queryPublicIP(ctx, true) }()
+	wg.Wait()
+	if ipv4 == "" && ipv6 == "" {
+		return "", "", fmt.Errorf("public IP not found: %w", errors.Join(err4, err6))
+	}
+
+	publicIPMu.Lock()
+	publicIPv4, publicIPv6 = ipv4, ipv6
+	publicIPExpires = time.Now().Add(publicIPTTL)
+	publicIPMu.Unlock()
+	return ipv4, ipv6, nil
+}
+
+// queryPublicIP asks the providers in order for the address of one family.
+func queryPublicIP(ctx context.Context, v6 bool) (string, error) {
+	var errs []error
+	for _, p := range publicIPProviders {
+		url := p.v4
+		if v6 {
+			url = p.v6
+		}
+		ip, err := fetchPublicIP(ctx, url, v6)
+		if err == nil {
+			return ip, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", p.name, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return "", errors.Join(errs...)
+}
+
+func fetchPublicIP(ctx context.Context, url string, v6 bool) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
 	}
-	return ""
+	resp, err := httpClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New(resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil {
+		return "", err
+	}
+	ip := net.ParseIP(strings.TrimSpace(string(body)))
+	if ip == nil || (ip.To4() == nil) != v6 {
+		return "", fmt.Errorf("unexpected answer %q", strings.TrimSpace(string(body)))
+	}
+	return ip.String(), nil
 }
 
 // MyIPv6 returns the first non-loopback IPv6 address.
@@ -218,8 +474,20 @@ func privateIPCheck(ip string) bool {
 
 // GetIpv4 resolves a hostname into an IPv4 string.
 func GetIpv4(address string) (string, error) {
-	if strings.Contains(address, ":") {
-		address = address[:strings.Index(address, ":")]
+	ctx, cancel := context.WithTimeout(context.Background(), defaultNetTimeout)
+	defer cancel()
+	return GetIpv4Ctx(ctx, address)
+}
+
+// GetIpv4Ctx is GetIpv4 bounded by ctx. The hosts file is consulted first,
+// then DNS. It honors WithTimeout and WithRetry.
+func GetIpv4Ctx(ctx context.Context, address string, opts ...Option) (string, error) {
+	address = hostOnly(address)
+	if ip := net.ParseIP(address); ip != nil {
+		if ipv4 := ip.To4(); ipv4 != nil {
+			return ipv4.String(), nil
+		}
+		return "", errors.New(address + " is not an IPv4 address")
 	}
 	hosts, err := txeh.NewHostsDefault()
 	if err != nil {
@@ -229,7 +497,18 @@ func GetIpv4(address string) (string, error) {
 	if exist {
 		return ip, nil
 	}
-	ips, _ := net.LookupIP(address)
+
+	o := NewOptions(opts...)
+	ctx, cancel := o.Context(ctx)
+	defer cancel()
+	var ips []net.IP
+	err = o.Do(ctx, func(ctx context.Context) (err error) {
+		ips, err = LookupIP(ctx, address)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
 	for _, ip := range ips {
 		if ipv4 := ip.To4(); ipv4 != nil {
 			return ipv4.String(), nil
@@ -240,8 +519,9 @@ func GetIpv4(address string) (string, error) {
 
 // IsLocal returns true if a hostname resolves to a private/local IP.
 func IsLocal(hostname string) bool {
-	if strings.Contains(hostname, ":") {
-		hostname = hostname[:strings.Index(hostname, ":")]
+	hostname = hostOnly(hostname)
+	if ip := net.ParseIP(hostname); ip != nil {
+		return privateIPCheck(hostname)
 	}
 	hosts, err := txeh.NewHostsDefault()
 	if err != nil {
@@ -256,67 +536,72 @@ func IsLocal(hostname string) bool {
 
 // ForeignIP queries ipinfo.io for details about an IP.
 func ForeignIP(ip string) (*IPInfo, error) {
-	if ip != "" {
-		ip += "/" + ip
-	}
-	resp, err := http.Get("http://ipinfo.io" + ip + "/json")
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), defaultNetTimeout)
+	defer cancel()
+	return ForeignIPCtx(ctx, ip)
+}
 
-	data, err := ioutil.ReadAll(resp.Body)
+// ForeignIPCtx is ForeignIP bounded by ctx; an empty ip describes this
+// machine's public address. It asks the configured GeoIPProvider (ipinfo.io
+// by default) and honors WithTimeout and WithRetry.
+func ForeignIPCtx(ctx context.Context, ip string, opts ...Option) (*IPInfo, error) {
+	o := NewOptions(opts...)
+	ctx, cancel := o.Context(ctx)
+	defer cancel()
+
+	provider := CurrentSettings().GeoIP
+	var info *IPInfo
+	err := o.Do(ctx, func(ctx context.Context) (err error) {
+		info, err = provider.Lookup(ctx, ip)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	var ipinfo IPInfo
-	if err := json.Unmarshal(data, &ipinfo); err != nil {
-		return nil, err
-	}
-	return &ipinfo, nil
+	return info, nil
 }
 
-// ScanIPs runs `arp -a` and extracts IPv4 addresses.
+// ScanIPs returns the IPv4 addresses of the neighbor table.
+//
+// Deprecated: use ScanIPsCtx, which can be cancelled.
 func ScanIPs() ([]string, error) {
-	cmd := exec.Command("arp", "-a")
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	err := cmd.Run()
+	return ScanIPsCtx(context.Background())
+}
+
+// ScanIPsCtx is ScanIPs bounded by ctx. The table is read natively, falling
+// back to `arp -a` (see Neighbors). It honors WithTimeout and WithRunner.
+func ScanIPsCtx(ctx context.Context, opts ...Option) ([]string, error) {
+	neighbors, err := Neighbors(ctx, opts...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute command: %w", err)
+		return nil, err
 	}
-
-	re := regexp.MustCompile(`\b(?:[0-9]{1,3}\.){3}[0-9]{1,3}\b`)
 	var ips []string
-	scanner := bufio.NewScanner(&out)
-	for scanner.Scan() {
-		line := scanner.Text()
-		ip := re.FindString(line)
-		if ip != "" {
-			ips = append(ips, ip)
+	for _, n := range neighbors {
+		if ip := net.ParseIP(n.IP); ip != nil && ip.To4() != nil {
+			ips = append(ips, n.IP)
 		}
 	}
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading output: %w", err)
-	}
 	return ips, nil
 }
 
-// GetHostnameIPMap scans the local network and returns hostname→IP mappings.
+// GetHostnameIPMap scans the network of the local interface holding localIp
+// and returns hostname→IP mappings. At most the /24 (IPv4) or /120 (IPv6)
+// around localIp is scanned, whatever the size of the interface network,
+// and the /24 when localIp isn't a local address.
 func GetHostnameIPMap(localIp string) map[string]string {
 	localNetworks := make([]string, 0)
 	if localIp != "" {
-		if strings.HasPrefix(localIp, "192.168.0.") {
-			localNetworks = append(localNetworks, "192.168.0.0/24")
-		} else if strings.HasPrefix(localIp, "10.") {
-			localNetworks = append(localNetworks, "10.0.0.0/24")
-		} else if strings.HasPrefix(localIp, "172.") {
-			localNetworks = append(localNetworks, "172.16.0.0/24")
+		if cidr := scanNetwork(localIp); cidr != "" {
+			localNetworks = append(localNetworks, cidr)
 		}
 	}
 	hostnameIPMap := make(map[string]string)
 	for _, netrange := range localNetworks {
-		if m, err := getHostnameIPMap(netrange); err == nil {
+		m, err := getHostnameIPMap(netrange)
+		if IsToolMissing(err) {
+			log.Println("GetHostnameIPMap:", err)
+			break
+		} else if err == nil {
 			for k, v := range m {
 				hostnameIPMap[k] = v
 			}
@@ -325,13 +610,41 @@ func GetHostnameIPMap(localIp string) map[string]string {
 	return hostnameIPMap
 }
 
+// scanNetwork returns the network of the local interface holding ip,
+// narrowed to the /24 (/120 for IPv6) around ip so a ping scan stays short,
+// or "" when ip is invalid.
+func scanNetwork(ip string) string {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return ""
+	}
+	bits, ones := 32, 24
+	if v4 := addr.To4(); v4 != nil {
+		addr = v4
+	} else {
+		bits, ones = 128, 120
+	}
+	if cidr, err := cidrForIP(ip); err == nil {
+		if _, n, err := net.ParseCIDR(cidr); err == nil {
+			if size, _ := n.Mask.Size(); size > ones {
+				ones = size
+			}
+		}
+	}
+	mask := net.CIDRMask(ones, bits)
+	return (&net.IPNet{IP: addr.Mask(mask), Mask: mask}).String()
+}
+
 func getHostnameIPMap(localnetwork string) (map[string]string, error) {
-	cmd := exec.Command("nmap", "-sn", localnetwork)
+	if err := RequireTool("nmap", "awk"); err != nil {
+		return nil, err
+	}
+	cmd := toolCommand(context.Background(), "nmap", "-sn", localnetwork)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return nil, fmt.Errorf("error running nmap: %v", err)
 	}
-	awkCmd := exec.Command("awk", "/for/ && $6 != \"\" {gsub(/[()]/, \"\"); print $5, $6}")
+	awkCmd := toolCommand(context.Background(), "awk", "/for/ && $6 != \"\" {gsub(/[()]/, \"\"); print $5, $6}")
 	awkCmd.Stdin = strings.NewReader(string(output))
 
 	awkOutput, err := awkCmd.CombinedOutput()
@@ -350,3 +663,79 @@ func getHostnameIPMap(localnetwork string) (map[string]string, error) {
 	return hostnameIPMap, nil
 }
 
+
+// ScanPorts returns the sorted TCP ports of host in [from, to] that accept a
+// connection within timeout.
+func ScanPorts(host string, from, to int, timeout time.Duration) ([]int, error) {
+	return ScanPortsCtx(context.Background(), host, from, to, timeout)
+}
+
+// ScanPortsCtx is ScanPorts bounded by ctx. Ports are probed concurrently.
+func ScanPortsCtx(ctx context.Context, host string, from, to int, timeout time.Duration) ([]int, error) {
+	if from < 1 || to > 65535 || from > to {
+		return nil, &GuardError{Field: "range", Reason: fmt.Sprintf("invalid port range %d-%d", from, to)}
+	}
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+
+	ports := make(chan int)
+	var mu sync.Mutex
+	var open []int
+	var wg sync.WaitGroup
+	workers := min(to-from+1, 128)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d := net.Dialer{Timeout: timeout}
+			for port := range ports {
+				conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+				if err != nil {
+					continue
+				}
+				conn.Close()
+				mu.Lock()
+				open = append(open, port)
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for port := from; port <= to; port++ {
+		select {
+		case ports <- port:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(ports)
+	wg.Wait()
+
+	sort.Ints(open)
+	return open, ctx.Err()
+}
+
+// GetFreePort returns a TCP port free for listening. With a preferred range
+// (from, to) or a single preferred port, those are tried first; when none is
+// free, any port chosen by the OS is returned.
+func GetFreePort(preferredRange ...int) (int, error) {
+	if len(preferredRange) > 0 {
+		from, to := preferredRange[0], preferredRange[0]
+		if len(preferredRange) > 1 {
+			to = preferredRange[1]
+		}
+		for port := max(from, 1); port <= to && port <= 65535; port++ {
+			if l, err := net.Listen("tcp", ":"+strconv.Itoa(port)); err == nil {
+				l.Close()
+				return port, nil
+			}
+		}
+	}
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,99 @@
+// utility/netif.go
+package Utility
+
+import (
+	"net"
+	"strings"
+)
+
+// Network interface inventory
+// ---------------------------
+// GetNetworkInterfaces walks the interfaces once and reports everything the
+// installer and the dashboard need about each of them. The default gateway
+// comes from the routing table (Linux and macOS); wireless and virtual
+// interfaces are detected through sysfs on Linux and by name elsewhere.
+
+// InterfaceInfo describes a network interface.
+type InterfaceInfo struct {
+	Name     string   `json:"name"`
+	Index    int      `json:"index"`
+	MAC      string   `json:"mac,omitempty"`
+	MTU      int      `json:"mtu"`
+	Flags    []string `json:"flags"`          // e.g. "up", "broadcast", "loopback", "multicast"
+	IPv4     []string `json:"ipv4,omitempty"` // CIDR notation
+	IPv6     []string `json:"ipv6,omitempty"`
+	Gateway  string   `json:"gateway,omitempty"` // default gateway through this interface
+	Up       bool     `json:"up"`
+	Loopback bool     `json:"loopback"`
+	Wireless bool     `json:"wireless"`
+	Virtual  bool     `json:"virtual"`
+}
+
+// GetNetworkInterfaces returns the inventory of the network interfaces.
+func GetNetworkInterfaces() ([]InterfaceInfo, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	gateways := defaultGateways()
+
+	infos := make([]InterfaceInfo, 0, len(ifaces))
+	for _, iface := range ifaces {
+		info := InterfaceInfo{
+			Name:     iface.Name,
+			Index:    iface.Index,
+			MAC:      iface.HardwareAddr.String(),
+			MTU:      iface.MTU,
+			Flags:    strings.Split(iface.Flags.String(), "|"),
+			Gateway:  gateways[iface.Name],
+			Up:       iface.Flags&net.FlagUp != 0,
+			Loopback: iface.Flags&net.FlagLoopback != 0,
+		}
+		if iface.Flags == 0 {
+			info.Flags = nil
+		}
+		info.Wireless, info.Virtual = interfaceKind(iface.Name)
+		info.Virtual = info.Virtual || info.Loopback
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			if ipnet.IP.To4() != nil {
+				info.IPv4 = append(info.IPv4, ipnet.String())
+			} else {
+				info.IPv6 = append(info.IPv6, ipnet.String())
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+var (
+	wirelessPrefixes = []string{"wl", "wifi", "wi-fi", "ath"}
+	virtualPrefixes  = []string{"lo", "docker", "veth", "br-", "virbr", "vmnet", "vboxnet", "utun", "tun", "tap",
+		"bridge", "awdl", "llw", "zt", "tailscale", "wg", "ifb", "dummy", "vethernet", "cni", "flannel", "kube"}
+)
+
+// interfaceKindByName guesses from the name whether an interface is wireless
+// or virtual.
+func interfaceKindByName(name string) (wireless, virtual bool) {
+	lower := strings.ToLower(name)
+	for _, p := range wirelessPrefixes {
+		if strings.HasPrefix(lower, p) {
+			wireless = true
+		}
+	}
+	for _, p := range virtualPrefixes {
+		if strings.HasPrefix(lower, p) {
+			virtual = true
+		}
+	}
+	return wireless, virtual
+}
@@ -0,0 +1,51 @@
+// utility/netif_darwin.go
+//go:build darwin
+
+package Utility
+
+import (
+	"net"
+	"syscall"
+
+	"golang.org/x/net/route"
+)
+
+// defaultGateways reads the default routes of the routing table, interface
+// name -> gateway.
+func defaultGateways() map[string]string {
+	gateways := make(map[string]string)
+	rib, err := route.FetchRIB(syscall.AF_INET, route.RIBTypeRoute, 0)
+	if err != nil {
+		return gateways
+	}
+	msgs, err := route.ParseRIB(route.RIBTypeRoute, rib)
+	if err != nil {
+		return gateways
+	}
+	for _, m := range msgs {
+		rm, ok := m.(*route.RouteMessage)
+		if !ok || rm.Flags&syscall.RTF_GATEWAY == 0 || len(rm.Addrs) <= syscall.RTAX_NETMASK {
+			continue
+		}
+		dst, ok := rm.Addrs[syscall.RTAX_DST].(*route.Inet4Addr)
+		if !ok || dst.IP != [4]byte{} {
+			continue
+		}
+		gw, ok := rm.Addrs[syscall.RTAX_GATEWAY].(*route.Inet4Addr)
+		if !ok {
+			continue
+		}
+		iface, err := net.InterfaceByIndex(rm.Index)
+		if err != nil {
+			continue
+		}
+		if _, seen := gateways[iface.Name]; !seen {
+			gateways[iface.Name] = net.IP(gw.IP[:]).String()
+		}
+	}
+	return gateways
+}
+
+func interfaceKind(name string) (wireless, virtual bool) {
+	return interfaceKindByName(name)
+}
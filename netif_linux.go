@@ -0,0 +1,61 @@
+// utility/netif_linux.go
+//go:build linux
+
+package Utility
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultGateways reads the default routes of /proc/net/route, interface
+// name -> gateway.
+func defaultGateways() map[string]string {
+	gateways := make(map[string]string)
+	f, err := os.Open("/proc/net/route")
+	if err != nil {
+		return gateways
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Scan() // header
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 8 || fields[1] != "00000000" || fields[7] != "00000000" {
+			continue
+		}
+		raw, err := hex.DecodeString(fields[2])
+		if err != nil || len(raw) != 4 {
+			continue
+		}
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, binary.LittleEndian.Uint32(raw))
+		if _, seen := gateways[fields[0]]; !seen {
+			gateways[fields[0]] = ip.String()
+		}
+	}
+	return gateways
+}
+
+// interfaceKind uses sysfs: wireless interfaces have a "wireless" (or
+// "phy80211") entry and virtual ones live under /sys/devices/virtual.
+func interfaceKind(name string) (wireless, virtual bool) {
+	dir := filepath.Join("/sys/class/net", name)
+	target, err := os.Readlink(dir)
+	if err != nil {
+		return interfaceKindByName(name)
+	}
+	virtual = strings.Contains(target, "/virtual/")
+	for _, entry := range []string{"wireless", "phy80211"} {
+		if _, err := os.Stat(filepath.Join(dir, entry)); err == nil {
+			wireless = true
+		}
+	}
+	return wireless, virtual
+}
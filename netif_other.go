@@ -0,0 +1,13 @@
+// utility/netif_other.go
+//go:build !linux && !darwin
+
+package Utility
+
+// defaultGateways is not implemented on this platform.
+func defaultGateways() map[string]string {
+	return map[string]string{}
+}
+
+func interfaceKind(name string) (wireless, virtual bool) {
+	return interfaceKindByName(name)
+}
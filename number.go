@@ -4,6 +4,8 @@ package Utility
 import (
 	"encoding/binary"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"math"
 	"reflect"
@@ -13,7 +15,21 @@ import (
 )
 
 // ToString converts many primitive/interface types into a string.
+// Unsupported types are logged and yield "".
 func ToString(value interface{}) string {
+	str, err := ToStringE(value)
+	if err != nil {
+		log.Println(err)
+	}
+	return str
+}
+
+// ToStringE converts many primitive/interface types into a string, returning
+// a GuardError for unsupported types.
+func ToStringE(value interface{}) (string, error) {
+	if value == nil {
+		return "", nil
+	}
 	var str string
 	switch reflect.TypeOf(value).Kind() {
 	case reflect.String:
@@ -58,51 +74,266 @@ func ToString(value interface{}) string {
 		} else if t == "map[string]interface {}" {
 			data, err := json.Marshal(value)
 			if err == nil {
-				return string(data)
+				return string(data), nil
 			}
-			return "{}"
+			return "{}", nil
 		} else {
-			log.Panicln("Value with type:", reflect.TypeOf(value).String(), "cannot be converted to string")
+			return "", &GuardError{Reason: "value with type " + t + " cannot be converted to string"}
 		}
 	}
-	return strings.TrimSpace(str)
+	return strings.TrimSpace(str), nil
 }
 
 // ToInt converts many primitive/interface types into int.
+// Unparsable strings yield 0; unsupported types are logged and yield 0.
 func ToInt(value interface{}) int {
+	val, err := ToIntE(value)
+	if _, isString := value.(string); err != nil && !isString {
+		log.Println(err)
+	}
+	return val
+}
+
+// ToIntE converts many primitive/interface types into int, returning a
+// GuardError for unparsable strings and unsupported types.
+func ToIntE(value interface{}) (int, error) {
 	if value == nil {
-		return 0
+		return 0, nil
 	}
 	switch reflect.TypeOf(value).Kind() {
 	case reflect.String:
-		val, _ := strconv.Atoi(value.(string))
-		return val
+		val, err := strconv.Atoi(strings.TrimSpace(value.(string)))
+		if err != nil {
+			return 0, &GuardError{Reason: "invalid integer " + strconv.Quote(value.(string))}
+		}
+		return val, nil
 	case reflect.Int:
-		return value.(int)
+		return value.(int), nil
 	case reflect.Int8:
-		return int(value.(int8))
+		return int(value.(int8)), nil
 	case reflect.Int16:
-		return int(value.(int16))
+		return int(value.(int16)), nil
 	case reflect.Int32:
-		return int(value.(int32))
+		return int(value.(int32)), nil
 	case reflect.Int64:
-		return int(value.(int64))
+		return int(value.(int64)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := reflect.ValueOf(value).Uint()
+		if u > math.MaxInt {
+			return 0, overflowError(value, "int")
+		}
+		return int(u), nil
 	case reflect.Float32:
-		return int(value.(float32))
+		return int(value.(float32)), nil
 	case reflect.Float64:
-		return int(value.(float64))
+		f := value.(float64)
+		if math.IsNaN(f) || f < math.MinInt || f >= -math.MinInt {
+			return 0, overflowError(value, "int")
+		}
+		return int(f), nil
 	case reflect.Bool:
 		if value.(bool) {
-			return 1
+			return 1, nil
 		}
-		return 0
+		return 0, nil
 	default:
 		if reflect.TypeOf(value).String() == "[]uint8" {
-			return int(binary.BigEndian.Uint64(value.([]uint8)))
+			b := value.([]uint8)
+			if len(b) != 8 {
+				return 0, &GuardError{Reason: "[]uint8 of length " + strconv.Itoa(len(b)) + " cannot be converted to int (want 8 big-endian bytes)"}
+			}
+			return int(binary.BigEndian.Uint64(b)), nil
+		}
+	}
+	return 0, &GuardError{Reason: "value with type " + reflect.TypeOf(value).String() + " cannot be converted to int"}
+}
+
+// The exact conversions below fail instead of wrapping around, truncating or
+// rounding: 1e19 is not an int32, -1 is not a uint and 2.5 is not an int
+// (unlike ToIntE, which truncates fractions). Strings may be integers or
+// floats ("1e3"); bools are 0 or 1.
+
+// ToInt8E converts value to an int8 exactly.
+func ToInt8E(value interface{}) (int8, error) {
+	n, err := toSignedE(value, 8)
+	return int8(n), err
+}
+
+// ToInt16E converts value to an int16 exactly.
+func ToInt16E(value interface{}) (int16, error) {
+	n, err := toSignedE(value, 16)
+	return int16(n), err
+}
+
+// ToInt32E converts value to an int32 exactly.
+func ToInt32E(value interface{}) (int32, error) {
+	n, err := toSignedE(value, 32)
+	return int32(n), err
+}
+
+// ToInt64E converts value to an int64 exactly.
+func ToInt64E(value interface{}) (int64, error) {
+	return toSignedE(value, 64)
+}
+
+// ToUintE converts value to a uint exactly.
+func ToUintE(value interface{}) (uint, error) {
+	n, err := toUnsignedE(value, strconv.IntSize)
+	return uint(n), err
+}
+
+// ToUint8E converts value to a uint8 exactly.
+func ToUint8E(value interface{}) (uint8, error) {
+	n, err := toUnsignedE(value, 8)
+	return uint8(n), err
+}
+
+// ToUint16E converts value to a uint16 exactly.
+func ToUint16E(value interface{}) (uint16, error) {
+	n, err := toUnsignedE(value, 16)
+	return uint16(n), err
+}
+
+// ToUint32E converts value to a uint32 exactly.
+func ToUint32E(value interface{}) (uint32, error) {
+	n, err := toUnsignedE(value, 32)
+	return uint32(n), err
+}
+
+// ToUint64E converts value to a uint64 exactly.
+func ToUint64E(value interface{}) (uint64, error) {
+	return toUnsignedE(value, 64)
+}
+
+// ToFloat32E converts value to a float32, failing when it is out of the
+// float32 range (rounding to the nearest float32 is not an error).
+func ToFloat32E(value interface{}) (float32, error) {
+	f, err := ToNumericE(value)
+	if err != nil {
+		return 0, err
+	}
+	if !math.IsInf(f, 0) && math.Abs(f) > math.MaxFloat32 {
+		return 0, overflowError(value, "float32")
+	}
+	return float32(f), nil
+}
+
+func overflowError(value interface{}, typ string) error {
+	return &GuardError{Reason: fmt.Sprintf("%v overflows %s", value, typ)}
+}
+
+func negativeError(value interface{}, typ string) error {
+	return &GuardError{Reason: fmt.Sprintf("%v is negative, %s is unsigned", value, typ)}
+}
+
+// toSignedE converts value to an integer of the given bit size exactly.
+func toSignedE(value interface{}, bits int) (int64, error) {
+	typ := "int" + strconv.Itoa(bits)
+	min, max := -int64(1)<<(bits-1), int64(1)<<(bits-1)-1
+	if value == nil {
+		return 0, nil
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n := rv.Int(); n >= min && n <= max {
+			return n, nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u := rv.Uint(); u <= uint64(max) {
+			return int64(u), nil
+		}
+	case reflect.Float32, reflect.Float64:
+		return floatToSigned(value, rv.Float(), bits)
+	case reflect.Bool:
+		if rv.Bool() {
+			return 1, nil
+		}
+		return 0, nil
+	case reflect.String:
+		s := strings.TrimSpace(rv.String())
+		n, err := strconv.ParseInt(s, 10, bits)
+		if err == nil {
+			return n, nil
+		}
+		if errors.Is(err, strconv.ErrSyntax) {
+			if f, ferr := strconv.ParseFloat(s, 64); ferr == nil {
+				return floatToSigned(value, f, bits)
+			}
+			return 0, &GuardError{Reason: "invalid integer " + strconv.Quote(s)}
+		}
+	default:
+		return 0, &GuardError{Reason: "value with type " + rv.Type().String() + " cannot be converted to " + typ}
+	}
+	return 0, overflowError(value, typ)
+}
+
+func floatToSigned(value interface{}, f float64, bits int) (int64, error) {
+	limit := math.Ldexp(1, bits-1)
+	switch {
+	case math.IsNaN(f) || f < -limit || f >= limit:
+		return 0, overflowError(value, "int"+strconv.Itoa(bits))
+	case f != math.Trunc(f):
+		return 0, &GuardError{Reason: fmt.Sprintf("%v is not an integer", value)}
+	}
+	return int64(f), nil
+}
+
+// toUnsignedE converts value to an unsigned integer of the given bit size
+// exactly.
+func toUnsignedE(value interface{}, bits int) (uint64, error) {
+	typ := "uint" + strconv.Itoa(bits)
+	max := uint64(1)<<bits - 1
+	if bits == 64 {
+		max = math.MaxUint64
+	}
+	if value == nil {
+		return 0, nil
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n := rv.Int(); n < 0 {
+			return 0, negativeError(value, typ)
+		} else if uint64(n) <= max {
+			return uint64(n), nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u := rv.Uint(); u <= max {
+			return u, nil
+		}
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		switch {
+		case f < 0:
+			return 0, negativeError(value, typ)
+		case math.IsNaN(f) || f >= math.Ldexp(1, bits):
+		case f != math.Trunc(f):
+			return 0, &GuardError{Reason: fmt.Sprintf("%v is not an integer", value)}
+		default:
+			return uint64(f), nil
+		}
+	case reflect.Bool:
+		if rv.Bool() {
+			return 1, nil
+		}
+		return 0, nil
+	case reflect.String:
+		s := strings.TrimSpace(rv.String())
+		n, err := strconv.ParseUint(s, 10, bits)
+		if err == nil {
+			return n, nil
+		}
+		if errors.Is(err, strconv.ErrSyntax) {
+			if f, ferr := strconv.ParseFloat(s, 64); ferr == nil {
+				return toUnsignedE(f, bits)
+			}
+			return 0, &GuardError{Reason: "invalid integer " + strconv.Quote(s)}
 		}
+	default:
+		return 0, &GuardError{Reason: "value with type " + rv.Type().String() + " cannot be converted to " + typ}
 	}
-	log.Panicln("Value with type:", reflect.TypeOf(value).String(), "cannot be converted to int")
-	return 0
+	return 0, overflowError(value, typ)
 }
 
 // IsBool checks if the value is or can be parsed as bool.
@@ -146,37 +377,53 @@ func IsNumeric(value interface{}) bool {
 }
 
 // ToNumeric converts value into float64 (bool -> 0/1, time -> unix timestamp).
+// Unparsable strings yield 0; unsupported types are logged and yield 0.
 func ToNumeric(value interface{}) float64 {
+	val, err := ToNumericE(value)
+	if _, isString := value.(string); err != nil && !isString {
+		log.Println(err)
+	}
+	return val
+}
+
+// ToNumericE converts value into float64, returning a GuardError for
+// unparsable strings and unsupported types.
+func ToNumericE(value interface{}) (float64, error) {
+	if value == nil {
+		return 0, nil
+	}
 	switch reflect.TypeOf(value).Kind() {
 	case reflect.String:
-		val, _ := strconv.ParseFloat(value.(string), 64)
-		return val
+		val, err := strconv.ParseFloat(strings.TrimSpace(value.(string)), 64)
+		if err != nil {
+			return 0, &GuardError{Reason: "invalid number " + strconv.Quote(value.(string))}
+		}
+		return val, nil
 	case reflect.Int:
-		return float64(value.(int))
+		return float64(value.(int)), nil
 	case reflect.Int8:
-		return float64(value.(int8))
+		return float64(value.(int8)), nil
 	case reflect.Int16:
-		return float64(value.(int16))
+		return float64(value.(int16)), nil
 	case reflect.Int32:
-		return float64(value.(int32))
+		return float64(value.(int32)), nil
 	case reflect.Int64:
-		return float64(value.(int64))
+		return float64(value.(int64)), nil
 	case reflect.Float32:
-		return float64(value.(float32))
+		return float64(value.(float32)), nil
 	case reflect.Float64:
-		return value.(float64)
+		return value.(float64), nil
 	case reflect.Bool:
 		if value.(bool) {
-			return 1.0
+			return 1.0, nil
 		}
-		return 0.0
+		return 0.0, nil
 	default:
 		if reflect.TypeOf(value).String() == "time.Time" {
-			return float64(value.(time.Time).Unix())
+			return float64(value.(time.Time).Unix()), nil
 		}
 	}
-	log.Panicln("Value with type:", reflect.TypeOf(value).String(), "cannot be converted to float64")
-	return 0
+	return 0, &GuardError{Reason: "value with type " + reflect.TypeOf(value).String() + " cannot be converted to float64"}
 }
 
 // Round rounds float64 to n decimals using bankers rounding.
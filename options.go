@@ -0,0 +1,167 @@
+// utility/options.go
+package Utility
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Functional options
+// ------------------
+// Helpers that do I/O accept a trailing ...Option so cross-cutting concerns
+// (timeouts, logging, retries, the filesystem, the process runner, the clock)
+// can be injected per call, e.g. fakes in tests, instead of through globals.
+// Every option is optional; the defaults reproduce the historical behavior.
+
+// RetryPolicy describes how failed attempts are retried.
+type RetryPolicy struct {
+	Attempts   int              // total attempts; <= 1 disables retries
+	Backoff    time.Duration    // delay before the second attempt, doubled afterwards
+	MaxBackoff time.Duration    // cap on the delay (0 = no cap)
+	RetryIf    func(error) bool // nil retries every error
+}
+
+// FS is the filesystem subset used by helpers that accept WithFS.
+type FS interface {
+	Open(name string) (io.ReadCloser, error)
+	Create(name string) (io.WriteCloser, error)
+	Stat(name string) (os.FileInfo, error)
+	MkdirAll(path string, perm os.FileMode) error
+	Remove(name string) error
+	Rename(oldpath, newpath string) error
+}
+
+// OSFS is the FS backed by the os package.
+type OSFS struct{}
+
+func (OSFS) Open(name string) (io.ReadCloser, error)      { return os.Open(name) }
+func (OSFS) Create(name string) (io.WriteCloser, error)   { return os.Create(name) }
+func (OSFS) Stat(name string) (os.FileInfo, error)        { return os.Stat(name) }
+func (OSFS) MkdirAll(path string, perm os.FileMode) error { return os.MkdirAll(path, perm) }
+func (OSFS) Remove(name string) error                     { return os.Remove(name) }
+func (OSFS) Rename(oldpath, newpath string) error         { return os.Rename(oldpath, newpath) }
+
+// Runner runs external commands for helpers that accept WithRunner.
+type Runner interface {
+	// Run executes name with args in dir and returns its stdout.
+	Run(ctx context.Context, dir, name string, args ...string) ([]byte, error)
+}
+
+// ExecRunner is the Runner backed by os/exec. It fails with *ErrToolMissing
+// when the command isn't installed.
+type ExecRunner struct{}
+
+// Run executes the command; stderr is included in the error on failure.
+func (ExecRunner) Run(ctx context.Context, dir, name string, args ...string) ([]byte, error) {
+	if err := RequireTool(name); err != nil {
+		return nil, err
+	}
+	cmd := toolCommand(ctx, name, args...)
+	cmd.Dir = dir
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return out.Bytes(), fmt.Errorf("%s: %w: %s", buildCmdLine(name, args), err, strings.TrimSpace(stderr.String()))
+	}
+	return out.Bytes(), nil
+}
+
+// Clock abstracts time for helpers that wait (retries, polling).
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Options is the resolved configuration of a call.
+type Options struct {
+	Timeout time.Duration // 0 = no timeout beyond the caller's context
+	Logger  *log.Logger   // nil prints to stdout; defaults to Settings.Logger
+	Retry   RetryPolicy
+	FS      FS
+	Runner  Runner
+	Clock   Clock
+}
+
+// Option configures Options.
+type Option func(*Options)
+
+// WithTimeout bounds the whole call, retries included.
+func WithTimeout(d time.Duration) Option { return func(o *Options) { o.Timeout = d } }
+
+// WithLogger sends diagnostics to l.
+func WithLogger(l *log.Logger) Option { return func(o *Options) { o.Logger = l } }
+
+// WithRetry retries failed attempts according to p.
+func WithRetry(p RetryPolicy) Option { return func(o *Options) { o.Retry = p } }
+
+// WithFS replaces the filesystem.
+func WithFS(fs FS) Option { return func(o *Options) { o.FS = fs } }
+
+// WithRunner replaces the command runner.
+func WithRunner(r Runner) Option { return func(o *Options) { o.Runner = r } }
+
+// WithClock replaces the clock (e.g. a fake clock in tests).
+func WithClock(c Clock) Option { return func(o *Options) { o.Clock = c } }
+
+// NewOptions applies opts over the defaults.
+func NewOptions(opts ...Option) *Options {
+	o := &Options{Logger: defaultLogger(), FS: OSFS{}, Runner: ExecRunner{}, Clock: realClock{}}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+	return o
+}
+
+// Context derives the context of the call from parent, applying Timeout.
+func (o *Options) Context(parent context.Context) (context.Context, context.CancelFunc) {
+	if o.Timeout > 0 {
+		return context.WithTimeout(parent, o.Timeout)
+	}
+	return context.WithCancel(parent)
+}
+
+// Logf writes a diagnostic line to Logger, or to stdout when none is set.
+func (o *Options) Logf(format string, args ...interface{}) {
+	if o.Logger != nil {
+		o.Logger.Printf(format, args...)
+		return
+	}
+	fmt.Printf(format+"\n", args...)
+}
+
+// Do runs fn, retrying according to Retry until it succeeds, the error is
+// not retryable, the attempts are exhausted or ctx is done.
+func (o *Options) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	delay := o.Retry.Backoff
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= o.Retry.Attempts || ctx.Err() != nil ||
+			(o.Retry.RetryIf != nil && !o.Retry.RetryIf(err)) {
+			return err
+		}
+		o.Logf("attempt %d/%d failed: %v", attempt, o.Retry.Attempts, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-o.Clock.After(delay):
+		}
+		delay *= 2
+		if o.Retry.MaxBackoff > 0 && delay > o.Retry.MaxBackoff {
+			delay = o.Retry.MaxBackoff
+		}
+	}
+}
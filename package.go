@@ -0,0 +1,446 @@
+// utility/package.go
+package Utility
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Application packages
+// --------------------
+// A package archive is a tar.gz whose first entry is package.json (the
+// Package manifest) followed by the payload under files/. Installing
+// verifies dependencies and checksums, runs the pre-install hooks, applies
+// all file changes through a FileTransaction and records the manifest under
+// <root>/.packages/<name>.json, which is what UninstallPackage and
+// VerifyInstall work from.
+
+const (
+	packageManifestName = "package.json"
+	packageDBDir        = ".packages"
+)
+
+// PackageFile is one file shipped by a package.
+type PackageFile struct {
+	Path   string      `json:"path"` // slash-separated, relative to the install root
+	SHA256 string      `json:"sha256"`
+	Mode   os.FileMode `json:"mode"`
+}
+
+// PackageDependency requires another installed package, e.g.
+// {Name: "event", Constraint: ">=1.2.0, <2.0.0"}.
+type PackageDependency struct {
+	Name       string `json:"name"`
+	Constraint string `json:"constraint,omitempty"`
+}
+
+// PackageHook is a command run around install/uninstall, from the install
+// root, with PACKAGE_NAME, PACKAGE_VERSION and PACKAGE_ROOT set.
+type PackageHook struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+}
+
+// Package is a package manifest.
+type Package struct {
+	Name          string              `json:"name"`
+	Version       string              `json:"version"`
+	Description   string              `json:"description,omitempty"`
+	Files         []PackageFile       `json:"files"`
+	Dependencies  []PackageDependency `json:"dependencies,omitempty"`
+	PreInstall    []PackageHook       `json:"preInstall,omitempty"`
+	PostInstall   []PackageHook       `json:"postInstall,omitempty"`
+	PreUninstall  []PackageHook       `json:"preUninstall,omitempty"`
+	PostUninstall []PackageHook       `json:"postUninstall,omitempty"`
+}
+
+var (
+	// ErrPackageNotInstalled is returned for operations on unknown packages.
+	ErrPackageNotInstalled = errors.New("package is not installed")
+	// ErrPackageDependency is returned when dependencies are not satisfied.
+	ErrPackageDependency = errors.New("package dependency not satisfied")
+)
+
+// BuildPackage writes a package archive for pkg to w, shipping every regular
+// file of srcDir. pkg.Files is filled with the computed checksums.
+func BuildPackage(pkg *Package, srcDir string, w io.Writer) (err error) {
+	defer endOperation(startOperation("package.build", map[string]interface{}{"name": pkg.Name, "src": srcDir}), &err)
+
+	if err := checkPackageName(pkg.Name); err != nil {
+		return err
+	}
+	pkg.Files = nil
+	err = filepath.WalkDir(srcDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, p)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		sum, err := fileSHA256(p)
+		if err != nil {
+			return err
+		}
+		pkg.Files = append(pkg.Files, PackageFile{Path: filepath.ToSlash(rel), SHA256: sum, Mode: info.Mode().Perm()})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(pkg.Files, func(i, j int) bool { return pkg.Files[i].Path < pkg.Files[j].Path })
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	manifest, err := json.MarshalIndent(pkg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: packageManifestName, Mode: 0644, Size: int64(len(manifest))}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		return err
+	}
+	for _, f := range pkg.Files {
+		if err := addFileToTar(tw, filepath.Join(srcDir, filepath.FromSlash(f.Path)), "files/"+f.Path); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// InstallPackage installs (or upgrades) the package read from archive into
+// root. Files of a previous version that the new one no longer ships are removed.
+func InstallPackage(archive io.Reader, root string) (pkg *Package, err error) {
+	op := startOperation("package.install", map[string]interface{}{"root": root})
+	defer endOperation(op, &err)
+
+	gz, err := gzip.NewReader(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("read package manifest: %w", err)
+	}
+	if hdr.Name != packageManifestName {
+		return nil, errors.New("package archive does not start with " + packageManifestName)
+	}
+	pkg = new(Package)
+	if err := json.NewDecoder(tr).Decode(pkg); err != nil {
+		return nil, fmt.Errorf("read package manifest: %w", err)
+	}
+	if op != nil {
+		op.Attrs["name"], op.Attrs["version"] = pkg.Name, pkg.Version
+	}
+	if err := checkPackage(pkg); err != nil {
+		return nil, fmt.Errorf("package manifest: %w", err)
+	}
+	if err := checkPackageDependencies(pkg, root); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	staging, err := os.MkdirTemp(root, ".pkg-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging)
+
+	expected := make(map[string]PackageFile, len(pkg.Files))
+	for _, f := range pkg.Files {
+		expected[f.Path] = f
+	}
+	tx := NewFileTransaction()
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rel := strings.TrimPrefix(path.Clean(hdr.Name), "files/")
+		f, listed := expected[rel]
+		if !listed || hdr.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("unexpected package entry %q", hdr.Name)
+		}
+		delete(expected, rel)
+		staged := filepath.Join(staging, filepath.FromSlash(rel))
+		if err := stageVerifiedFile(tr, staged, f); err != nil {
+			return nil, err
+		}
+		tx.Move(staged, filepath.Join(root, filepath.FromSlash(rel)))
+	}
+	if len(expected) > 0 {
+		return nil, fmt.Errorf("package archive is missing %d file(s)", len(expected))
+	}
+
+	// upgrade: drop files the previous version shipped but this one does not
+	if prev, err := InstalledPackage(root, pkg.Name); err == nil {
+		shipped := make(map[string]bool, len(pkg.Files))
+		for _, f := range pkg.Files {
+			shipped[f.Path] = true
+		}
+		for _, f := range prev.Files {
+			if !shipped[f.Path] {
+				tx.Delete(filepath.Join(root, filepath.FromSlash(f.Path)))
+			}
+		}
+	}
+
+	manifest, err := json.MarshalIndent(pkg, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	tx.Write(packageRecordPath(root, pkg.Name), manifest, 0644)
+
+	if err := runPackageHooks(pkg, root, pkg.PreInstall); err != nil {
+		return nil, fmt.Errorf("pre-install: %w", err)
+	}
+	if err := tx.Execute(); err != nil {
+		return nil, err
+	}
+	if err := runPackageHooks(pkg, root, pkg.PostInstall); err != nil {
+		return pkg, fmt.Errorf("post-install: %w", err)
+	}
+	return pkg, nil
+}
+
+// stageVerifiedFile writes r to dst and checks it against f.SHA256.
+func stageVerifiedFile(r io.Reader, dst string, f PackageFile) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	mode := f.Mode.Perm()
+	if mode == 0 {
+		mode = 0644
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(out, h), r)
+	out.Close()
+	if err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != f.SHA256 {
+		return fmt.Errorf("checksum mismatch for %s", f.Path)
+	}
+	return nil
+}
+
+// UninstallPackage removes an installed package. It refuses when another
+// installed package depends on it.
+func UninstallPackage(name, root string) (err error) {
+	defer endOperation(startOperation("package.uninstall", map[string]interface{}{"name": name, "root": root}), &err)
+
+	pkg, err := InstalledPackage(root, name)
+	if err != nil {
+		return err
+	}
+	installed, err := InstalledPackages(root)
+	if err != nil {
+		return err
+	}
+	for _, other := range installed {
+		for _, dep := range other.Dependencies {
+			if dep.Name == name && other.Name != name {
+				return fmt.Errorf("%w: %s depends on %s", ErrPackageDependency, other.Name, name)
+			}
+		}
+	}
+
+	if err := runPackageHooks(pkg, root, pkg.PreUninstall); err != nil {
+		return fmt.Errorf("pre-uninstall: %w", err)
+	}
+	tx := NewFileTransaction()
+	for _, f := range pkg.Files {
+		tx.Delete(filepath.Join(root, filepath.FromSlash(f.Path)))
+	}
+	tx.Delete(packageRecordPath(root, name))
+	if err := tx.Execute(); err != nil {
+		return err
+	}
+	if err := runPackageHooks(pkg, root, pkg.PostUninstall); err != nil {
+		return fmt.Errorf("post-uninstall: %w", err)
+	}
+	return nil
+}
+
+// VerifyInstall checks the files of an installed package against their
+// checksums and returns the paths that are missing or modified.
+func VerifyInstall(name, root string) ([]string, error) {
+	pkg, err := InstalledPackage(root, name)
+	if err != nil {
+		return nil, err
+	}
+	var bad []string
+	for _, f := range pkg.Files {
+		sum, err := fileSHA256(filepath.Join(root, filepath.FromSlash(f.Path)))
+		if err != nil || sum != f.SHA256 {
+			bad = append(bad, f.Path)
+		}
+	}
+	return bad, nil
+}
+
+// InstalledPackage returns the manifest of an installed package.
+func InstalledPackage(root, name string) (*Package, error) {
+	if err := checkPackageName(name); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(packageRecordPath(root, name))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrPackageNotInstalled, name)
+	} else if err != nil {
+		return nil, err
+	}
+	pkg := new(Package)
+	if err := json.Unmarshal(data, pkg); err != nil {
+		return nil, err
+	}
+	if err := checkPackage(pkg); err != nil {
+		return nil, fmt.Errorf("package record %s: %w", name, err)
+	}
+	return pkg, nil
+}
+
+// InstalledPackages returns the manifests of every package installed in root.
+func InstalledPackages(root string) ([]*Package, error) {
+	entries, err := os.ReadDir(filepath.Join(root, packageDBDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var pkgs []*Package
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		pkg, err := InstalledPackage(root, strings.TrimSuffix(e.Name(), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs, nil
+}
+
+// checkPackage rejects manifests whose name or file paths would reach
+// outside the install root.
+func checkPackage(pkg *Package) error {
+	if err := checkPackageName(pkg.Name); err != nil {
+		return err
+	}
+	for _, f := range pkg.Files {
+		if err := checkRelPath(f.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkPackageName accepts names usable as a file name in the package
+// database.
+func checkPackageName(name string) error {
+	if err := RequireNonEmpty(name, "name"); err != nil {
+		return err
+	}
+	if name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return &GuardError{Field: "name", Reason: fmt.Sprintf("%q is not a valid package name", name)}
+	}
+	return nil
+}
+
+func packageRecordPath(root, name string) string {
+	return filepath.Join(root, packageDBDir, name+".json")
+}
+
+func checkPackageDependencies(pkg *Package, root string) error {
+	for _, dep := range pkg.Dependencies {
+		installed, err := InstalledPackage(root, dep.Name)
+		if err != nil {
+			return fmt.Errorf("%w: %s requires %s %s", ErrPackageDependency, pkg.Name, dep.Name, dep.Constraint)
+		}
+		ok, err := NewVersion(installed.Version).SatisfiesConstraint(dep.Constraint)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%w: %s requires %s %s, %s is installed", ErrPackageDependency, pkg.Name, dep.Name, dep.Constraint, installed.Version)
+		}
+	}
+	return nil
+}
+
+func runPackageHooks(pkg *Package, root string, hooks []PackageHook) error {
+	for _, h := range hooks {
+		cmd := exec.Command(h.Command, h.Args...)
+		cmd.Dir = root
+		cmd.Env = append(os.Environ(), "PACKAGE_NAME="+pkg.Name, "PACKAGE_VERSION="+pkg.Version, "PACKAGE_ROOT="+root)
+		var out bytes.Buffer
+		cmd.Stdout, cmd.Stderr = &out, &out
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s: %w: %s", buildCmdLine(h.Command, h.Args), err, strings.TrimSpace(out.String()))
+		}
+	}
+	return nil
+}
+
+// PackageInstallOrder sorts pkgs so that every package comes after the
+// packages it depends on. Dependencies outside pkgs are ignored.
+func PackageInstallOrder(pkgs []*Package) ([]*Package, error) {
+	byName := make(map[string]*Package, len(pkgs))
+	g := NewDAG[string]()
+	for _, p := range pkgs {
+		byName[p.Name] = p
+		g.AddNode(p.Name)
+	}
+	for _, p := range pkgs {
+		for _, dep := range p.Dependencies {
+			if _, ok := byName[dep.Name]; ok {
+				g.AddEdge(dep.Name, p.Name)
+			}
+		}
+	}
+	names, err := g.TopologicalSort()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*Package, len(names))
+	for i, n := range names {
+		out[i] = byName[n]
+	}
+	return out, nil
+}
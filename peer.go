@@ -0,0 +1,174 @@
+// utility/peer.go
+package Utility
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Peer address and TLS information helpers
+// ----------------------------------------
+// Forwarding headers (X-Forwarded-For, X-Real-IP) are only honored when the
+// direct peer is a trusted proxy, otherwise any client could spoof them. The
+// gRPC equivalents live in the grpcutil package.
+
+var (
+	trustedProxiesMu sync.RWMutex
+	trustedProxies   []*net.IPNet
+)
+
+// SetTrustedProxies replaces the list of proxies (IPs or CIDRs) whose
+// forwarding headers are trusted. Passing nothing disables header parsing.
+func SetTrustedProxies(proxies ...string) error {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, p := range proxies {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if !strings.Contains(p, "/") {
+			if ip := net.ParseIP(p); ip != nil && ip.To4() != nil {
+				p += "/32"
+			} else {
+				p += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			return err
+		}
+		nets = append(nets, n)
+	}
+	trustedProxiesMu.Lock()
+	trustedProxies = nets
+	trustedProxiesMu.Unlock()
+	return nil
+}
+
+// IsTrustedProxy reports whether ip belongs to a configured trusted proxy range.
+func IsTrustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	trustedProxiesMu.RLock()
+	defer trustedProxiesMu.RUnlock()
+	for _, n := range trustedProxies {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostOnly strips an optional port (and IPv6 brackets) from addr.
+func hostOnly(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return strings.Trim(addr, "[]")
+}
+
+// ClientIPFromHeaders returns the client address given the direct peer
+// (host or host:port) and the X-Forwarded-For and X-Real-IP values.
+func ClientIPFromHeaders(remote string, forwardedFor []string, realIP string) string {
+	remote = hostOnly(remote)
+	if !IsTrustedProxy(remote) {
+		return remote
+	}
+
+	hops := make([]string, 0)
+	for _, h := range forwardedFor {
+		for _, part := range strings.Split(h, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				hops = append(hops, hostOnly(part))
+			}
+		}
+	}
+	// Walk right to left: the rightmost untrusted hop is the real client.
+	for i := len(hops) - 1; i >= 0; i-- {
+		if net.ParseIP(hops[i]) == nil {
+			break
+		}
+		if !IsTrustedProxy(hops[i]) {
+			return hops[i]
+		}
+	}
+	if realIP = hostOnly(strings.TrimSpace(realIP)); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+	if len(hops) > 0 && net.ParseIP(hops[0]) != nil {
+		return hops[0]
+	}
+	return remote
+}
+
+// GetPeerIP returns the client IP of an HTTP request, honoring forwarding
+// headers set by trusted proxies.
+func GetPeerIP(r *http.Request) string {
+	return ClientIPFromHeaders(r.RemoteAddr, r.Header.Values("X-Forwarded-For"), r.Header.Get("X-Real-IP"))
+}
+
+// TLSClientCertInfo summarizes the verified client certificate of a connection.
+type TLSClientCertInfo struct {
+	Subject      string
+	CommonName   string
+	Issuer       string
+	SerialNumber string
+	DNSNames     []string
+	EmailAddress []string
+	IPAddresses  []string
+	NotBefore    time.Time
+	NotAfter     time.Time
+	Verified     bool
+	TLSVersion   uint16
+	CipherSuite  string
+}
+
+// ErrNoClientCert is returned when the connection carries no client certificate.
+var ErrNoClientCert = errors.New("no TLS client certificate presented")
+
+// TLSClientCertInfoOf returns the client certificate details of a TLS
+// connection state.
+func TLSClientCertInfoOf(state *tls.ConnectionState) (*TLSClientCertInfo, error) {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil, ErrNoClientCert
+	}
+	cert := state.PeerCertificates[0]
+	info := &TLSClientCertInfo{
+		Subject:      cert.Subject.String(),
+		CommonName:   cert.Subject.CommonName,
+		Issuer:       cert.Issuer.String(),
+		SerialNumber: cert.SerialNumber.String(),
+		DNSNames:     cert.DNSNames,
+		EmailAddress: cert.EmailAddresses,
+		NotBefore:    cert.NotBefore,
+		NotAfter:     cert.NotAfter,
+		Verified:     len(state.VerifiedChains) > 0,
+		TLSVersion:   state.Version,
+		CipherSuite:  tls.CipherSuiteName(state.CipherSuite),
+	}
+	for _, ip := range cert.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ip.String())
+	}
+	return info, nil
+}
+
+// GetTLSClientCertInfo returns the client certificate details of an HTTP request.
+func GetTLSClientCertInfo(r *http.Request) (*TLSClientCertInfo, error) {
+	return TLSClientCertInfoOf(r.TLS)
+}
+
+// ClientCertificate returns the leaf client certificate of an HTTP request, if any.
+func ClientCertificate(r *http.Request) (*x509.Certificate, bool) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil, false
+	}
+	return r.TLS.PeerCertificates[0], true
+}
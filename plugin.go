@@ -0,0 +1,97 @@
+// utility/plugin.go
+package Utility
+
+import (
+	"fmt"
+	"path/filepath"
+	"plugin"
+	"sort"
+)
+
+// Plugins
+// -------
+// LoadPlugin opens a Go plugin (go build -buildmode=plugin) and calls its
+// exported Register function so it can add types, functions, constructors or
+// migrations to the TypeManager, e.g.:
+//
+//	package main
+//
+//	import Utility "github.com/globulario/utility"
+//
+//	func Register(tm *Utility.TypeManager) error {
+//		tm.Namespace("billing").RegisterTypeOf((*Invoice)(nil))
+//		return nil
+//	}
+//
+// The plugin must be built with the same Go toolchain and the same version of
+// this package as the host. Go plugins are supported on Linux, macOS and
+// FreeBSD with cgo; elsewhere LoadPlugin returns plugin.Open's error.
+
+// PluginRegisterSymbol is the function a plugin exports. Accepted signatures
+// are func(*TypeManager) error, func(*TypeManager) and func() error.
+const PluginRegisterSymbol = "Register"
+
+// LoadPlugin loads the plugin at path into the default TypeManager.
+func LoadPlugin(path string) error {
+	return DefaultTypeManager().LoadPlugin(path)
+}
+
+// LoadPlugin loads the plugin at path into tm. Loading the same file again is
+// a no-op.
+func (tm *TypeManager) LoadPlugin(path string) (err error) {
+	defer endOperation(startOperation("plugin.load", map[string]interface{}{"path": path}), &err)
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	tm.mu.Lock()
+	loaded := tm.plugins[abs]
+	tm.mu.Unlock()
+	if loaded {
+		return nil
+	}
+
+	p, err := plugin.Open(abs)
+	if err != nil {
+		return err
+	}
+	sym, err := p.Lookup(PluginRegisterSymbol)
+	if err != nil {
+		return fmt.Errorf("plugin %s: %w", path, err)
+	}
+
+	switch register := sym.(type) {
+	case func(*TypeManager) error:
+		err = register(tm)
+	case func(*TypeManager):
+		register(tm)
+	case func() error:
+		err = register()
+	default:
+		return fmt.Errorf("plugin %s: %s has unsupported type %T", path, PluginRegisterSymbol, sym)
+	}
+	if err != nil {
+		return fmt.Errorf("plugin %s: %w", path, err)
+	}
+
+	tm.mu.Lock()
+	if tm.plugins == nil {
+		tm.plugins = make(map[string]bool)
+	}
+	tm.plugins[abs] = true
+	tm.mu.Unlock()
+	return nil
+}
+
+// LoadedPlugins returns the paths of the plugins loaded into tm.
+func (tm *TypeManager) LoadedPlugins() []string {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	paths := make([]string, 0, len(tm.plugins))
+	for path := range tm.plugins {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -4,12 +4,12 @@ package Utility
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
-	"os/exec"
 	"runtime"
 	"strings"
 	"syscall"
@@ -150,12 +150,23 @@ func ReadOutput(output chan string, rc io.ReadCloser) {
 // It sends the final error (nil on success) on wait and returns.
 // Stdout is streamed; stderr is captured and included in the error on failure.
 func RunCmd(name, dir string, args []string, wait chan error) {
-	cmd := exec.Command(name, args...)
+	RunCmdCtx(context.Background(), name, dir, args, wait)
+}
+
+// RunCmdCtx is RunCmd bounded by ctx: the process is killed when ctx is done.
+func RunCmdCtx(ctx context.Context, name, dir string, args []string, wait chan error) {
+	op := startOperation("exec", map[string]interface{}{"name": name, "dir": dir, "args": args})
+	finish := func(err error) {
+		endOperation(op, &err)
+		wait <- err
+	}
+
+	cmd := toolCommand(ctx, name, args...)
 	cmd.Dir = dir
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		wait <- err
+		finish(err)
 		return
 	}
 
@@ -166,7 +177,7 @@ func RunCmd(name, dir string, args []string, wait chan error) {
 
 	// Start the command before launching readers; if Start fails, we won't block on pipes.
 	if err := cmd.Start(); err != nil {
-		wait <- fmt.Errorf("%s </br> %w: %s", buildCmdLine(name, args), err, stderr.String())
+		finish(fmt.Errorf("%s </br> %w: %s", buildCmdLine(name, args), err, stderr.String()))
 		return
 	}
 
@@ -196,11 +207,11 @@ func RunCmd(name, dir string, args []string, wait chan error) {
 	<-donePrint
 
 	if err != nil {
-		wait <- fmt.Errorf("%s </br> %v: %s", buildCmdLine(name, args), err, strings.TrimSpace(stderr.String()))
+		finish(fmt.Errorf("%s </br> %v: %s", buildCmdLine(name, args), err, strings.TrimSpace(stderr.String())))
 		return
 	}
 
-	wait <- nil
+	finish(nil)
 }
 
 // buildCmdLine formats `name` and `args` into a shell-like string.
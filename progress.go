@@ -0,0 +1,240 @@
+// utility/progress.go
+package Utility
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Progress reporting
+// ------------------
+// Progress tracks a task measured in units (bytes, files, frames...) that can
+// be split into weighted sub-tasks; a parent's fraction is the weighted mean
+// of its children. Listeners are throttled, and snapshots marshal to JSON so
+// every long-running helper reports progress the same way to UIs. Progress
+// implements io.Writer (counting bytes) for use with io.TeeReader/MultiWriter.
+
+// ProgressSnapshot is a point-in-time view of a Progress tree.
+type ProgressSnapshot struct {
+	Name      string             `json:"name"`
+	Done      int64              `json:"done"`
+	Total     int64              `json:"total"` // 0 when unknown
+	Fraction  float64            `json:"fraction"`
+	Elapsed   time.Duration      `json:"elapsed"`
+	ETA       time.Duration      `json:"eta"` // -1 when unknown
+	Completed bool               `json:"completed"`
+	Children  []ProgressSnapshot `json:"children,omitempty"`
+}
+
+type progressListener struct {
+	fn       func(ProgressSnapshot)
+	interval time.Duration
+	last     time.Time
+}
+
+// Progress is a concurrency-safe, possibly nested, progress tracker.
+type Progress struct {
+	mu *sync.Mutex // shared by the whole tree
+
+	name      string
+	total     int64
+	done      int64
+	weight    float64
+	completed bool
+	started   time.Time
+	finished  time.Time
+
+	parent    *Progress
+	children  []*Progress
+	listeners map[int]*progressListener
+	nextID    int
+}
+
+// NewProgress creates a root task of total units (0 if unknown).
+func NewProgress(name string, total int64) *Progress {
+	return &Progress{mu: new(sync.Mutex), name: name, total: total, weight: 1, started: time.Now()}
+}
+
+// SubTask adds a child task; weight is its share of the parent (default 1).
+func (p *Progress) SubTask(name string, total int64, weight float64) *Progress {
+	if weight <= 0 {
+		weight = 1
+	}
+	child := &Progress{mu: p.mu, name: name, total: total, weight: weight, started: time.Now(), parent: p}
+	p.mu.Lock()
+	p.children = append(p.children, child)
+	p.mu.Unlock()
+	return child
+}
+
+// Add advances the task by n units.
+func (p *Progress) Add(n int64) {
+	p.update(func() { p.done += n })
+}
+
+// Set sets the number of completed units.
+func (p *Progress) Set(done int64) {
+	p.update(func() { p.done = done })
+}
+
+// SetTotal changes the total number of units (e.g. once Content-Length is known).
+func (p *Progress) SetTotal(total int64) {
+	p.update(func() { p.total = total })
+}
+
+// Done marks the task, and any unfinished sub-task, as completed.
+func (p *Progress) Done() {
+	p.update(func() { p.complete() })
+}
+
+// Write counts len(b) units, so a Progress can sit behind an io.Writer.
+func (p *Progress) Write(b []byte) (int, error) {
+	p.Add(int64(len(b)))
+	return len(b), nil
+}
+
+func (p *Progress) complete() {
+	if p.completed {
+		return
+	}
+	for _, c := range p.children {
+		c.complete()
+	}
+	if p.total > 0 {
+		p.done = p.total
+	}
+	p.completed = true
+	p.finished = time.Now()
+}
+
+// update applies fn under the tree lock, then notifies the listeners of p and
+// of its ancestors.
+func (p *Progress) update(fn func()) {
+	type call struct {
+		fn   func(ProgressSnapshot)
+		snap ProgressSnapshot
+	}
+	var calls []call
+
+	p.mu.Lock()
+	fn()
+	now := time.Now()
+	for n := p; n != nil; n = n.parent {
+		if len(n.listeners) == 0 {
+			continue
+		}
+		snap := n.snapshot(now)
+		for _, l := range n.listeners {
+			if snap.Completed || now.Sub(l.last) >= l.interval {
+				l.last = now
+				calls = append(calls, call{l.fn, snap})
+			}
+		}
+	}
+	p.mu.Unlock()
+
+	for _, c := range calls {
+		c.fn(c.snap)
+	}
+}
+
+// OnChange registers fn to be called at most once per interval (and always on
+// completion) when the task or one of its sub-tasks changes. It returns a
+// function removing the listener.
+func (p *Progress) OnChange(fn func(ProgressSnapshot), interval time.Duration) (remove func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.listeners == nil {
+		p.listeners = make(map[int]*progressListener)
+	}
+	id := p.nextID
+	p.nextID++
+	p.listeners[id] = &progressListener{fn: fn, interval: interval}
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		delete(p.listeners, id)
+	}
+}
+
+// Fraction returns the completed fraction in [0,1].
+func (p *Progress) Fraction() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.fraction()
+}
+
+func (p *Progress) fraction() float64 {
+	if p.completed {
+		return 1
+	}
+	if len(p.children) > 0 {
+		var sum, weights float64
+		for _, c := range p.children {
+			sum += c.fraction() * c.weight
+			weights += c.weight
+		}
+		return sum / weights
+	}
+	if p.total <= 0 {
+		return 0
+	}
+	f := float64(p.done) / float64(p.total)
+	if f > 1 {
+		f = 1
+	}
+	return f
+}
+
+// ETA estimates the remaining time from the average rate so far, or returns
+// -1 when no estimate is possible yet.
+func (p *Progress) ETA() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.eta(time.Now())
+}
+
+func (p *Progress) eta(now time.Time) time.Duration {
+	if p.completed {
+		return 0
+	}
+	f := p.fraction()
+	if f <= 0 {
+		return -1
+	}
+	elapsed := now.Sub(p.started)
+	return time.Duration(float64(elapsed) * (1 - f) / f)
+}
+
+// Snapshot returns the current state of the task and its sub-tasks.
+func (p *Progress) Snapshot() ProgressSnapshot {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.snapshot(time.Now())
+}
+
+func (p *Progress) snapshot(now time.Time) ProgressSnapshot {
+	end := now
+	if p.completed {
+		end = p.finished
+	}
+	s := ProgressSnapshot{
+		Name:      p.name,
+		Done:      p.done,
+		Total:     p.total,
+		Fraction:  p.fraction(),
+		Elapsed:   end.Sub(p.started),
+		ETA:       p.eta(now),
+		Completed: p.completed,
+	}
+	for _, c := range p.children {
+		s.Children = append(s.Children, c.snapshot(now))
+	}
+	return s
+}
+
+// MarshalJSON encodes the current snapshot.
+func (p *Progress) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.Snapshot())
+}
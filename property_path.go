@@ -0,0 +1,303 @@
+// utility/property_path.go
+package Utility
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Path-based property access
+// --------------------------
+// Paths use dots for fields/map keys and brackets for indexes or quoted keys:
+//   "Owner.Address.City", "Items[3].Name", `Labels["app.kubernetes.io/name"]`
+
+type pathSegment struct {
+	name    string // field name or map key
+	index   int    // slice/array index when isIndex
+	isIndex bool
+}
+
+func (s pathSegment) String() string {
+	if s.isIndex {
+		return "[" + strconv.Itoa(s.index) + "]"
+	}
+	return s.name
+}
+
+// parsePropertyPath splits a path into segments.
+func parsePropertyPath(path string) ([]pathSegment, error) {
+	segs := make([]pathSegment, 0)
+	i := 0
+	for i < len(path) {
+		switch path[i] {
+		case '.':
+			i++
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated '[' in path %q", path)
+			}
+			inner := path[i+1 : i+end]
+			i += end + 1
+			if len(inner) >= 2 && (inner[0] == '"' || inner[0] == '\'') && inner[len(inner)-1] == inner[0] {
+				segs = append(segs, pathSegment{name: inner[1 : len(inner)-1]})
+			} else if n, err := strconv.Atoi(inner); err == nil {
+				segs = append(segs, pathSegment{index: n, isIndex: true})
+			} else if inner != "" {
+				segs = append(segs, pathSegment{name: inner})
+			} else {
+				return nil, fmt.Errorf("empty '[]' in path %q", path)
+			}
+		default:
+			end := strings.IndexAny(path[i:], ".[")
+			if end < 0 {
+				end = len(path) - i
+			}
+			segs = append(segs, pathSegment{name: path[i : i+end]})
+			i += end
+		}
+	}
+	if len(segs) == 0 {
+		return nil, errors.New("empty property path")
+	}
+	return segs, nil
+}
+
+// GetPropertyByPath resolves a nested path (fields, map keys, slice indexes)
+// starting at ptr. Returns (nil, false) if any step is missing.
+func GetPropertyByPath(ptr interface{}, path string) (interface{}, bool) {
+	segs, err := parsePropertyPath(path)
+	if err != nil {
+		return nil, false
+	}
+	v := reflect.ValueOf(ptr)
+	for _, seg := range segs {
+		v = indirectValue(v)
+		if !v.IsValid() {
+			return nil, false
+		}
+		v = stepValue(v, seg)
+		if !v.IsValid() {
+			return nil, false
+		}
+	}
+	if !v.CanInterface() {
+		return nil, false
+	}
+	return v.Interface(), true
+}
+
+// SetPropertyByPath assigns val at path, allocating nil pointers, maps and
+// interface placeholders along the way and growing slices when the index is
+// just past the end.
+func SetPropertyByPath(ptr interface{}, path string, val interface{}) error {
+	segs, err := parsePropertyPath(path)
+	if err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(ptr)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("SetPropertyByPath: target must be a non-nil pointer")
+	}
+	return setPathValue(rv.Elem(), segs, val, "")
+}
+
+// indirectValue dereferences pointers and interfaces.
+func indirectValue(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// stepValue moves one segment down from v (read-only).
+func stepValue(v reflect.Value, seg pathSegment) reflect.Value {
+	switch v.Kind() {
+	case reflect.Struct:
+		if seg.isIndex {
+			return reflect.Value{}
+		}
+		f := structField(v, seg.name)
+		if f.IsValid() && !f.CanInterface() {
+			return reflect.Value{}
+		}
+		return f
+	case reflect.Map:
+		key, err := mapKeyValue(v.Type().Key(), seg)
+		if err != nil {
+			return reflect.Value{}
+		}
+		return v.MapIndex(key)
+	case reflect.Slice, reflect.Array:
+		if !seg.isIndex || seg.index < 0 || seg.index >= v.Len() {
+			return reflect.Value{}
+		}
+		return v.Index(seg.index)
+	}
+	return reflect.Value{}
+}
+
+// mapKeyValue converts a segment into a key of type kt.
+func mapKeyValue(kt reflect.Type, seg pathSegment) (reflect.Value, error) {
+	raw := seg.name
+	if seg.isIndex {
+		raw = strconv.Itoa(seg.index)
+	}
+	switch kt.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(raw).Convert(kt), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(n).Convert(kt), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(n).Convert(kt), nil
+	}
+	return reflect.Value{}, fmt.Errorf("unsupported map key type %v", kt)
+}
+
+// setPathValue assigns val at segs below the settable value v.
+func setPathValue(v reflect.Value, segs []pathSegment, val interface{}, at string) error {
+	if len(segs) == 0 {
+		return assignValue(v, val, at)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setPathValue(v.Elem(), segs, val, at)
+
+	case reflect.Interface:
+		// Work on a settable copy of the dynamic value, then store it back.
+		var cur reflect.Value
+		if v.IsNil() {
+			cur = reflect.ValueOf(make(map[string]interface{}))
+		} else {
+			cur = v.Elem()
+		}
+		tmp := reflect.New(cur.Type()).Elem()
+		tmp.Set(cur)
+		if err := setPathValue(tmp, segs, val, at); err != nil {
+			return err
+		}
+		v.Set(tmp)
+		return nil
+	}
+
+	seg := segs[0]
+	next := at + pathJoin(at, seg)
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if seg.isIndex {
+			return fmt.Errorf("%s: cannot index struct", next)
+		}
+		f := settableField(v, seg.name)
+		if !f.IsValid() {
+			return fmt.Errorf("%s: no such field", next)
+		}
+		if !f.CanSet() {
+			return fmt.Errorf("%s: field is not settable", next)
+		}
+		return setPathValue(f, segs[1:], val, next)
+
+	case reflect.Map:
+		key, err := mapKeyValue(v.Type().Key(), seg)
+		if err != nil {
+			return fmt.Errorf("%s: %w", next, err)
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if existing := v.MapIndex(key); existing.IsValid() {
+			elem.Set(existing)
+		}
+		if err := setPathValue(elem, segs[1:], val, next); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+		return nil
+
+	case reflect.Slice:
+		if !seg.isIndex || seg.index < 0 {
+			return fmt.Errorf("%s: invalid slice index", next)
+		}
+		if seg.index >= v.Len() {
+			if seg.index > v.Len() {
+				return fmt.Errorf("%s: index out of range (len %d)", next, v.Len())
+			}
+			v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+		}
+		return setPathValue(v.Index(seg.index), segs[1:], val, next)
+
+	case reflect.Array:
+		if !seg.isIndex || seg.index < 0 || seg.index >= v.Len() {
+			return fmt.Errorf("%s: index out of range", next)
+		}
+		return setPathValue(v.Index(seg.index), segs[1:], val, next)
+	}
+	return fmt.Errorf("%s: cannot traverse %v", next, v.Kind())
+}
+
+// assignValue stores val into dst, converting when needed.
+func assignValue(dst reflect.Value, val interface{}, at string) error {
+	if val == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	rv := reflect.ValueOf(val)
+	if rv.Type().AssignableTo(dst.Type()) {
+		dst.Set(rv)
+		return nil
+	}
+	if fv, ok, err := convertFieldValue(dst.Type(), val); ok {
+		if err != nil {
+			return fmt.Errorf("%s: %w", at, err)
+		}
+		dst.Set(fv)
+		return nil
+	}
+	if isBaseKind(dst.Kind()) && isBaseKind(rv.Kind()) {
+		if fv := InitializeBaseTypeValue(dst.Type(), val); fv.IsValid() {
+			dst.Set(fv.Convert(dst.Type()))
+			return nil
+		}
+	}
+	if rv.CanConvert(dst.Type()) {
+		dst.Set(rv.Convert(dst.Type()))
+		return nil
+	}
+	return fmt.Errorf("%s: cannot assign %v to %v", at, rv.Type(), dst.Type())
+}
+
+// isBaseKind reports whether k is a string, bool or numeric kind.
+func isBaseKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func pathJoin(at string, seg pathSegment) string {
+	if seg.isIndex || at == "" {
+		return seg.String()
+	}
+	return "." + seg.String()
+}
@@ -0,0 +1,182 @@
+// utility/references.go
+package Utility
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// M_ reference resolution
+// -----------------------
+// Dynamic initialization stores references held in M_ fields as UUID strings
+// (M_Owner string, M_Members []string). ResolveReferences is the second pass
+// that wires the actual objects into the companion field named with a "Ptr"
+// suffix (M_OwnerPtr *User, M_MembersPtr []*User), walking nested entities.
+
+// EntityIndex collects entities by UUID. Its Add method can be passed as the
+// setEntity callback of MakeInstance/InitializeStructures, and Lookup as the
+// lookup of ResolveReferences.
+type EntityIndex struct {
+	mu       sync.RWMutex
+	entities map[string]interface{}
+}
+
+// NewEntityIndex creates an empty index.
+func NewEntityIndex() *EntityIndex {
+	return &EntityIndex{entities: make(map[string]interface{})}
+}
+
+// Add indexes entity by its UUID (GetUUID or a UUID field); others are ignored.
+func (idx *EntityIndex) Add(entity interface{}) {
+	uuid := entityUUID(entity)
+	if uuid == "" {
+		return
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.entities[uuid] = entity
+}
+
+// Lookup returns the entity with the given UUID, or nil.
+func (idx *EntityIndex) Lookup(uuid string) interface{} {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.entities[uuid]
+}
+
+// Entities returns every indexed entity.
+func (idx *EntityIndex) Entities() []interface{} {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	out := make([]interface{}, 0, len(idx.entities))
+	for _, e := range idx.entities {
+		out = append(out, e)
+	}
+	return out
+}
+
+func entityUUID(entity interface{}) string {
+	if r, ok := entity.(Referenceable); ok {
+		return r.GetUUID()
+	}
+	if v, ok := GetProperty(entity, conventionsOfType(reflect.TypeOf(entity)).IDKey); ok {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// UnresolvedReferencesError lists the UUIDs the lookup could not provide.
+type UnresolvedReferencesError struct {
+	UUIDs []string
+}
+
+func (e *UnresolvedReferencesError) Error() string {
+	return fmt.Sprintf("%d unresolved reference(s): %s", len(e.UUIDs), strings.Join(e.UUIDs, ", "))
+}
+
+// ResolveReferences walks the entities (and everything reachable from them)
+// and fills each M_xxxPtr field from the UUIDs held in M_xxx. Every reference
+// that can be resolved is wired; the others are reported in an
+// *UnresolvedReferencesError.
+func ResolveReferences(entities []interface{}, lookup func(uuid string) interface{}) error {
+	r := &referenceResolver{lookup: lookup, visited: make(map[uintptr]bool), missing: make(map[string]bool)}
+	for _, e := range entities {
+		r.walk(reflect.ValueOf(e))
+	}
+	if len(r.missing) == 0 {
+		return nil
+	}
+	uuids := make([]string, 0, len(r.missing))
+	for u := range r.missing {
+		uuids = append(uuids, u)
+	}
+	sort.Strings(uuids)
+	return &UnresolvedReferencesError{UUIDs: uuids}
+}
+
+type referenceResolver struct {
+	lookup  func(uuid string) interface{}
+	visited map[uintptr]bool
+	missing map[string]bool
+}
+
+func (r *referenceResolver) walk(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || r.visited[v.Pointer()] {
+			return
+		}
+		r.visited[v.Pointer()] = true
+		r.walk(v.Elem())
+	case reflect.Interface:
+		if !v.IsNil() {
+			r.walk(v.Elem())
+		}
+	case reflect.Struct:
+		t := v.Type()
+		refPrefix := conventionsOfType(t).RefPrefix
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if sf.PkgPath != "" {
+				continue
+			}
+			if strings.HasPrefix(sf.Name, refPrefix) && !strings.HasSuffix(sf.Name, "Ptr") {
+				if ptr := v.FieldByName(sf.Name + "Ptr"); ptr.IsValid() && ptr.CanSet() {
+					r.resolveField(v.Field(i), ptr)
+				}
+			}
+			r.walk(v.Field(i))
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			r.walk(v.Index(i))
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			r.walk(iter.Value())
+		}
+	}
+}
+
+// resolveField sets dst (pointer/interface or slice of them) from the UUID(s) in src.
+func (r *referenceResolver) resolveField(src, dst reflect.Value) {
+	switch {
+	case src.Kind() == reflect.String && dst.Kind() != reflect.Slice:
+		if src.Len() == 0 {
+			return
+		}
+		if target, ok := r.target(src.String(), dst.Type()); ok {
+			dst.Set(target)
+		}
+	case src.Kind() == reflect.Slice && src.Type().Elem().Kind() == reflect.String && dst.Kind() == reflect.Slice:
+		out := reflect.MakeSlice(dst.Type(), 0, src.Len())
+		for i := 0; i < src.Len(); i++ {
+			if target, ok := r.target(src.Index(i).String(), dst.Type().Elem()); ok {
+				out = reflect.Append(out, target)
+			}
+		}
+		dst.Set(out)
+	}
+}
+
+func (r *referenceResolver) target(uuid string, t reflect.Type) (reflect.Value, bool) {
+	obj := r.lookup(uuid)
+	if obj == nil {
+		r.missing[uuid] = true
+		return reflect.Value{}, false
+	}
+	rv := reflect.ValueOf(obj)
+	if !rv.Type().AssignableTo(t) {
+		r.missing[uuid] = true
+		return reflect.Value{}, false
+	}
+	// resolved objects are walked too, so their own references get wired
+	r.walk(rv)
+	return rv, true
+}
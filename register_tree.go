@@ -0,0 +1,81 @@
+// utility/register_tree.go
+package Utility
+
+import (
+	"reflect"
+	"unicode"
+)
+
+// Bulk type registration
+// ----------------------
+// Registering a root entity type also registers every exported named struct
+// type reachable through its fields (pointers, slices, arrays, maps), so
+// nested entities are rebuilt as structs instead of map[string]interface{}.
+// Structs without exported fields (time.Time, sync.Mutex...) are skipped since
+// they cannot be initialized from a map anyway.
+
+// RegisterTypesFromValue registers the types of the given values (instances
+// or typed nil pointers) and everything reachable from them. It returns the
+// names of the newly registered types.
+func RegisterTypesFromValue(values ...interface{}) []string {
+	seen := make(map[reflect.Type]bool)
+	var names []string
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		names = append(names, registerTypeTree(reflect.TypeOf(v), seen)...)
+	}
+	return names
+}
+
+// RegisterTypeTree registers t and every exported struct type reachable from
+// it, returning the names of the newly registered types.
+func RegisterTypeTree(t reflect.Type) []string {
+	return registerTypeTree(t, make(map[reflect.Type]bool))
+}
+
+func registerTypeTree(t reflect.Type, seen map[reflect.Type]bool) []string {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+
+	var names []string
+	switch t.Kind() {
+	case reflect.Map:
+		names = append(names, registerTypeTree(t.Key(), seen)...)
+		names = append(names, registerTypeTree(t.Elem(), seen)...)
+	case reflect.Struct:
+		if registrableStruct(t) {
+			name := typeNameOf(t)
+			if _, exists := DefaultTypeManager().GetType(name); !exists {
+				RegisterType(reflect.Zero(reflect.PointerTo(t)).Interface())
+				names = append(names, name)
+			}
+		}
+		for i := 0; i < t.NumField(); i++ {
+			if sf := t.Field(i); sf.IsExported() || sf.Anonymous {
+				names = append(names, registerTypeTree(sf.Type, seen)...)
+			}
+		}
+	}
+	return names
+}
+
+// registrableStruct reports whether t is a named, exported struct with at
+// least one exported field.
+func registrableStruct(t reflect.Type) bool {
+	if t.Name() == "" || t.PkgPath() == "" || !unicode.IsUpper([]rune(t.Name())[0]) {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			return true
+		}
+	}
+	return false
+}
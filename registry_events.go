@@ -0,0 +1,177 @@
+// utility/registry_events.go
+package Utility
+
+import (
+	"log"
+	"reflect"
+	"sync"
+)
+
+// Registry change events
+// ----------------------
+// Subscribers are called synchronously, after the registry lock is released,
+// for every registration or deletion. Events raised in a namespace are also
+// delivered to the subscribers of its ancestors, with Namespace set.
+
+// RegistryEventKind tells what changed in a registry.
+type RegistryEventKind int
+
+const (
+	TypeRegistered RegistryEventKind = iota
+	TypeDeleted
+	FuncRegistered
+	FuncDeleted
+)
+
+func (k RegistryEventKind) String() string {
+	switch k {
+	case TypeRegistered:
+		return "TypeRegistered"
+	case TypeDeleted:
+		return "TypeDeleted"
+	case FuncRegistered:
+		return "FuncRegistered"
+	case FuncDeleted:
+		return "FuncDeleted"
+	}
+	return "Unknown"
+}
+
+// RegistryEvent describes one registry change.
+type RegistryEvent struct {
+	Kind      RegistryEventKind
+	Name      string
+	Namespace string       // namespace the change happened in, "" for the root
+	Type      reflect.Type // for type events
+	Func      interface{}  // for function events
+}
+
+type registrySubscriber struct {
+	id int
+	fn func(RegistryEvent)
+}
+
+// Subscribe registers fn for registry events and returns a function that
+// unsubscribes it. A panicking subscriber is logged and does not affect others.
+func (tm *TypeManager) Subscribe(fn func(event RegistryEvent)) (unsubscribe func()) {
+	tm.mu.Lock()
+	tm.subSeq++
+	id := tm.subSeq
+	tm.subscribers = append(tm.subscribers, registrySubscriber{id: id, fn: fn})
+	tm.mu.Unlock()
+
+	return func() {
+		tm.mu.Lock()
+		defer tm.mu.Unlock()
+		for i, s := range tm.subscribers {
+			if s.id == id {
+				tm.subscribers = append(tm.subscribers[:i:i], tm.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// emit delivers ev to the subscribers of tm and of its ancestors.
+func (tm *TypeManager) emit(ev RegistryEvent) {
+	ev.Namespace = tm.name
+	for m := tm; m != nil; m = m.parent {
+		m.mu.RLock()
+		subs := m.subscribers
+		m.mu.RUnlock()
+		for _, s := range subs {
+			deliverRegistryEvent(s.fn, ev)
+		}
+	}
+}
+
+func deliverRegistryEvent(fn func(RegistryEvent), ev RegistryEvent) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("registry subscriber panicked on", ev.Kind, ev.Name+":", r)
+		}
+	}()
+	fn(ev)
+}
+
+// Subscribe registers fn for events of the default TypeManager.
+func Subscribe(fn func(event RegistryEvent)) (unsubscribe func()) {
+	return DefaultTypeManager().Subscribe(fn)
+}
+
+// qualifiedName returns the event's name as it is looked up from the root
+// ("ns:name" for namespaced changes).
+func (ev RegistryEvent) qualifiedName() string {
+	if ev.Namespace == "" {
+		return ev.Name
+	}
+	return ev.Namespace + ":" + ev.Name
+}
+
+// OnTypeRegistered calls fn whenever a type is registered in tm or one of its
+// namespaces. It returns a function that removes the callback.
+func (tm *TypeManager) OnTypeRegistered(fn func(name string, t reflect.Type)) (remove func()) {
+	return tm.Subscribe(func(ev RegistryEvent) {
+		if ev.Kind == TypeRegistered {
+			fn(ev.qualifiedName(), ev.Type)
+		}
+	})
+}
+
+// OnFuncRegistered calls fn whenever a function is registered in tm or one of
+// its namespaces. It returns a function that removes the callback.
+func (tm *TypeManager) OnFuncRegistered(fn func(name string, f interface{})) (remove func()) {
+	return tm.Subscribe(func(ev RegistryEvent) {
+		if ev.Kind == FuncRegistered {
+			fn(ev.qualifiedName(), ev.Func)
+		}
+	})
+}
+
+// Changes returns a channel receiving every registry event of tm. Events are
+// never blocked on: when the buffer is full they are dropped, so consumers
+// should treat any received event as "the registry changed" and resync.
+// cancel unsubscribes and closes the channel.
+func (tm *TypeManager) Changes(buffer int) (events <-chan RegistryEvent, cancel func()) {
+	if buffer < 1 {
+		buffer = 1
+	}
+	ch := make(chan RegistryEvent, buffer)
+	var mu sync.Mutex
+	closed := false
+	unsubscribe := tm.Subscribe(func(ev RegistryEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return
+		}
+		select {
+		case ch <- ev:
+		default:
+		}
+	})
+	return ch, func() {
+		unsubscribe()
+		mu.Lock()
+		defer mu.Unlock()
+		if !closed {
+			closed = true
+			close(ch)
+		}
+	}
+}
+
+// OnTypeRegistered calls fn for types registered in the default TypeManager.
+func OnTypeRegistered(fn func(name string, t reflect.Type)) (remove func()) {
+	return DefaultTypeManager().OnTypeRegistered(fn)
+}
+
+// OnFuncRegistered calls fn for functions registered in the default TypeManager.
+func OnFuncRegistered(fn func(name string, f interface{})) (remove func()) {
+	return DefaultTypeManager().OnFuncRegistered(fn)
+}
+
+// RegistryChanges returns a change channel of the default TypeManager.
+func RegistryChanges(buffer int) (events <-chan RegistryEvent, cancel func()) {
+	return DefaultTypeManager().Changes(buffer)
+}
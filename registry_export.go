@@ -0,0 +1,138 @@
+// utility/registry_export.go
+package Utility
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Registry manifests
+// ------------------
+// Export describes every registered type (name and field layout) in a
+// JSON/gob friendly form; Import checks a manifest received from another node
+// against the local registry so two peers can confirm they agree on entity
+// schemas before exchanging gob payloads.
+
+// FieldLayout describes one exported field of a registered struct.
+type FieldLayout struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Kind string `json:"kind"`
+}
+
+// TypeLayout describes a registered type.
+type TypeLayout struct {
+	Name   string        `json:"name"`
+	Kind   string        `json:"kind"`
+	Fields []FieldLayout `json:"fields,omitempty"`
+}
+
+// RegistryManifest is a snapshot of the types registered in a TypeManager.
+type RegistryManifest struct {
+	Namespace string       `json:"namespace,omitempty"`
+	Types     []TypeLayout `json:"types"`
+}
+
+// RegistryMismatchError lists the differences found by Import.
+type RegistryMismatchError struct {
+	Problems []string
+}
+
+func (e *RegistryMismatchError) Error() string {
+	return "registry mismatch: " + strings.Join(e.Problems, "; ")
+}
+
+// Export returns the manifest of the types registered in tm, sorted by name.
+func (tm *TypeManager) Export() *RegistryManifest {
+	names := tm.ListTypes()
+	sort.Strings(names)
+	m := &RegistryManifest{Namespace: tm.Name(), Types: make([]TypeLayout, 0, len(names))}
+	for _, name := range names {
+		if t, ok := tm.GetType(name); ok {
+			m.Types = append(m.Types, layoutOf(name, t))
+		}
+	}
+	return m
+}
+
+// Import validates a manifest exported by another node. Every type it lists
+// must be registered locally with the same kind and exactly the same exported
+// fields; types registered only locally are ignored.
+func (tm *TypeManager) Import(manifest *RegistryManifest) error {
+	if err := RequireNonNil(manifest, "manifest"); err != nil {
+		return err
+	}
+	var problems []string
+	for _, remote := range manifest.Types {
+		t, ok := tm.GetType(remote.Name)
+		if !ok {
+			problems = append(problems, remote.Name+": not registered")
+			continue
+		}
+		problems = append(problems, compareLayouts(remote, layoutOf(remote.Name, t))...)
+	}
+	if len(problems) > 0 {
+		return &RegistryMismatchError{Problems: problems}
+	}
+	return nil
+}
+
+// ExportRegistry returns the manifest of the default TypeManager.
+func ExportRegistry() *RegistryManifest {
+	return DefaultTypeManager().Export()
+}
+
+// ImportRegistry validates a manifest against the default TypeManager.
+func ImportRegistry(manifest *RegistryManifest) error {
+	return DefaultTypeManager().Import(manifest)
+}
+
+// layoutOf describes t; pointers are described by their element type.
+func layoutOf(name string, t reflect.Type) TypeLayout {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	l := TypeLayout{Name: name, Kind: t.Kind().String()}
+	if t.Kind() != reflect.Struct {
+		return l
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		l.Fields = append(l.Fields, FieldLayout{Name: f.Name, Type: f.Type.String(), Kind: f.Type.Kind().String()})
+	}
+	return l
+}
+
+// compareLayouts reports the differences between a remote and a local layout.
+func compareLayouts(remote, local TypeLayout) []string {
+	var problems []string
+	if remote.Kind != local.Kind {
+		return []string{fmt.Sprintf("%s: kind %s, local %s", remote.Name, remote.Kind, local.Kind)}
+	}
+	localFields := make(map[string]FieldLayout, len(local.Fields))
+	for _, f := range local.Fields {
+		localFields[f.Name] = f
+	}
+	for _, rf := range remote.Fields {
+		lf, ok := localFields[rf.Name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s.%s: missing locally", remote.Name, rf.Name))
+			continue
+		}
+		delete(localFields, rf.Name)
+		if rf.Type != lf.Type {
+			problems = append(problems, fmt.Sprintf("%s.%s: type %s, local %s", remote.Name, rf.Name, rf.Type, lf.Type))
+		}
+	}
+	for _, lf := range local.Fields {
+		if _, extra := localFields[lf.Name]; extra {
+			problems = append(problems, fmt.Sprintf("%s.%s: only defined locally", remote.Name, lf.Name))
+		}
+	}
+	return problems
+}
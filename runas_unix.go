@@ -0,0 +1,55 @@
+// utility/runas_unix.go
+//go:build !windows
+
+package Utility
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// RunAsUser makes cmd run with the credentials of username (uid, primary gid
+// and supplementary groups). The caller must be root unless username is the
+// current user. When cmd.Env is nil the child also gets that user's HOME,
+// USER and LOGNAME.
+func RunAsUser(cmd *exec.Cmd, username string) error {
+	if err := RequireNonNil(cmd, "cmd"); err != nil {
+		return err
+	}
+	u, err := LookupUser(username)
+	if err != nil {
+		return err
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return fmt.Errorf("user %s: invalid uid %q", username, u.Uid)
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return fmt.Errorf("user %s: invalid gid %q", username, u.Gid)
+	}
+	if euid := os.Geteuid(); euid != 0 && uint64(euid) != uid {
+		return fmt.Errorf("running as %s requires root privileges", username)
+	}
+
+	var groups []uint32
+	if ids, err := u.GroupIds(); err == nil {
+		for _, id := range ids {
+			if g, err := strconv.ParseUint(id, 10, 32); err == nil {
+				groups = append(groups, uint32(g))
+			}
+		}
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = new(syscall.SysProcAttr)
+	}
+	cmd.SysProcAttr.Credential = &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid), Groups: groups}
+	if cmd.Env == nil {
+		cmd.Env = BuildChildEnv(SnapshotEnv(), map[string]string{"HOME": u.HomeDir, "USER": u.Username, "LOGNAME": u.Username})
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+// utility/runas_windows.go
+//go:build windows
+
+package Utility
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+// RunAsUser is not available on Windows: starting a process as another user
+// needs that user's logon token. Use RunAsToken.
+func RunAsUser(cmd *exec.Cmd, username string) error {
+	return errors.New("RunAsUser needs a logon token on windows, use RunAsToken")
+}
+
+// RunAsToken makes cmd start with token (CreateProcessAsUser), e.g. a token
+// obtained with LogonUser or WTSQueryUserToken.
+func RunAsToken(cmd *exec.Cmd, token syscall.Token) error {
+	if err := RequireNonNil(cmd, "cmd"); err != nil {
+		return err
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = new(syscall.SysProcAttr)
+	}
+	cmd.SysProcAttr.Token = token
+	return nil
+}
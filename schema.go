@@ -0,0 +1,202 @@
+// utility/schema.go
+package Utility
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// JSON Schema generation for registered types
+// -------------------------------------------
+// Schemas follow draft 2020-12. Nested struct types are emitted once under
+// "$defs" and referenced by name, which also keeps recursive types finite.
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// GenerateSchema returns a JSON Schema (as a generic map, ready for json.Marshal)
+// describing the registered type typeName.
+func (tm *TypeManager) GenerateSchema(typeName string) (map[string]interface{}, error) {
+	t, ok := tm.GetType(typeName)
+	if !ok {
+		return nil, errors.New("no type was registered with name " + typeName)
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, errors.New("type " + typeName + " is not a struct")
+	}
+
+	g := &schemaGenerator{tm: tm, root: t, defs: make(map[string]interface{}), seen: make(map[reflect.Type]string)}
+	schema := g.structSchema(t, typeName)
+	schema["$schema"] = jsonSchemaDraft
+	schema["$id"] = typeName
+	schema["title"] = typeName
+	if len(g.defs) > 0 {
+		schema["$defs"] = g.defs
+	}
+	return schema, nil
+}
+
+// GenerateSchema returns the JSON Schema of a type registered in the default TypeManager.
+func GenerateSchema(typeName string) (map[string]interface{}, error) {
+	return DefaultTypeManager().GenerateSchema(typeName)
+}
+
+// nameOfType returns the registered name of t (reverse lookup).
+func (tm *TypeManager) nameOfType(t reflect.Type) (string, bool) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	for name, rt := range tm.typeRegistry {
+		if rt == t {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+type schemaGenerator struct {
+	tm   *TypeManager
+	root reflect.Type
+	defs map[string]interface{}
+	seen map[reflect.Type]string
+}
+
+// structSchema describes the exported fields of t as an object schema.
+func (g *schemaGenerator) structSchema(t reflect.Type, typeName string) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := make([]string, 0)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+		jsonTag := f.Tag.Get("json")
+		if jsonTag == "-" {
+			continue
+		}
+
+		// Embedded structs are flattened, mirroring field promotion.
+		if f.Anonymous {
+			et := f.Type
+			if et.Kind() == reflect.Ptr {
+				et = et.Elem()
+			}
+			if et.Kind() == reflect.Struct {
+				embedded := g.structSchema(et, "")
+				for k, v := range embedded["properties"].(map[string]interface{}) {
+					if _, exists := properties[k]; !exists {
+						properties[k] = v
+					}
+				}
+				if req, ok := embedded["required"].([]string); ok {
+					required = append(required, req...)
+				}
+				continue
+			}
+		}
+
+		if f.Name == g.tm.Conventions().TypeKey && typeName != "" {
+			properties[f.Name] = map[string]interface{}{"type": "string", "const": typeName}
+			required = append(required, f.Name)
+			continue
+		}
+
+		properties[f.Name] = g.typeSchema(f.Type)
+		if isRequiredField(f) {
+			required = append(required, f.Name)
+		}
+	}
+
+	sort.Strings(required)
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// typeSchema describes an arbitrary Go type.
+func (g *schemaGenerator) typeSchema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.typeSchema(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s := map[string]interface{}{"type": "integer"}
+		if t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64 {
+			s["minimum"] = 0
+		}
+		return s
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+		}
+		s := map[string]interface{}{"type": "array", "items": g.typeSchema(t.Elem())}
+		if t.Kind() == reflect.Array {
+			s["minItems"] = t.Len()
+			s["maxItems"] = t.Len()
+		}
+		return s
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.typeSchema(t.Elem())}
+	case reflect.Struct:
+		if t == timeType {
+			return map[string]interface{}{"type": "string", "format": "date-time"}
+		}
+		if t == g.root {
+			return map[string]interface{}{"$ref": "#"}
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + g.define(t)}
+	default:
+		// interfaces, funcs, channels: accept anything
+		return map[string]interface{}{}
+	}
+}
+
+// define emits t under $defs (once) and returns its definition name.
+func (g *schemaGenerator) define(t reflect.Type) string {
+	if name, ok := g.seen[t]; ok {
+		return name
+	}
+	name, registered := g.tm.nameOfType(t)
+	if !registered {
+		name = typeNameOf(t)
+		if t.Name() == "" {
+			name = "anonymous" + ToString(len(g.seen))
+		}
+	}
+	g.seen[t] = name
+	if registered {
+		g.defs[name] = g.structSchema(t, name)
+	} else {
+		g.defs[name] = g.structSchema(t, "")
+	}
+	return name
+}
+
+// isRequiredField reports whether a field must be present: value fields are
+// required unless tagged omitempty; pointers, maps and interfaces are optional.
+func isRequiredField(f reflect.StructField) bool {
+	switch f.Type.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map:
+		return false
+	}
+	return !strings.Contains(f.Tag.Get("json"), "omitempty")
+}
@@ -0,0 +1,85 @@
+// utility/settings.go
+package Utility
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// Package settings
+// ----------------
+// Configure sets process-wide defaults once at startup: where temporary files
+// go, which logger and HTTP client the helpers use, how much they may run in
+// parallel, where external tools live when they aren't on PATH, and which
+// GeoIP provider ForeignIP uses. Zero fields keep the package defaults
+// (os.TempDir, stdout, http.DefaultClient, runtime.NumCPU, PATH lookup,
+// ipinfo.io).
+
+// Settings holds the package-wide configuration.
+type Settings struct {
+	TempDir           string
+	Logger            *log.Logger
+	HTTPClient        *http.Client // see SetHTTPClientOptions
+	MaxParallelism    int
+	ExternalToolPaths map[string]string // tool name -> executable path
+	GeoIP             GeoIPProvider     // used by ForeignIP
+}
+
+var (
+	settingsMu sync.RWMutex
+	settings   Settings
+)
+
+// Configure replaces the package settings.
+func Configure(s Settings) {
+	paths := make(map[string]string, len(s.ExternalToolPaths))
+	for name, path := range s.ExternalToolPaths {
+		paths[name] = path
+	}
+	s.ExternalToolPaths = paths
+
+	settingsMu.Lock()
+	settings = s
+	settingsMu.Unlock()
+	setToolOverrides(paths)
+}
+
+// CurrentSettings returns the effective settings, defaults filled in.
+func CurrentSettings() Settings {
+	settingsMu.RLock()
+	s := settings
+	settingsMu.RUnlock()
+	if s.TempDir == "" {
+		s.TempDir = os.TempDir()
+	}
+	if s.HTTPClient == nil {
+		s.HTTPClient = http.DefaultClient
+	}
+	if s.MaxParallelism <= 0 {
+		s.MaxParallelism = runtime.NumCPU()
+	}
+	if s.GeoIP == nil {
+		s.GeoIP = &IPInfoGeoIP{}
+	}
+	return s
+}
+
+// tempDir is the directory for temporary files.
+func tempDir() string {
+	return CurrentSettings().TempDir
+}
+
+// httpClient is the client used for outgoing HTTP requests.
+func httpClient() *http.Client {
+	return CurrentSettings().HTTPClient
+}
+
+// defaultLogger is the configured logger, or nil for stdout.
+func defaultLogger() *log.Logger {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return settings.Logger
+}
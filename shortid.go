@@ -0,0 +1,124 @@
+// utility/shortid.go
+package Utility
+
+import (
+	"crypto/rand"
+	"math/bits"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Short identifiers
+// -----------------
+// RandomUUID strings are long for share links and file-name suffixes.
+// NewULID returns 26-char, lexicographically sortable IDs (48-bit millisecond
+// timestamp + 80 random bits, Crockford base32, monotonic within a
+// millisecond); NewNanoID returns compact random IDs over any alphabet.
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// DefaultNanoIDAlphabet is the URL-safe alphabet used when none is given.
+const DefaultNanoIDAlphabet = "_-0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+var (
+	ulidMu      sync.Mutex
+	ulidLastMs  uint64
+	ulidLastRnd [10]byte
+)
+
+// NewULID returns a new ULID.
+func NewULID() string {
+	ms := uint64(time.Now().UnixMilli())
+
+	ulidMu.Lock()
+	var rnd [10]byte
+	if ms <= ulidLastMs {
+		// Same (or earlier) millisecond: increment the previous random part so
+		// IDs stay strictly increasing.
+		ms = ulidLastMs
+		rnd = ulidLastRnd
+		for i := len(rnd) - 1; i >= 0; i-- {
+			rnd[i]++
+			if rnd[i] != 0 {
+				break
+			}
+		}
+	} else {
+		rand.Read(rnd[:])
+	}
+	ulidLastMs, ulidLastRnd = ms, rnd
+	ulidMu.Unlock()
+
+	var b [16]byte
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+	copy(b[6:], rnd[:])
+	return encodeULID(b)
+}
+
+// encodeULID writes the 128 bits of b as 26 Crockford base32 characters.
+func encodeULID(b [16]byte) string {
+	hi := uint64(b[0])<<56 | uint64(b[1])<<48 | uint64(b[2])<<40 | uint64(b[3])<<32 |
+		uint64(b[4])<<24 | uint64(b[5])<<16 | uint64(b[6])<<8 | uint64(b[7])
+	lo := uint64(b[8])<<56 | uint64(b[9])<<48 | uint64(b[10])<<40 | uint64(b[11])<<32 |
+		uint64(b[12])<<24 | uint64(b[13])<<16 | uint64(b[14])<<8 | uint64(b[15])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// ULIDTime returns the timestamp encoded in a ULID.
+func ULIDTime(id string) (time.Time, error) {
+	if len(id) != 26 {
+		return time.Time{}, &GuardError{Field: "id", Reason: "a ULID has 26 characters"}
+	}
+	var ms uint64
+	for _, c := range strings.ToUpper(id[:10]) {
+		i := strings.IndexRune(crockford, c)
+		if i < 0 {
+			return time.Time{}, &GuardError{Field: "id", Reason: "invalid ULID character " + string(c)}
+		}
+		ms = ms<<5 | uint64(i)
+	}
+	return time.UnixMilli(int64(ms)), nil
+}
+
+// NewNanoID returns a random ID of length characters drawn uniformly from
+// alphabet, an ASCII string (DefaultNanoIDAlphabet when empty; 21 characters
+// when length <= 0).
+func NewNanoID(length int, alphabet string) (string, error) {
+	if alphabet == "" {
+		alphabet = DefaultNanoIDAlphabet
+	}
+	if length <= 0 {
+		length = 21
+	}
+	if len(alphabet) < 2 || len(alphabet) > 256 {
+		return "", &GuardError{Field: "alphabet", Reason: "must have between 2 and 256 characters"}
+	}
+
+	// Draw bytes masked to the next power of two and reject those outside the
+	// alphabet, so every character is equally likely.
+	mask := byte(1<<bits.Len(uint(len(alphabet)-1)) - 1)
+	out := make([]byte, 0, length)
+	buf := make([]byte, length*2)
+	for len(out) < length {
+		rand.Read(buf)
+		for _, b := range buf {
+			if i := int(b & mask); i < len(alphabet) {
+				out = append(out, alphabet[i])
+				if len(out) == length {
+					break
+				}
+			}
+		}
+	}
+	return string(out), nil
+}
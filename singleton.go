@@ -0,0 +1,64 @@
+// utility/singleton.go
+package Utility
+
+import "sync"
+
+// Lazy singletons
+// ---------------
+// Services register a factory per name and share one instance, built on the
+// first GetSingleton call. Like types, singletons of a namespace fall back to
+// the parent manager.
+
+type singletonEntry struct {
+	once    sync.Once
+	factory func() interface{}
+	value   interface{}
+}
+
+// RegisterSingleton registers (or replaces) the factory of a named singleton.
+// Replacing a factory drops the instance it built, if any.
+func (tm *TypeManager) RegisterSingleton(name string, factory func() interface{}) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if tm.singletons == nil {
+		tm.singletons = make(map[string]*singletonEntry)
+	}
+	tm.singletons[name] = &singletonEntry{factory: factory}
+}
+
+// GetSingleton returns the named singleton, calling its factory exactly once.
+func (tm *TypeManager) GetSingleton(name string) (interface{}, bool) {
+	tm.mu.RLock()
+	e, ok := tm.singletons[name]
+	parent := tm.parent
+	tm.mu.RUnlock()
+	if !ok {
+		if parent != nil {
+			return parent.GetSingleton(name)
+		}
+		return nil, false
+	}
+	e.once.Do(func() {
+		if e.factory != nil {
+			e.value = e.factory()
+		}
+	})
+	return e.value, true
+}
+
+// DeleteSingleton removes a singleton and its instance (no-op if not present).
+func (tm *TypeManager) DeleteSingleton(name string) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	delete(tm.singletons, name)
+}
+
+// RegisterSingleton registers a singleton factory in the default TypeManager.
+func RegisterSingleton(name string, factory func() interface{}) {
+	DefaultTypeManager().RegisterSingleton(name, factory)
+}
+
+// GetSingleton returns a singleton of the default TypeManager.
+func GetSingleton(name string) (interface{}, bool) {
+	return DefaultTypeManager().GetSingleton(name)
+}
@@ -0,0 +1,309 @@
+// utility/sse.go
+package Utility
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server-sent events and long-poll helper
+// ---------------------------------------
+// SSEBroker fans published events out to web clients. Clients subscribe to
+// one or more topics (?topic=a&topic=b, none meaning all topics), receive
+// periodic heartbeats, and get the last N events of each topic replayed when
+// they reconnect with a Last-Event-ID header.
+
+// SSEEvent is a published event as delivered to clients.
+type SSEEvent struct {
+	ID    uint64      `json:"id"`
+	Topic string      `json:"topic"`
+	Data  interface{} `json:"data"`
+	Time  time.Time   `json:"time"`
+}
+
+type sseClient struct {
+	topics map[string]bool
+	events chan SSEEvent
+}
+
+func (c *sseClient) wants(topic string) bool {
+	return len(c.topics) == 0 || c.topics[topic]
+}
+
+// SSEBroker manages SSE/long-poll clients and a per-topic replay buffer.
+type SSEBroker struct {
+	mu        sync.Mutex
+	clients   map[*sseClient]struct{}
+	history   map[string][]SSEEvent
+	nextID    uint64
+	notify    chan struct{} // closed and replaced on every publish (long-poll wakeup)
+	closed    bool
+	replay    int
+	heartbeat time.Duration
+}
+
+// NewSSEBroker creates a broker keeping the last replay events per topic and
+// sending a heartbeat comment every heartbeat interval (0 disables it).
+func NewSSEBroker(replay int, heartbeat time.Duration) *SSEBroker {
+	if replay < 0 {
+		replay = 0
+	}
+	return &SSEBroker{
+		clients:   make(map[*sseClient]struct{}),
+		history:   make(map[string][]SSEEvent),
+		notify:    make(chan struct{}),
+		replay:    replay,
+		heartbeat: heartbeat,
+	}
+}
+
+// Publish sends event to every client subscribed to topic and records it for replay.
+// Clients whose queue is full are disconnected rather than blocking the publisher.
+func (b *SSEBroker) Publish(topic string, event interface{}) SSEEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	ev := SSEEvent{ID: b.nextID, Topic: topic, Data: event, Time: time.Now()}
+	if b.closed {
+		return ev
+	}
+
+	if b.replay > 0 {
+		h := append(b.history[topic], ev)
+		if len(h) > b.replay {
+			h = h[len(h)-b.replay:]
+		}
+		b.history[topic] = h
+	}
+
+	for c := range b.clients {
+		if !c.wants(topic) {
+			continue
+		}
+		select {
+		case c.events <- ev:
+		default:
+			delete(b.clients, c)
+			close(c.events)
+		}
+	}
+
+	close(b.notify)
+	b.notify = make(chan struct{})
+	return ev
+}
+
+// Close disconnects every client; later publications are dropped.
+func (b *SSEBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for c := range b.clients {
+		close(c.events)
+	}
+	b.clients = make(map[*sseClient]struct{})
+	close(b.notify)
+}
+
+// ClientCount returns the number of connected SSE clients.
+func (b *SSEBroker) ClientCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.clients)
+}
+
+// since returns the recorded events newer than lastID for the given topics, in ID order.
+func (b *SSEBroker) since(topics map[string]bool, lastID uint64) []SSEEvent {
+	events := make([]SSEEvent, 0)
+	for topic, h := range b.history {
+		if len(topics) > 0 && !topics[topic] {
+			continue
+		}
+		for _, ev := range h {
+			if ev.ID > lastID {
+				events = append(events, ev)
+			}
+		}
+	}
+	// merge order across topics
+	for i := 1; i < len(events); i++ {
+		for j := i; j > 0 && events[j].ID < events[j-1].ID; j-- {
+			events[j], events[j-1] = events[j-1], events[j]
+		}
+	}
+	return events
+}
+
+// Poll returns the events newer than lastID for topics, waiting until at least
+// one is available or ctx is done (in which case an empty slice is returned).
+func (b *SSEBroker) Poll(ctx context.Context, topics []string, lastID uint64) ([]SSEEvent, error) {
+	set := topicSet(topics)
+	for {
+		b.mu.Lock()
+		events := b.since(set, lastID)
+		notify, closed := b.notify, b.closed
+		b.mu.Unlock()
+
+		if len(events) > 0 || closed {
+			return events, nil
+		}
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return events, nil
+			}
+			return events, ctx.Err()
+		}
+	}
+}
+
+// ServeHTTP streams events as text/event-stream.
+func (b *SSEBroker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	client := &sseClient{topics: topicSet(r.URL.Query()["topic"]), events: make(chan SSEEvent, 64)}
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		http.Error(w, "broker closed", http.StatusServiceUnavailable)
+		return
+	}
+	var backlog []SSEEvent
+	if last := r.Header.Get("Last-Event-ID"); last != "" {
+		if id, err := strconv.ParseUint(last, 10, 64); err == nil {
+			backlog = b.since(client.topics, id)
+		}
+	}
+	b.clients[client] = struct{}{}
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		if _, ok := b.clients[client]; ok {
+			delete(b.clients, client)
+			close(client.events)
+		}
+		b.mu.Unlock()
+	}()
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	for _, ev := range backlog {
+		if err := writeSSEEvent(w, ev); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	var tick <-chan time.Time
+	if b.heartbeat > 0 {
+		ticker := time.NewTicker(b.heartbeat)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-client.events:
+			if !ok {
+				return
+			}
+			if err := writeSSEEvent(w, ev); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-tick:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// LongPollHandler serves events as a JSON array for clients that cannot use SSE.
+// Query parameters: topic (repeatable), since (last seen event id) and
+// timeout (Go duration, default 30s).
+func (b *SSEBroker) LongPollHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		lastID, _ := strconv.ParseUint(q.Get("since"), 10, 64)
+		timeout := 30 * time.Second
+		if t, err := time.ParseDuration(q.Get("timeout")); err == nil && t > 0 {
+			timeout = t
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		events, err := b.Poll(ctx, q["topic"], lastID)
+		if err != nil {
+			return // client went away
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-cache")
+		json.NewEncoder(w).Encode(events)
+	})
+}
+
+// writeSSEEvent writes ev in the text/event-stream wire format.
+func writeSSEEvent(w http.ResponseWriter, ev SSEEvent) error {
+	var data string
+	switch d := ev.Data.(type) {
+	case string:
+		data = d
+	case []byte:
+		data = string(d)
+	default:
+		b, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		data = string(b)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("id: " + strconv.FormatUint(ev.ID, 10) + "\n")
+	if ev.Topic != "" {
+		sb.WriteString("event: " + ev.Topic + "\n")
+	}
+	for _, line := range strings.Split(data, "\n") {
+		sb.WriteString("data: " + line + "\n")
+	}
+	sb.WriteString("\n")
+	_, err := fmt.Fprint(w, sb.String())
+	return err
+}
+
+func topicSet(topics []string) map[string]bool {
+	set := make(map[string]bool, len(topics))
+	for _, t := range topics {
+		if t != "" && t != "*" {
+			set[t] = true
+		}
+	}
+	return set
+}
@@ -7,10 +7,14 @@ import (
 	"errors"
 	"reflect"
 	"sort"
+	"strings"
+	"sync"
 	"unicode"
 
 	"github.com/kalafut/imohash"
 	"github.com/pborman/uuid"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
 	"golang.org/x/text/runes"
 	"golang.org/x/text/transform"
 	"golang.org/x/text/unicode/norm"
@@ -26,6 +30,22 @@ func Contains(slice []string, item string) bool {
 	return ok
 }
 
+// ContainsFold checks if a slice contains a string, ignoring case.
+func ContainsFold(slice []string, item string) bool {
+	return IndexFold(slice, item) >= 0
+}
+
+// IndexFold returns the index of the first string equal to item under
+// Unicode case folding, or -1.
+func IndexFold(slice []string, item string) int {
+	for i, s := range slice {
+		if strings.EqualFold(s, item) {
+			return i
+		}
+	}
+	return -1
+}
+
 // Remove removes an element from a slice by index.
 func Remove(s []string, index int) ([]string, error) {
 	if index >= len(s) {
@@ -87,6 +107,39 @@ func RemoveAccent(text string) string {
 	return s
 }
 
+// collators pools collate.Collator values (not safe for concurrent use) per locale.
+var collators sync.Map // locale -> *sync.Pool
+
+func collatorFor(locale string) (*collate.Collator, *sync.Pool) {
+	p, ok := collators.Load(locale)
+	if !ok {
+		tag := language.Make(locale)
+		p, _ = collators.LoadOrStore(locale, &sync.Pool{New: func() interface{} {
+			return collate.New(tag, collate.IgnoreCase)
+		}})
+	}
+	pool := p.(*sync.Pool)
+	return pool.Get().(*collate.Collator), pool
+}
+
+// CompareLocale compares a and b with the collation rules of locale (a BCP 47
+// tag such as "fr", "de-DE" or "sv"), ignoring case. It returns -1, 0 or 1.
+func CompareLocale(a, b, locale string) int {
+	c, pool := collatorFor(locale)
+	defer pool.Put(c)
+	return c.CompareString(a, b)
+}
+
+// SortStringsLocale returns a copy of s sorted for display in locale.
+func SortStringsLocale(s []string, locale string) []string {
+	result := make([]string, len(s))
+	copy(result, s)
+	c, pool := collatorFor(locale)
+	defer pool.Put(c)
+	c.SortStrings(result)
+	return result
+}
+
 // SortStrings returns a new sorted copy of the input slice.
 func SortStrings(s []string) []string {
 	result := make([]string, len(s))
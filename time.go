@@ -3,8 +3,11 @@ package Utility
 
 import (
 	"errors"
+	"math"
+	"reflect"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -117,3 +120,75 @@ func MatchISO8601_DateTime(str string) (*time.Time, error) {
 	return &t, nil
 }
 
+// ToTimeE converts value to a time.Time. Strings may be RFC 3339, an
+// ISO 8601 date or date-time without zone (read as UTC), "2006-01-02
+// 15:04:05" or a number; numbers are Unix times in seconds, or in
+// milliseconds when they are too large to be seconds (JavaScript's
+// Date.now()). An empty string is the zero time.
+func ToTimeE(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case nil:
+		return time.Time{}, nil
+	case time.Time:
+		return v, nil
+	case *time.Time:
+		if v == nil {
+			return time.Time{}, nil
+		}
+		return *v, nil
+	case string:
+		s := strings.TrimSpace(v)
+		if s == "" {
+			return time.Time{}, nil
+		}
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return t, nil
+		}
+		if t, err := time.ParseInLocation(time.DateTime, s, time.UTC); err == nil {
+			return t, nil
+		}
+		if t, err := MatchISO8601_DateTime(s); err == nil {
+			return *t, nil
+		}
+		if t, err := MatchISO8601_Date(s); err == nil {
+			return *t, nil
+		}
+		if _, err := strconv.ParseFloat(s, 64); err != nil {
+			return time.Time{}, &GuardError{Reason: "invalid time " + strconv.Quote(v)}
+		}
+	}
+	n, err := ToNumericE(value)
+	if err != nil {
+		return time.Time{}, &GuardError{Reason: "value with type " + reflect.TypeOf(value).String() + " cannot be converted to time.Time"}
+	}
+	if math.Abs(n) >= 1e12 {
+		return time.UnixMilli(int64(n)).UTC(), nil
+	}
+	sec, frac := math.Modf(n)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC(), nil
+}
+
+// ToDurationE converts value to a time.Duration. Strings use the
+// time.ParseDuration syntax ("1h30m"); numbers are nanoseconds, as
+// encoding/json writes durations.
+func ToDurationE(value interface{}) (time.Duration, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case time.Duration:
+		return v, nil
+	case string:
+		s := strings.TrimSpace(v)
+		if d, err := time.ParseDuration(s); err == nil {
+			return d, nil
+		}
+		if _, err := strconv.ParseFloat(s, 64); err != nil {
+			return 0, &GuardError{Reason: "invalid duration " + strconv.Quote(v)}
+		}
+	}
+	n, err := ToNumericE(value)
+	if err != nil {
+		return 0, &GuardError{Reason: "value with type " + reflect.TypeOf(value).String() + " cannot be converted to time.Duration"}
+	}
+	return time.Duration(n), nil
+}
@@ -0,0 +1,166 @@
+// utility/tools.go
+package Utility
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"sync"
+)
+
+// External tool capabilities
+// --------------------------
+// Some helpers shell out to tools that may not be installed (ffprobe, nmap,
+// tesseract...). They check the registry first and return an *ErrToolMissing
+// carrying an install hint instead of a bare exec error.
+
+// ErrToolMissing is returned when a helper needs a tool that is not on PATH.
+type ErrToolMissing struct {
+	Tool string
+	Hint string
+}
+
+func (e *ErrToolMissing) Error() string {
+	msg := "required tool " + e.Tool + " was not found in PATH"
+	if e.Hint != "" {
+		msg += " (" + e.Hint + ")"
+	}
+	return msg
+}
+
+// IsToolMissing reports whether err (or an error it wraps) is an *ErrToolMissing.
+func IsToolMissing(err error) bool {
+	var tm *ErrToolMissing
+	return errors.As(err, &tm)
+}
+
+// toolHints lists the tools used by this package and how to get them.
+var toolHints = map[string]string{
+	"ffprobe":   "install ffmpeg, e.g. apt install ffmpeg / brew install ffmpeg",
+	"ffmpeg":    "install ffmpeg, e.g. apt install ffmpeg / brew install ffmpeg",
+	"nmap":      "install nmap, e.g. apt install nmap / brew install nmap",
+	"awk":       "install awk (gawk or mawk)",
+	"arp":       "install net-tools, e.g. apt install net-tools",
+	"tar":       "install tar",
+	"tesseract": "install tesseract-ocr, e.g. apt install tesseract-ocr / brew install tesseract",
+	"rsync":     "install rsync, e.g. apt install rsync / brew install rsync",
+	"useradd":   "install passwd (shadow-utils)",
+	"usermod":   "install passwd (shadow-utils)",
+}
+
+var (
+	toolsMu       sync.RWMutex
+	tools         = make(map[string]string) // name -> resolved path, "" when missing
+	toolOverrides map[string]string         // name -> configured path (Settings.ExternalToolPaths)
+)
+
+func init() {
+	RefreshTools()
+}
+
+// RefreshTools re-resolves every known tool (call after installing one).
+func RefreshTools() {
+	toolsMu.RLock()
+	names := make([]string, 0, len(toolHints)+len(tools))
+	for name := range toolHints {
+		names = append(names, name)
+	}
+	for name := range tools {
+		if _, hinted := toolHints[name]; !hinted {
+			names = append(names, name)
+		}
+	}
+	toolsMu.RUnlock()
+
+	found := make(map[string]string, len(names))
+	for _, name := range names {
+		found[name] = lookupTool(name)
+	}
+	toolsMu.Lock()
+	tools = found
+	toolsMu.Unlock()
+}
+
+// RegisterTool adds a tool (and its install hint) to the registry.
+func RegisterTool(name, hint string) bool {
+	path := lookupTool(name)
+	toolsMu.Lock()
+	defer toolsMu.Unlock()
+	toolHints[name] = hint
+	tools[name] = path
+	return path != ""
+}
+
+// ToolPath returns the resolved path of a tool. Unknown tools are looked up
+// once and cached.
+func ToolPath(name string) (string, bool) {
+	toolsMu.RLock()
+	path, known := tools[name]
+	toolsMu.RUnlock()
+	if !known {
+		path = lookupTool(name)
+		toolsMu.Lock()
+		tools[name] = path
+		toolsMu.Unlock()
+	}
+	return path, path != ""
+}
+
+// HasTool reports whether a tool is available on PATH.
+func HasTool(name string) bool {
+	_, ok := ToolPath(name)
+	return ok
+}
+
+// RequireTool returns an *ErrToolMissing for the first unavailable tool.
+func RequireTool(names ...string) error {
+	for _, name := range names {
+		if !HasTool(name) {
+			toolsMu.RLock()
+			hint := toolHints[name]
+			toolsMu.RUnlock()
+			return &ErrToolMissing{Tool: name, Hint: hint}
+		}
+	}
+	return nil
+}
+
+// AvailableTools returns every known tool and whether it is available.
+func AvailableTools() map[string]bool {
+	toolsMu.RLock()
+	defer toolsMu.RUnlock()
+	out := make(map[string]bool, len(tools))
+	for name, path := range tools {
+		out[name] = path != ""
+	}
+	return out
+}
+
+// lookupTool resolves a tool through its configured path, else PATH; it
+// returns "" when the tool can't be executed.
+func lookupTool(name string) string {
+	toolsMu.RLock()
+	override := toolOverrides[name]
+	toolsMu.RUnlock()
+	if override != "" {
+		name = override
+	}
+	path, _ := exec.LookPath(name)
+	return path
+}
+
+// setToolOverrides installs configured tool paths and re-resolves the tools.
+func setToolOverrides(paths map[string]string) {
+	toolsMu.Lock()
+	toolOverrides = paths
+	toolsMu.Unlock()
+	RefreshTools()
+}
+
+// toolCommand is exec.CommandContext running the resolved path of a tool.
+func toolCommand(ctx context.Context, name string, args ...string) *exec.Cmd {
+	if path, ok := ToolPath(name); ok {
+		name = path
+	}
+	return exec.CommandContext(ctx, name, args...)
+}
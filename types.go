@@ -3,7 +3,10 @@ package Utility
 
 import (
 	"reflect"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 // TypeManager provides concurrent-safe registries for types and functions.
@@ -11,6 +14,29 @@ type TypeManager struct {
 	mu               sync.RWMutex
 	typeRegistry     map[string]reflect.Type
 	functionRegistry map[string]interface{}
+	converters       map[converterKey]FieldConverter
+	functionMeta     map[string]FuncMeta
+	interceptors     []CallInterceptor
+	convGen          uint64 // bumped when converters change
+
+	name       string                  // namespace name, "" for a root manager
+	parent     *TypeManager            // lookup fallback for namespaces
+	namespaces map[string]*TypeManager // child namespaces, created on demand
+
+	subscribers []registrySubscriber
+	subSeq      int
+
+	singletons   map[string]*singletonEntry
+	constructors map[string]constructorEntry
+
+	typeVersions map[string]int                   // current TYPEVERSION per type name
+	migrations   map[string]map[int]typeMigration // type name -> from version -> step
+
+	plugins map[string]bool // absolute paths of the loaded plugins
+
+	conventions *Conventions // nil inherits the parent's, then the defaults
+
+	funcSchemas map[string]*FuncSchema // schemas of the functions registered typed
 }
 
 // NewTypeManager creates a new, empty manager.
@@ -18,21 +44,37 @@ func NewTypeManager() *TypeManager {
 	return &TypeManager{
 		typeRegistry:     make(map[string]reflect.Type),
 		functionRegistry: make(map[string]interface{}),
+		converters:       make(map[converterKey]FieldConverter),
+		functionMeta:     make(map[string]FuncMeta),
 	}
 }
 
 // RegisterType registers a type under a name (overwrites if already present).
 func (tm *TypeManager) RegisterType(name string, t reflect.Type) {
 	tm.mu.Lock()
-	defer tm.mu.Unlock()
 	tm.typeRegistry[name] = t
+	tm.mu.Unlock()
+	atomic.AddUint64(&typeGen, 1)
+	tm.emit(RegistryEvent{Kind: TypeRegistered, Name: name, Type: t})
 }
 
 // GetType returns a type and a boolean indicating if it exists.
+// Namespace-qualified names ("pluginA:model.User") are resolved in that
+// namespace; unqualified names fall back to the parent manager.
 func (tm *TypeManager) GetType(name string) (reflect.Type, bool) {
+	if ns, rest, qualified := strings.Cut(name, ":"); qualified {
+		if child := tm.findNamespace(ns); child != nil {
+			return child.GetType(rest)
+		}
+		return nil, false
+	}
 	tm.mu.RLock()
-	defer tm.mu.RUnlock()
 	t, ok := tm.typeRegistry[name]
+	parent := tm.parent
+	tm.mu.RUnlock()
+	if !ok && parent != nil {
+		return parent.GetType(name)
+	}
 	return t, ok
 }
 
@@ -47,30 +89,53 @@ func (tm *TypeManager) RegisterInstance(name string, instance interface{}) {
 // RegisterFunc registers a callable under a name (overwrites if already present).
 func (tm *TypeManager) RegisterFunc(name string, fn interface{}) {
 	tm.mu.Lock()
-	defer tm.mu.Unlock()
 	tm.functionRegistry[name] = fn
+	tm.mu.Unlock()
+	tm.emit(RegistryEvent{Kind: FuncRegistered, Name: name, Func: fn})
 }
 
 // GetFunc returns a function and a boolean indicating if it exists.
+// Lookup follows the same namespace rules as GetType.
 func (tm *TypeManager) GetFunc(name string) (interface{}, bool) {
+	if ns, rest, qualified := strings.Cut(name, ":"); qualified {
+		if child := tm.findNamespace(ns); child != nil {
+			return child.GetFunc(rest)
+		}
+		return nil, false
+	}
 	tm.mu.RLock()
-	defer tm.mu.RUnlock()
 	f, ok := tm.functionRegistry[name]
+	parent := tm.parent
+	tm.mu.RUnlock()
+	if !ok && parent != nil {
+		return parent.GetFunc(name)
+	}
 	return f, ok
 }
 
 // DeleteType removes a type by name (no-op if not present).
 func (tm *TypeManager) DeleteType(name string) {
 	tm.mu.Lock()
-	defer tm.mu.Unlock()
+	t, ok := tm.typeRegistry[name]
 	delete(tm.typeRegistry, name)
+	delete(tm.typeVersions, name)
+	tm.mu.Unlock()
+	if ok {
+		atomic.AddUint64(&typeGen, 1)
+		tm.emit(RegistryEvent{Kind: TypeDeleted, Name: name, Type: t})
+	}
 }
 
 // DeleteFunc removes a function by name (no-op if not present).
 func (tm *TypeManager) DeleteFunc(name string) {
 	tm.mu.Lock()
-	defer tm.mu.Unlock()
+	fn, ok := tm.functionRegistry[name]
 	delete(tm.functionRegistry, name)
+	delete(tm.functionMeta, name)
+	tm.mu.Unlock()
+	if ok {
+		tm.emit(RegistryEvent{Kind: FuncDeleted, Name: name, Func: fn})
+	}
 }
 
 // ListTypes returns a snapshot of registered type names.
@@ -95,6 +160,28 @@ func (tm *TypeManager) ListFuncs() []string {
 	return keys
 }
 
+// GetTypesImplementing returns the sorted names of registered types T for
+// which T or *T implements the interface ifacePtr points to, e.g.
+// (*Referenceable)(nil). It returns nil if ifacePtr isn't an interface pointer.
+func (tm *TypeManager) GetTypesImplementing(ifacePtr interface{}) []string {
+	it := reflect.TypeOf(ifacePtr)
+	if it == nil || it.Kind() != reflect.Ptr || it.Elem().Kind() != reflect.Interface {
+		return nil
+	}
+	it = it.Elem()
+
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	var names []string
+	for name, t := range tm.typeRegistry {
+		if t.Implements(it) || (t.Kind() != reflect.Ptr && reflect.PointerTo(t).Implements(it)) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
 // -----------------------------------------------------------------------------
 // Singleton accessors (replaces the original package-level getTypeManager()).
 // -----------------------------------------------------------------------------
@@ -0,0 +1,178 @@
+// utility/upload.go
+package Utility
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// File uploads
+// ------------
+// UploadFile sends a file either as a multipart/form-data form (the file
+// under FieldName, plus FormFields) or as the raw body of a streaming PUT.
+// The file is streamed from disk, never buffered, and reopened on every
+// retry. Progress, bandwidth limiting and retries behave as for downloads.
+
+// UploadOptions configures UploadFile; zero values use the defaults.
+type UploadOptions struct {
+	Multipart   bool              // send a multipart/form-data form instead of the raw file
+	Method      string            // default POST for multipart, PUT for raw uploads
+	FieldName   string            // form field of the file (default "file")
+	FileName    string            // file name sent in the form (default the base name of path)
+	FormFields  map[string]string // extra form fields
+	ContentType string            // raw body type (default application/octet-stream)
+
+	Header      http.Header
+	BearerToken string // sent as "Authorization: Bearer <token>"
+	Username    string // HTTP basic authentication when set
+	Password    string
+
+	Retry          RetryPolicy // default 5 attempts, 500ms backoff doubling up to 30s
+	BandwidthLimit int64       // bytes per second, 0 = unlimited
+	Progress       *Progress   // counts the file bytes sent, completed at the end
+}
+
+const maxUploadResponse = 10 << 20
+
+// UploadFile uploads the file at path to url and returns the response body
+// (up to 10 MiB).
+func UploadFile(ctx context.Context, url, path string, opts UploadOptions) (response []byte, err error) {
+	defer endOperation(startOperation("upload", map[string]interface{}{"url": url, "file": path}), &err)
+
+	if err := RequireNonEmpty(url, "url"); err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return nil, &GuardError{Field: "path", Reason: "is a directory"}
+	}
+	if opts.Method == "" {
+		opts.Method = http.MethodPut
+		if opts.Multipart {
+			opts.Method = http.MethodPost
+		}
+	}
+	if opts.FieldName == "" {
+		opts.FieldName = "file"
+	}
+	if opts.FileName == "" {
+		opts.FileName = filepath.Base(path)
+	}
+	if opts.ContentType == "" {
+		opts.ContentType = "application/octet-stream"
+	}
+
+	o := NewOptions(WithRetry(transferRetry(opts.Retry)))
+	err = o.Do(ctx, func(ctx context.Context) error {
+		response, err = uploadAttempt(ctx, url, path, fi.Size(), &opts)
+		return err
+	})
+	if err == nil && opts.Progress != nil {
+		opts.Progress.Done()
+	}
+	return response, err
+}
+
+func uploadAttempt(ctx context.Context, url, path string, size int64, opts *UploadOptions) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// downloadWriter does the counting, throttling and reporting of the
+	// bytes read from the file.
+	if opts.Progress != nil {
+		opts.Progress.SetTotal(size)
+		opts.Progress.Set(0)
+	}
+	counter := &downloadWriter{
+		w: io.Discard, ctx: ctx, start: time.Now(),
+		opts: &DownloadOptions{BandwidthLimit: opts.BandwidthLimit, Progress: opts.Progress},
+	}
+	source := io.TeeReader(file, counter)
+
+	var body io.Reader
+	contentType := opts.ContentType
+	contentLength := size
+	if opts.Multipart {
+		body, contentType, contentLength, err = multipartBody(source, size, opts)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		body = source
+	}
+
+	req, err := http.NewRequestWithContext(ctx, opts.Method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = contentLength
+	for k, v := range opts.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", contentType)
+	if opts.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+opts.BearerToken)
+	} else if opts.Username != "" {
+		req.SetBasicAuth(opts.Username, opts.Password)
+	}
+
+	resp, err := httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxUploadResponse))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err := statusError("upload", resp)
+		if msg := strings.TrimSpace(string(data)); msg != "" {
+			if len(msg) > 512 {
+				msg = msg[:512]
+			}
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// multipartBody streams the form around file, returning the body, its
+// content type and its exact length (so no chunked encoding is needed).
+func multipartBody(file io.Reader, size int64, opts *UploadOptions) (io.Reader, string, int64, error) {
+	var head bytes.Buffer
+	mw := multipart.NewWriter(&head)
+	for k, v := range opts.FormFields {
+		if err := mw.WriteField(k, v); err != nil {
+			return nil, "", 0, err
+		}
+	}
+	if _, err := mw.CreateFormFile(opts.FieldName, opts.FileName); err != nil {
+		return nil, "", 0, err
+	}
+	headLen := head.Len()
+	if err := mw.Close(); err != nil {
+		return nil, "", 0, err
+	}
+	// mw.Close appended the closing boundary after the file part header.
+	tail := append([]byte(nil), head.Bytes()[headLen:]...)
+	head.Truncate(headLen)
+
+	length := int64(head.Len()) + size + int64(len(tail))
+	return io.MultiReader(&head, file, bytes.NewReader(tail)), mw.FormDataContentType(), length, nil
+}
@@ -0,0 +1,176 @@
+// utility/users.go
+package Utility
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/user"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Users and groups
+// ----------------
+// The installer creates the service account that runs Globular. These
+// helpers wrap the platform tools (useradd/usermod on Linux, dscl and
+// dseditgroup on macOS, net user/localgroup on Windows) and are idempotent:
+// creating an existing user or adding a member twice succeeds without
+// running anything. They honor WithRunner and WithTimeout.
+
+// unixAccountRe is the portable user/group name syntax of useradd.
+var unixAccountRe = regexp.MustCompile(`^[a-z_][a-z0-9_-]*$`)
+
+// checkAccountName validates a user or group name before it is passed to
+// the platform tools, where a leading '-' would be read as an option.
+func checkAccountName(name, field string) error {
+	if err := RequireNonEmpty(name, field); err != nil {
+		return err
+	}
+	if runtime.GOOS == "windows" {
+		if name[0] == '-' || name[0] == '/' || strings.ContainsAny(name, `"/\[]:;|=,+*?<>@`) {
+			return &GuardError{Field: field, Reason: "invalid account name " + strconv.Quote(name)}
+		}
+		return nil
+	}
+	if len(name) > 32 || !unixAccountRe.MatchString(name) {
+		return &GuardError{Field: field, Reason: "invalid account name " + strconv.Quote(name)}
+	}
+	return nil
+}
+
+// LookupUser returns the account name, or an error when it doesn't exist.
+func LookupUser(name string) (*user.User, error) {
+	return user.Lookup(name)
+}
+
+// LookupGroup returns the group name, or an error when it doesn't exist.
+func LookupGroup(name string) (*user.Group, error) {
+	return user.LookupGroup(name)
+}
+
+// CreateSystemUser creates a system (non-login, no password) account with
+// the given home directory and shell; empty values use the platform
+// defaults. Home and shell are ignored on Windows. On Unix the name must be
+// lowercase letters, digits, '_' and '-', not starting with a digit or '-'.
+func CreateSystemUser(name, home, shell string, opts ...Option) (err error) {
+	if err := checkAccountName(name, "name"); err != nil {
+		return err
+	}
+	if _, err := LookupUser(name); err == nil {
+		return nil
+	}
+	defer endOperation(startOperation("create_user", map[string]interface{}{"name": name}), &err)
+
+	o := NewOptions(opts...)
+	ctx, cancel := o.Context(context.Background())
+	defer cancel()
+
+	switch runtime.GOOS {
+	case "windows":
+		_, err = o.Runner.Run(ctx, "", "net", "user", name, "/add", "/passwordreq:no")
+		return err
+	case "darwin":
+		return createDarwinUser(ctx, o, name, home, shell)
+	}
+	args := []string{"--system"}
+	if home != "" {
+		args = append(args, "--home-dir", home, "--create-home")
+	} else {
+		args = append(args, "--no-create-home")
+	}
+	if shell != "" {
+		args = append(args, "--shell", shell)
+	}
+	_, err = o.Runner.Run(ctx, "", "useradd", append(args, name)...)
+	return err
+}
+
+// createDarwinUser creates the user record with dscl, using the first free
+// UID of the system range (400-499).
+func createDarwinUser(ctx context.Context, o *Options, name, home, shell string) error {
+	out, err := o.Runner.Run(ctx, "", "dscl", ".", "-list", "/Users", "UniqueID")
+	if err != nil {
+		return err
+	}
+	used := make(map[int]bool)
+	for _, line := range strings.Split(string(out), "\n") {
+		if f := strings.Fields(line); len(f) == 2 {
+			if id, err := strconv.Atoi(f[1]); err == nil {
+				used[id] = true
+			}
+		}
+	}
+	uid := 400
+	for used[uid] {
+		uid++
+	}
+	if uid >= 500 {
+		return errors.New("no free system UID left")
+	}
+	if home == "" {
+		home = "/var/empty"
+	}
+	if shell == "" {
+		shell = "/usr/bin/false"
+	}
+
+	record := "/Users/" + name
+	for _, attr := range [][]string{
+		{},
+		{"UniqueID", strconv.Itoa(uid)},
+		{"PrimaryGroupID", "20"},
+		{"NFSHomeDirectory", home},
+		{"UserShell", shell},
+		{"RealName", name},
+		{"IsHidden", "1"},
+	} {
+		args := append([]string{".", "-create", record}, attr...)
+		if _, err := o.Runner.Run(ctx, "", "dscl", args...); err != nil {
+			return fmt.Errorf("create user %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// AddUserToGroup adds the user to the supplementary group.
+func AddUserToGroup(username, group string, opts ...Option) (err error) {
+	if err := checkAccountName(username, "username"); err != nil {
+		return err
+	}
+	if err := checkAccountName(group, "group"); err != nil {
+		return err
+	}
+	u, err := LookupUser(username)
+	if err != nil {
+		return err
+	}
+	g, err := LookupGroup(group)
+	if err != nil {
+		return err
+	}
+	if ids, err := u.GroupIds(); err == nil {
+		for _, id := range ids {
+			if id == g.Gid {
+				return nil
+			}
+		}
+	}
+	defer endOperation(startOperation("add_user_to_group", map[string]interface{}{"user": username, "group": group}), &err)
+
+	o := NewOptions(opts...)
+	ctx, cancel := o.Context(context.Background())
+	defer cancel()
+
+	switch runtime.GOOS {
+	case "windows":
+		_, err = o.Runner.Run(ctx, "", "net", "localgroup", group, username, "/add")
+	case "darwin":
+		_, err = o.Runner.Run(ctx, "", "dseditgroup", "-o", "edit", "-a", username, "-t", "user", group)
+	default:
+		_, err = o.Runner.Run(ctx, "", "usermod", "-a", "-G", group, username)
+	}
+	return err
+}
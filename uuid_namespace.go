@@ -0,0 +1,90 @@
+// utility/uuid_namespace.go
+package Utility
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/pborman/uuid"
+)
+
+// Deterministic UUID namespaces
+// -----------------------------
+// GenerateUUID hashes every value in NameSpace_DNS, so equal strings from
+// different entity domains (a user "admin" and a file "admin") share an ID.
+// Domains register their own namespace UUID and derive IDs with
+// GenerateUUIDIn; MigrateUUIDs maps IDs produced by GenerateUUID to the new
+// ones for data that must be rewritten.
+
+var (
+	uuidNamespacesMu sync.RWMutex
+	uuidNamespaces   = map[string]uuid.UUID{"dns": uuid.NameSpace_DNS}
+)
+
+// RegisterUUIDNamespace registers a namespace by name. Registering the same
+// name twice with a different UUID is an error, since it would change IDs.
+func RegisterUUIDNamespace(name, namespaceUUID string) error {
+	if err := RequireNonEmpty(name, "name"); err != nil {
+		return err
+	}
+	ns := uuid.Parse(namespaceUUID)
+	if ns == nil {
+		return &GuardError{Field: "uuid", Reason: "invalid UUID " + namespaceUUID}
+	}
+	uuidNamespacesMu.Lock()
+	defer uuidNamespacesMu.Unlock()
+	if cur, ok := uuidNamespaces[name]; ok && !uuid.Equal(cur, ns) {
+		return fmt.Errorf("uuid namespace %s is already registered as %s", name, cur)
+	}
+	uuidNamespaces[name] = ns
+	return nil
+}
+
+// UUIDNamespace returns the UUID of a registered namespace.
+func UUIDNamespace(name string) (string, bool) {
+	uuidNamespacesMu.RLock()
+	defer uuidNamespacesMu.RUnlock()
+	ns, ok := uuidNamespaces[name]
+	if !ok {
+		return "", false
+	}
+	return ns.String(), true
+}
+
+// UUIDNamespaces returns the registered namespace names ("dns" is built in).
+func UUIDNamespaces() []string {
+	uuidNamespacesMu.RLock()
+	defer uuidNamespacesMu.RUnlock()
+	names := make([]string, 0, len(uuidNamespaces))
+	for n := range uuidNamespaces {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GenerateUUIDIn creates the MD5 UUID of val in a registered namespace;
+// GenerateUUIDIn("dns", val) equals GenerateUUID(val).
+func GenerateUUIDIn(namespace, val string) (string, error) {
+	uuidNamespacesMu.RLock()
+	ns, ok := uuidNamespaces[namespace]
+	uuidNamespacesMu.RUnlock()
+	if !ok {
+		return "", &GuardError{Field: "namespace", Reason: "unknown UUID namespace " + namespace}
+	}
+	return uuid.NewMD5(ns, []byte(val)).String(), nil
+}
+
+// MigrateUUIDs maps the GenerateUUID ID of each value to its ID in namespace.
+func MigrateUUIDs(namespace string, values []string) (map[string]string, error) {
+	out := make(map[string]string, len(values))
+	for _, val := range values {
+		id, err := GenerateUUIDIn(namespace, val)
+		if err != nil {
+			return nil, err
+		}
+		out[GenerateUUID(val)] = id
+	}
+	return out, nil
+}
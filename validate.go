@@ -0,0 +1,260 @@
+// utility/validate.go
+package Utility
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Struct validation
+// -----------------
+// Validate checks `validate:"..."` tags, e.g.
+//
+//	Name  string `validate:"required,min=3,max=64"`
+//	Email string `validate:"email"`
+//	Role  string `validate:"oneof=admin user guest"`
+//
+// Rules other than "required" are skipped for zero values, so optional fields
+// only need to be valid when set. min/max/len compare the length of strings,
+// slices and maps and the value of numbers. Nested structs (direct, pointer,
+// slice or map elements) are validated recursively; `validate:"-"` skips a field.
+
+// ValidatorFunc checks a field value against a rule parameter (the text after
+// "="; empty when the rule has none) and returns a message-bearing error.
+type ValidatorFunc func(value reflect.Value, param string) error
+
+// ValidationError describes one failed rule.
+type ValidationError struct {
+	Field   string // path from the root, e.g. Address.Street or Items[2].Name
+	Rule    string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationErrors collects every failed rule of a Validate call.
+type ValidationErrors []ValidationError
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+var (
+	validatorsMu sync.RWMutex
+	validators   = map[string]ValidatorFunc{
+		"required":   validateRequired,
+		"min":        func(v reflect.Value, p string) error { return validateBound(v, p, "min") },
+		"max":        func(v reflect.Value, p string) error { return validateBound(v, p, "max") },
+		"len":        func(v reflect.Value, p string) error { return validateBound(v, p, "len") },
+		"oneof":      validateOneOf,
+		"email":      stringValidator(IsEmail, "must be a valid email address"),
+		"phone":      stringValidator(IsPhoneNumber, "must be a valid phone number"),
+		"uuid":       stringValidator(IsUuid, "must be a valid UUID"),
+		"creditcard": stringValidator(IsCreditCardNumber, "must be a valid credit card number"),
+		"base64":     stringValidator(IsStdBase64, "must be standard base64"),
+		"varname":    stringValidator(IsValidVariableName, "must be a valid variable name"),
+	}
+)
+
+// RegisterValidator adds (or replaces) a rule usable in validate tags.
+func RegisterValidator(name string, fn ValidatorFunc) {
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+	validators[name] = fn
+}
+
+func getValidator(name string) (ValidatorFunc, bool) {
+	validatorsMu.RLock()
+	defer validatorsMu.RUnlock()
+	fn, ok := validators[name]
+	return fn, ok
+}
+
+// Validate checks instance (a struct or pointer to struct) against its
+// validate tags and returns ValidationErrors listing every failure, or nil.
+func Validate(instance interface{}) error {
+	v := reflect.ValueOf(instance)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return errors.New("Validate: nil instance")
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("Validate: expected a struct, got %v", v.Kind())
+	}
+
+	var errs ValidationErrors
+	validateStruct(v, "", &errs, make(map[uintptr]bool))
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// MakeValidatedInstance is MakeInstance followed by Validate. Nested
+// entities are passed to setEntity as they are built, the instance itself
+// only once it is valid.
+func MakeValidatedInstance(typeName string, data map[string]interface{}, setEntity func(interface{})) (reflect.Value, error) {
+	if _, ok := DefaultTypeManager().GetType(typeName); !ok {
+		return reflect.Value{}, errors.New("no type was registered with name " + typeName)
+	}
+	value := initializeStructureValue(newInitState(conventions(), InitOptions{}), typeName, data, setEntity)
+	if err := Validate(value.Interface()); err != nil {
+		return value, err
+	}
+	if setEntity != nil {
+		setEntity(value.Interface())
+	}
+	return value, nil
+}
+
+func validateStruct(v reflect.Value, prefix string, errs *ValidationErrors, seen map[uintptr]bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		tag := sf.Tag.Get("validate")
+		if tag == "-" {
+			continue
+		}
+		path := sf.Name
+		if prefix != "" {
+			path = prefix + "." + sf.Name
+		}
+		fv := v.Field(i)
+
+		if tag != "" {
+			for _, rule := range strings.Split(tag, ",") {
+				rule = strings.TrimSpace(rule)
+				if rule == "" {
+					continue
+				}
+				name, param, _ := strings.Cut(rule, "=")
+				if name != "required" && fv.IsZero() {
+					continue
+				}
+				fn, ok := getValidator(name)
+				if !ok {
+					*errs = append(*errs, ValidationError{Field: path, Rule: name, Message: "unknown validation rule " + name})
+					continue
+				}
+				if err := fn(fv, param); err != nil {
+					*errs = append(*errs, ValidationError{Field: path, Rule: name, Message: err.Error()})
+				}
+			}
+		}
+		validateNested(fv, path, errs, seen)
+	}
+}
+
+// validateNested descends into struct values reachable from v.
+func validateNested(v reflect.Value, path string, errs *ValidationErrors, seen map[uintptr]bool) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || seen[v.Pointer()] {
+			return
+		}
+		seen[v.Pointer()] = true
+		validateNested(v.Elem(), path, errs, seen)
+	case reflect.Interface:
+		if !v.IsNil() {
+			validateNested(v.Elem(), path, errs, seen)
+		}
+	case reflect.Struct:
+		if v.Type() != timeType {
+			validateStruct(v, path, errs, seen)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateNested(v.Index(i), fmt.Sprintf("%s[%d]", path, i), errs, seen)
+		}
+	case reflect.Map:
+		for _, k := range sortedMapKeys(v) {
+			validateNested(v.MapIndex(k), fmt.Sprintf("%s[%v]", path, k.Interface()), errs, seen)
+		}
+	}
+}
+
+// ---------------------------
+// Built-in rules
+// ---------------------------
+
+func validateRequired(v reflect.Value, _ string) error {
+	if v.IsZero() {
+		return errors.New("is required")
+	}
+	if (v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.Len() == 0 {
+		return errors.New("is required")
+	}
+	return nil
+}
+
+func validateBound(v reflect.Value, param, rule string) error {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s parameter %q", rule, param)
+	}
+
+	var n float64
+	what := "value"
+	switch v.Kind() {
+	case reflect.String:
+		n, what = float64(len([]rune(v.String()))), "length"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		n, what = float64(v.Len()), "length"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = v.Float()
+	default:
+		return fmt.Errorf("%s does not apply to %v", rule, v.Kind())
+	}
+
+	switch {
+	case rule == "min" && n < limit:
+		return fmt.Errorf("%s must be at least %s", what, param)
+	case rule == "max" && n > limit:
+		return fmt.Errorf("%s must be at most %s", what, param)
+	case rule == "len" && n != limit:
+		return fmt.Errorf("%s must be exactly %s", what, param)
+	}
+	return nil
+}
+
+func validateOneOf(v reflect.Value, param string) error {
+	s := fmt.Sprint(v.Interface())
+	for _, opt := range strings.Fields(param) {
+		if s == opt {
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of [%s]", param)
+}
+
+// stringValidator adapts one of the Is* helpers to a rule.
+func stringValidator(check func(string) bool, msg string) ValidatorFunc {
+	return func(v reflect.Value, _ string) error {
+		if v.Kind() != reflect.String {
+			return errors.New("rule only applies to strings")
+		}
+		if !check(v.String()) {
+			return errors.New(msg)
+		}
+		return nil
+	}
+}
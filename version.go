@@ -2,6 +2,7 @@
 package Utility
 
 import (
+	"errors"
 	"strings"
 )
 
@@ -77,3 +78,71 @@ func (v *Version) Compare(to *Version) int {
 	return 0
 }
 
+
+// SatisfiesConstraint checks v against a constraint made of comma-separated
+// comparisons (">=1.2.0, <2.0.0", "=1.4.2", "!=1.3.0"), a caret ("^1.2.0":
+// same major) or tilde ("~1.2.0": same major and minor) range, or "*".
+// Missing minor/patch numbers are read as zero ("<2" is "<2.0.0").
+func (v *Version) SatisfiesConstraint(constraint string) (bool, error) {
+	for _, part := range strings.Split(constraint, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" || part == "*" {
+			continue
+		}
+
+		op := ""
+		for _, candidate := range []string{">=", "<=", "!=", ">", "<", "=", "^", "~"} {
+			if strings.HasPrefix(part, candidate) {
+				op = candidate
+				break
+			}
+		}
+		bound, err := parseConstraintVersion(strings.TrimSpace(part[len(op):]))
+		if err != nil {
+			return false, err
+		}
+
+		cmp := v.Compare(bound)
+		ok := false
+		switch op {
+		case "", "=":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		case "^":
+			ok = cmp >= 0 && v.Major == bound.Major
+		case "~":
+			ok = cmp >= 0 && v.Major == bound.Major && v.Minor == bound.Minor
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// parseConstraintVersion parses "1", "1.2" or "v1.2.3" into a Version.
+func parseConstraintVersion(str string) (*Version, error) {
+	str = strings.TrimPrefix(str, "v")
+	values := strings.Split(str, ".")
+	if str == "" || len(values) > 3 {
+		return nil, errors.New("invalid version in constraint: " + str)
+	}
+	for len(values) < 3 {
+		values = append(values, "0")
+	}
+	for _, n := range values[:2] {
+		if _, err := ToIntE(n); err != nil {
+			return nil, errors.New("invalid version in constraint: " + str)
+		}
+	}
+	return NewVersion(strings.Join(values, ".")), nil
+}
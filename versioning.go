@@ -0,0 +1,177 @@
+// utility/versioning.go
+package Utility
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Versioned types and migrations
+// ------------------------------
+// A type registered with RegisterTypeVersion carries a current version.
+// Serialized entities record theirs in an integer TYPEVERSION field/key; when
+// an older entity is loaded (InitializeStructure, MakeInstance, FromBytes)
+// the registered migrations are chained, as maps, up to the current version.
+// Entities without TYPEVERSION are loaded as they are.
+//
+// Gob payloads are first decoded into the current struct, so a migration run
+// by FromBytes only sees fields that still exist; renames need the map path.
+
+// Migration rewrites the map form of an entity from one version to the next.
+type Migration func(map[string]interface{}) map[string]interface{}
+
+type typeMigration struct {
+	to int
+	fn Migration
+}
+
+// RegisterTypeVersion registers a type under name (and with gob) as being at
+// version. typedNil is a typed nil pointer to a struct, e.g. (*User)(nil).
+func (tm *TypeManager) RegisterTypeVersion(name string, version int, typedNil interface{}) error {
+	if err := RequireNonEmpty(name, "name"); err != nil {
+		return err
+	}
+	if typedNil == nil {
+		return &GuardError{Field: "typedNil", Reason: "must not be nil"}
+	}
+	if t := reflect.TypeOf(typedNil); t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return &GuardError{Field: "typedNil", Reason: "must be a pointer to a struct, got " + t.String()}
+	}
+	if err := registerGobName(tm.QualifiedName(name), typedNil); err != nil {
+		return err
+	}
+	tm.RegisterType(name, reflect.TypeOf(typedNil).Elem())
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if tm.typeVersions == nil {
+		tm.typeVersions = make(map[string]int)
+	}
+	tm.typeVersions[name] = version
+	return nil
+}
+
+// RegisterMigration registers the step migrating name from fromVer to toVer.
+func (tm *TypeManager) RegisterMigration(name string, fromVer, toVer int, fn Migration) error {
+	if toVer <= fromVer {
+		return &GuardError{Field: "toVer", Reason: fmt.Sprintf("must be greater than %d", fromVer)}
+	}
+	if err := RequireNonNil(fn, "fn"); err != nil {
+		return err
+	}
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if tm.migrations == nil {
+		tm.migrations = make(map[string]map[int]typeMigration)
+	}
+	if tm.migrations[name] == nil {
+		tm.migrations[name] = make(map[int]typeMigration)
+	}
+	tm.migrations[name][fromVer] = typeMigration{to: toVer, fn: fn}
+	return nil
+}
+
+// TypeVersion returns the current version of a versioned type.
+func (tm *TypeManager) TypeVersion(name string) (int, bool) {
+	owner, local := tm.versionOwner(name)
+	if owner == nil {
+		return 0, false
+	}
+	owner.mu.RLock()
+	defer owner.mu.RUnlock()
+	v, ok := owner.typeVersions[local]
+	return v, ok
+}
+
+// versionOwner returns the manager holding the version of name (following
+// namespaces and parents as GetType does) and the name local to it.
+func (tm *TypeManager) versionOwner(name string) (*TypeManager, string) {
+	if ns, rest, qualified := strings.Cut(name, ":"); qualified {
+		if child := tm.findNamespace(ns); child != nil {
+			return child.versionOwner(rest)
+		}
+		return nil, ""
+	}
+	tm.mu.RLock()
+	_, ok := tm.typeVersions[name]
+	parent := tm.parent
+	tm.mu.RUnlock()
+	if !ok && parent != nil {
+		return parent.versionOwner(name)
+	}
+	return tm, name
+}
+
+// migrate brings the map form of an entity up to the current version of
+// name. It returns data itself when no migration is needed, otherwise a
+// migrated copy with TYPEVERSION updated.
+func (tm *TypeManager) migrate(name string, data map[string]interface{}) (map[string]interface{}, error) {
+	versionKey := tm.conventionsOf(name).VersionKey
+	raw, has := data[versionKey]
+	if !has || raw == nil {
+		return data, nil
+	}
+	current, ok := tm.TypeVersion(name)
+	if !ok {
+		return data, nil
+	}
+	version := ToInt(raw)
+	if version >= current {
+		return data, nil
+	}
+
+	owner, local := tm.versionOwner(name)
+	out := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		out[k] = v
+	}
+	for version < current {
+		owner.mu.RLock()
+		step, ok := owner.migrations[local][version]
+		owner.mu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("no migration registered for %s from version %d", name, version)
+		}
+		if out = step.fn(out); out == nil {
+			return nil, fmt.Errorf("migration of %s from version %d returned nil", name, version)
+		}
+		version = step.to
+	}
+	out[versionKey] = version
+	return out, nil
+}
+
+// migrateValue migrates a decoded *T whose TYPEVERSION field is out of date
+// and returns the rebuilt value, or v unchanged.
+func (tm *TypeManager) migrateValue(name string, v interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return v, nil
+	}
+	f := structField(rv.Elem(), tm.conventionsOf(name).VersionKey)
+	if !f.IsValid() || !f.CanInt() {
+		return v, nil
+	}
+	if current, ok := tm.TypeVersion(name); !ok || int(f.Int()) >= current {
+		return v, nil
+	}
+
+	m, err := StructToMap(v, MapperOptions{IncludeZero: true, KeepNumericKinds: true})
+	if err != nil {
+		return v, err
+	}
+	if m, err = tm.migrate(name, m); err != nil {
+		return v, err
+	}
+	return newStructFromMap(newInitState(tm.conventionsOf(name), InitOptions{}), rv.Elem().Type(), m, nil).Interface(), nil
+}
+
+// RegisterTypeVersion registers a versioned type in the default TypeManager.
+func RegisterTypeVersion(name string, version int, typedNil interface{}) error {
+	return DefaultTypeManager().RegisterTypeVersion(name, version, typedNil)
+}
+
+// RegisterMigration registers a migration step in the default TypeManager.
+func RegisterMigration(name string, fromVer, toVer int, fn Migration) error {
+	return DefaultTypeManager().RegisterMigration(name, fromVer, toVer, fn)
+}
@@ -0,0 +1,233 @@
+// utility/walk.go
+package Utility
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Tree walking
+// ------------
+// Glob finds files with shell-like patterns, "**" and brace alternatives
+// included:
+//
+//	files, err := Glob("src/**/*.{go,proto}", "vendor", "*_test.go")
+//
+// Walk lists a tree through a filter function and can read directories
+// from several goroutines, which pays off on big trees and network disks.
+
+// WalkOptions configures Walk.
+type WalkOptions struct {
+	// Filter selects the entries returned; nil returns them all. With
+	// Concurrency above 1 it is called from several goroutines.
+	Filter func(path string, d os.DirEntry) bool
+	// Exclude lists globs of entries left out, directories with their
+	// content. Patterns without '/' are matched against the base name, the
+	// others against the slash-separated path relative to the root.
+	Exclude []string
+	// Concurrency is the number of directories read at once; 0 or 1 walks
+	// from the calling goroutine only.
+	Concurrency int
+}
+
+// Walk returns the paths of the entries under root (root excluded) kept by
+// opts, sorted. Symbolic links are listed, not followed.
+func Walk(root string, opts WalkOptions) (paths []string, err error) {
+	defer endOperation(startOperation("fs.walk", map[string]interface{}{"root": root}), &err)
+	exclude, err := compileGlobs(opts.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	var mu sync.Mutex
+	err = walkDirConcurrent(root, opts.Concurrency-1, func(p, rel string, e os.DirEntry) error {
+		if matchGlobs(exclude, rel) {
+			if e.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if opts.Filter == nil || opts.Filter(p, e) {
+			mu.Lock()
+			paths = append(paths, p)
+			mu.Unlock()
+		}
+		return nil
+	})
+	sort.Strings(paths)
+	return paths, err
+}
+
+// Glob returns the paths matching pattern, sorted. Patterns are '/'
+// separated and use the syntax of CompileGlob, "**" crossing directories,
+// plus brace alternatives ("*.{jpg,png}", see ExpandBraces). Entries
+// matching one of the exclude globs are left out, directories with their
+// content; exclude patterns without '/' are matched against base names.
+// Unlike filepath.Glob, names starting with '.' are matched like any other.
+func Glob(pattern string, exclude ...string) (matches []string, err error) {
+	defer endOperation(startOperation("fs.glob", map[string]interface{}{"pattern": pattern}), &err)
+	ex, err := compileGlobs(exclude)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]bool)
+	for _, alt := range ExpandBraces(filepath.ToSlash(pattern)) {
+		if err := globOne(alt, ex, found); err != nil {
+			return nil, err
+		}
+	}
+	matches = make([]string, 0, len(found))
+	for p := range found {
+		matches = append(matches, p)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// globOne adds the matches of a brace-free pattern to found. The pattern is
+// cleaned first, so "./*.go" matches as "*.go" does. The walk starts at the
+// longest leading run of literal segments and, without "**", goes no deeper
+// than the pattern.
+func globOne(pattern string, exclude []*GlobMatcher, found map[string]bool) error {
+	pattern = path.Clean(pattern) // keeps a leading "/"
+	segs := strings.Split(pattern, "/")
+	n := 0
+	for n < len(segs) && !strings.ContainsAny(segs[n], `*?[\`) {
+		n++
+	}
+	root := strings.Join(segs[:n], "/")
+	switch {
+	case n == len(segs):
+		if _, err := os.Lstat(filepath.FromSlash(root)); err == nil && !matchGlobs(exclude, root) {
+			found[filepath.FromSlash(root)] = true
+		}
+		return nil
+	case root == "" && n > 0:
+		root = "/"
+	case root == "":
+		root = "."
+	}
+	g, err := CompileGlob(pattern)
+	if err != nil {
+		return err
+	}
+	maxDepth := -1
+	if !strings.Contains(pattern, "**") {
+		maxDepth = len(segs) - n
+	}
+
+	err = walkDirConcurrent(filepath.FromSlash(root), 0, func(p, rel string, e os.DirEntry) error {
+		full := rel // in the form of the pattern, not cleaned
+		switch root {
+		case ".":
+		case "/":
+			full = "/" + rel
+		default:
+			full = root + "/" + rel
+		}
+		if matchGlobs(exclude, full) {
+			if e.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if g.Match(full) {
+			found[p] = true
+		}
+		if e.IsDir() && maxDepth >= 0 && strings.Count(rel, "/")+1 >= maxDepth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// walkDirConcurrent calls visit for every entry under root; rel is the
+// slash-separated path relative to root. Returning filepath.SkipDir for a
+// directory skips its content. Up to workers goroutines besides the caller
+// read directories, so visit must be safe for concurrent use when workers
+// is positive. Entries removed during the walk are ignored. The first error
+// stops the walk and is returned.
+func walkDirConcurrent(root string, workers int, visit func(p, rel string, e os.DirEntry) error) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return &GuardError{Field: "path", Reason: "not a directory: " + root}
+	}
+	if workers < 0 {
+		workers = 0
+	}
+	w := &concurrentWalker{visit: visit, sem: make(chan struct{}, workers)}
+	w.dir(root, "")
+	w.wg.Wait()
+	return w.err
+}
+
+type concurrentWalker struct {
+	visit  func(p, rel string, e os.DirEntry) error
+	sem    chan struct{} // bounds the extra goroutines
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	err    error
+	failed atomic.Bool
+}
+
+func (w *concurrentWalker) fail(err error) {
+	if os.IsNotExist(err) {
+		return
+	}
+	w.mu.Lock()
+	if w.err == nil {
+		w.err = err
+	}
+	w.mu.Unlock()
+	w.failed.Store(true)
+}
+
+// dir walks the directory p, handing subdirectories to new goroutines while
+// there is room and walking them itself otherwise.
+func (w *concurrentWalker) dir(p, rel string) {
+	entries, err := os.ReadDir(p)
+	if err != nil {
+		w.fail(err)
+		return
+	}
+	for _, e := range entries {
+		if w.failed.Load() {
+			return
+		}
+		sub, subRel := filepath.Join(p, e.Name()), path.Join(rel, e.Name())
+		err := w.visit(sub, subRel, e)
+		switch {
+		case err == filepath.SkipDir && e.IsDir():
+			continue
+		case err != nil && err != filepath.SkipDir:
+			w.fail(err)
+			return
+		case !e.IsDir():
+			continue
+		}
+		select {
+		case w.sem <- struct{}{}:
+			w.wg.Add(1)
+			go func() {
+				defer func() {
+					<-w.sem
+					w.wg.Done()
+				}()
+				w.dir(sub, subRel)
+			}()
+		default:
+			w.dir(sub, subRel)
+		}
+	}
+}
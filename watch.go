@@ -0,0 +1,442 @@
+// utility/watch.go
+package Utility
+
+import (
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Directory watching
+// ------------------
+// WatchDir reports changes below a directory as they happen instead of
+// polling it. Bursts of events on the same path are coalesced (a file being
+// written produces one event once it has been quiet for the debounce delay),
+// a rename followed by the matching create is reported as one FileRename,
+// and directories created under a recursive watch are watched in turn:
+//
+//	events := make(chan FileEvent, 64)
+//	w, err := WatchDir("/var/media", true, events)
+//	...
+//	for ev := range events { ... }
+//	w.Close()
+
+// FileOp is the kind of change a FileEvent reports.
+type FileOp int
+
+const (
+	FileCreate FileOp = iota + 1
+	FileWrite
+	FileRemove
+	FileRename
+	FileChmod
+)
+
+func (op FileOp) String() string {
+	switch op {
+	case FileCreate:
+		return "create"
+	case FileWrite:
+		return "write"
+	case FileRemove:
+		return "remove"
+	case FileRename:
+		return "rename"
+	case FileChmod:
+		return "chmod"
+	}
+	return "unknown"
+}
+
+// FileEvent is a change below a watched directory.
+type FileEvent struct {
+	Path    string
+	OldPath string // FileRename only: the previous path
+	Op      FileOp
+	IsDir   bool
+	Time    time.Time // last raw event coalesced into this one
+}
+
+// WatchOptions configures WatchDirWithOptions. Patterns without a '/' match
+// the base name, others the slash-separated path relative to the watched
+// directory (see CompileGlob).
+type WatchOptions struct {
+	Debounce   time.Duration // quiet delay before an event is sent; default 100ms
+	Extensions []string      // e.g. ".mp4"; only files with one of them are reported
+	Patterns   []string      // only paths matching one of them are reported
+	Ignore     []string      // paths to leave out; ignored directories aren't watched
+	OnError    func(error)   // default logs
+}
+
+// DirWatcher is a running WatchDir.
+type DirWatcher struct {
+	root      string
+	recursive bool
+	opts      WatchOptions
+	events    chan<- FileEvent
+	fsw       *fsnotify.Watcher
+	include   []*GlobMatcher
+	exclude   []*GlobMatcher
+
+	// owned by the run goroutine
+	dirs    map[string]bool
+	pending map[string]*pendingFileEvent
+	renames []*pendingFileEvent
+	seq     uint64
+
+	closeOnce sync.Once
+	done      chan struct{}
+	stopped   chan struct{}
+}
+
+type pendingFileEvent struct {
+	ev  FileEvent
+	due time.Time
+	seq uint64 // arrival order, for events due at the same time
+}
+
+// WatchDir sends the changes below path to events until Close is called;
+// with recursive, subdirectories are watched too. events is not closed.
+func WatchDir(path string, recursive bool, events chan FileEvent) (*DirWatcher, error) {
+	return WatchDirWithOptions(path, recursive, events, WatchOptions{})
+}
+
+// WatchDirWithOptions is WatchDir with debouncing and filtering options.
+func WatchDirWithOptions(path string, recursive bool, events chan FileEvent, opts WatchOptions) (*DirWatcher, error) {
+	if err := RequireNonNil(events, "events"); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, &GuardError{Field: "path", Reason: "not a directory: " + path}
+	}
+	if opts.Debounce <= 0 {
+		opts.Debounce = 100 * time.Millisecond
+	}
+	if opts.OnError == nil {
+		opts.OnError = func(err error) { log.Println("WatchDir:", err) }
+	}
+
+	w := &DirWatcher{
+		root:      filepath.Clean(path),
+		recursive: recursive,
+		opts:      opts,
+		events:    events,
+		dirs:      make(map[string]bool),
+		pending:   make(map[string]*pendingFileEvent),
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	if w.include, err = compileGlobs(opts.Patterns); err != nil {
+		return nil, err
+	}
+	if w.exclude, err = compileGlobs(opts.Ignore); err != nil {
+		return nil, err
+	}
+	if w.fsw, err = fsnotify.NewWatcher(); err != nil {
+		return nil, err
+	}
+	if err := w.fsw.Add(w.root); err != nil {
+		w.fsw.Close()
+		return nil, err
+	}
+	w.dirs[w.root] = true
+	if recursive {
+		w.addTree(w.root, false)
+	}
+	go w.run()
+	return w, nil
+}
+
+// Close stops the watcher. Pending events are dropped.
+func (w *DirWatcher) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		close(w.done)
+		err = w.fsw.Close()
+		<-w.stopped
+	})
+	return err
+}
+
+func (w *DirWatcher) run() {
+	defer close(w.stopped)
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	for {
+		if due, ok := w.nextDue(); ok {
+			timer.Reset(time.Until(due))
+		} else {
+			timer.Stop()
+		}
+		select {
+		case <-w.done:
+			return
+		case ev, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			w.handle(ev)
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			w.opts.OnError(err)
+		case <-timer.C:
+		}
+		if !w.flush(time.Now()) {
+			return
+		}
+	}
+}
+
+// handle turns a raw event into pending events.
+func (w *DirWatcher) handle(raw fsnotify.Event) {
+	name, now := raw.Name, time.Now()
+	switch {
+	case raw.Has(fsnotify.Create):
+		info, err := os.Lstat(name)
+		isDir := err == nil && info.IsDir()
+		ev := FileEvent{Path: name, Op: FileCreate, IsDir: isDir, Time: now}
+		renamed := w.takeRename(name, isDir)
+		if renamed != nil && renamed.ev.Op != FileCreate {
+			ev.Op, ev.OldPath = FileRename, renamed.ev.Path
+		}
+		if isDir && w.recursive && !w.ignored(name) {
+			w.addTree(name, renamed == nil) // a moved directory's entries aren't new
+		}
+		w.queue(ev)
+	case raw.Has(fsnotify.Rename):
+		// Paired with the create of the new name, if it comes; a file created
+		// and renamed within the debounce delay stays a creation.
+		w.seq++
+		r := &pendingFileEvent{
+			ev:  FileEvent{Path: name, Op: FileRemove, IsDir: w.dirs[name], Time: now},
+			due: now.Add(w.opts.Debounce),
+			seq: w.seq,
+		}
+		if p, ok := w.pending[name]; ok {
+			if p.ev.Op == FileCreate {
+				r.ev.Op = FileCreate
+			}
+			delete(w.pending, name)
+		}
+		w.renames = append(w.renames, r)
+		w.forgetDir(name)
+	case raw.Has(fsnotify.Remove):
+		isDir := w.dirs[name]
+		w.forgetDir(name)
+		w.queue(FileEvent{Path: name, Op: FileRemove, IsDir: isDir, Time: now})
+	case raw.Has(fsnotify.Write):
+		w.queue(FileEvent{Path: name, Op: FileWrite, Time: now})
+	case raw.Has(fsnotify.Chmod):
+		w.queue(FileEvent{Path: name, Op: FileChmod, Time: now})
+	}
+}
+
+// queue coalesces ev with the pending event of the same path.
+func (w *DirWatcher) queue(ev FileEvent) {
+	due := ev.Time.Add(w.opts.Debounce)
+	p, ok := w.pending[ev.Path]
+	if !ok {
+		w.seq++
+		w.pending[ev.Path] = &pendingFileEvent{ev: ev, due: due, seq: w.seq}
+		return
+	}
+	prev := p.ev.Op
+	switch {
+	case prev == FileCreate && ev.Op == FileRemove:
+		delete(w.pending, ev.Path) // came and went
+		return
+	case (prev == FileCreate || prev == FileRename) && ev.Op != FileRemove && ev.Op != FileRename:
+		// still a new file, whatever happened to it since
+	case ev.Op == FileChmod && prev == FileWrite:
+	default:
+		p.ev.Op, p.ev.OldPath, p.ev.IsDir = ev.Op, ev.OldPath, ev.IsDir
+	}
+	p.ev.Time, p.due = ev.Time, due
+}
+
+// takeRename removes and returns the pending rename that created name,
+// preferring one with the same base name (a move) over one in the same
+// directory (a rename); a lone pending rename always matches.
+func (w *DirWatcher) takeRename(name string, isDir bool) *pendingFileEvent {
+	best := -1
+	for i, r := range w.renames {
+		if r.ev.IsDir != isDir {
+			continue
+		}
+		if filepath.Base(r.ev.Path) == filepath.Base(name) {
+			best = i
+			break
+		}
+		if best < 0 && (filepath.Dir(r.ev.Path) == filepath.Dir(name) || len(w.renames) == 1) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+	r := w.renames[best]
+	w.renames = append(w.renames[:best], w.renames[best+1:]...)
+	return r
+}
+
+// addTree watches dir and the directories below it; with emit, their
+// entries are reported as created (they may predate the watch).
+func (w *DirWatcher) addTree(dir string, emit bool) {
+	now := time.Now()
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if path != dir && w.ignored(path) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if emit && path != dir {
+			w.queue(FileEvent{Path: path, Op: FileCreate, IsDir: d.IsDir(), Time: now})
+		}
+		if d.IsDir() && !w.dirs[path] {
+			if err := w.fsw.Add(path); err != nil {
+				w.opts.OnError(err)
+				return filepath.SkipDir
+			}
+			w.dirs[path] = true
+		}
+		return nil
+	})
+}
+
+// forgetDir stops watching dir and the directories below it.
+func (w *DirWatcher) forgetDir(dir string) {
+	if !w.dirs[dir] {
+		return
+	}
+	prefix := dir + string(filepath.Separator)
+	for d := range w.dirs {
+		if d == dir || strings.HasPrefix(d, prefix) {
+			w.fsw.Remove(d) // already gone when the directory was removed
+			delete(w.dirs, d)
+		}
+	}
+}
+
+func (w *DirWatcher) nextDue() (time.Time, bool) {
+	var next time.Time
+	for _, p := range w.pending {
+		if next.IsZero() || p.due.Before(next) {
+			next = p.due
+		}
+	}
+	for _, r := range w.renames {
+		if next.IsZero() || r.due.Before(next) {
+			next = r.due
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// flush sends the events that have been quiet long enough, oldest first.
+// Renames left unpaired moved out of the tree and are sent as removals
+// (nothing at all for files that were new).
+func (w *DirWatcher) flush(now time.Time) bool {
+	var ready []*pendingFileEvent
+	for path, p := range w.pending {
+		if !p.due.After(now) {
+			ready = append(ready, p)
+			delete(w.pending, path)
+		}
+	}
+	kept := w.renames[:0]
+	for _, r := range w.renames {
+		switch {
+		case r.due.After(now):
+			kept = append(kept, r)
+		case r.ev.Op == FileRemove:
+			ready = append(ready, r)
+		}
+	}
+	w.renames = kept
+	sort.Slice(ready, func(i, j int) bool {
+		if !ready[i].due.Equal(ready[j].due) {
+			return ready[i].due.Before(ready[j].due)
+		}
+		return ready[i].seq < ready[j].seq
+	})
+
+	for _, p := range ready {
+		if !w.match(p.ev) {
+			continue
+		}
+		select {
+		case w.events <- p.ev:
+		case <-w.done:
+			return false
+		}
+	}
+	return true
+}
+
+// match applies the filters of the options to ev.
+func (w *DirWatcher) match(ev FileEvent) bool {
+	if w.matchPath(ev.Path, ev.IsDir) {
+		return true
+	}
+	return ev.OldPath != "" && w.matchPath(ev.OldPath, ev.IsDir)
+}
+
+func (w *DirWatcher) matchPath(path string, isDir bool) bool {
+	if w.ignored(path) {
+		return false
+	}
+	if len(w.opts.Extensions) > 0 {
+		if isDir {
+			return false
+		}
+		ext, found := filepath.Ext(path), false
+		for _, e := range w.opts.Extensions {
+			if strings.EqualFold(ext, "."+strings.TrimPrefix(e, ".")) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return len(w.include) == 0 || w.globMatch(w.include, path)
+}
+
+// ignored reports whether path, or a directory above it, matches Ignore.
+func (w *DirWatcher) ignored(path string) bool {
+	if len(w.exclude) == 0 {
+		return false
+	}
+	for p := path; len(p) > len(w.root); p = filepath.Dir(p) {
+		if w.globMatch(w.exclude, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *DirWatcher) globMatch(globs []*GlobMatcher, path string) bool {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		return false
+	}
+	return matchGlobs(globs, filepath.ToSlash(rel))
+}
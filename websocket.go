@@ -0,0 +1,281 @@
+// utility/websocket.go
+package Utility
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebSocket upgrade and hub helper
+// --------------------------------
+// WSHub keeps track of upgraded connections, gives each one a bounded send
+// queue drained by a dedicated writer goroutine, and runs ping/pong keepalive.
+// Broadcast never blocks: a connection whose queue is full is considered too
+// slow and is dropped. SendTo waits up to WriteTimeout for room in the queue.
+
+// ErrWSQueueFull is returned by SendTo when the connection's send queue stays full.
+var ErrWSQueueFull = errors.New("websocket send queue is full")
+
+// ErrWSNotFound is returned by SendTo for an unknown connection id.
+var ErrWSNotFound = errors.New("websocket connection not found")
+
+// WSConn is a connection registered with a WSHub.
+type WSConn struct {
+	ID   string
+	conn *websocket.Conn
+	hub  *WSHub
+	send chan []byte
+	done chan struct{}
+	once sync.Once
+}
+
+// Send queues msg for this connection (see WSHub.SendTo).
+func (c *WSConn) Send(msg []byte) error {
+	select {
+	case <-c.done:
+		return websocket.ErrCloseSent
+	default:
+	}
+	timer := time.NewTimer(c.hub.WriteTimeout)
+	defer timer.Stop()
+	select {
+	case c.send <- msg:
+		return nil
+	case <-c.done:
+		return websocket.ErrCloseSent
+	case <-timer.C:
+		return ErrWSQueueFull
+	}
+}
+
+// Close unregisters the connection; its writer sends a close frame and
+// releases the underlying socket.
+func (c *WSConn) Close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.hub.unregister(c)
+	})
+}
+
+// RemoteAddr returns the peer address.
+func (c *WSConn) RemoteAddr() string {
+	return c.conn.RemoteAddr().String()
+}
+
+// WSHub manages a set of websocket connections. The zero value is ready to
+// use: settings left at zero get the defaults of NewWSHub (but
+// MaxMessageSize, which stays unlimited) when the first connection is
+// registered.
+type WSHub struct {
+	// Upgrader used by Upgrade/ServeHTTP; adjust CheckOrigin as needed.
+	Upgrader websocket.Upgrader
+	// SendQueue is the per-connection queue length.
+	SendQueue int
+	// PingInterval between keepalive pings; PongWait must be larger.
+	PingInterval time.Duration
+	// PongWait is how long a connection may stay silent before being dropped.
+	PongWait time.Duration
+	// WriteTimeout bounds each write and SendTo's wait for queue room.
+	WriteTimeout time.Duration
+	// MaxMessageSize limits inbound messages (0 means unlimited).
+	MaxMessageSize int64
+
+	// OnMessage, if set, receives every inbound message.
+	OnMessage func(c *WSConn, msg []byte)
+	// OnClose, if set, is called after a connection is unregistered.
+	OnClose func(c *WSConn)
+
+	mu       sync.RWMutex
+	conns    map[string]*WSConn
+	initOnce sync.Once
+}
+
+// NewWSHub creates a hub with sensible keepalive defaults.
+func NewWSHub() *WSHub {
+	return &WSHub{
+		Upgrader:       websocket.Upgrader{ReadBufferSize: 4096, WriteBufferSize: 4096},
+		SendQueue:      256,
+		PingInterval:   30 * time.Second,
+		PongWait:       60 * time.Second,
+		WriteTimeout:   10 * time.Second,
+		MaxMessageSize: 1 << 20,
+	}
+}
+
+// init fills the zero settings with the defaults and creates the
+// connection map; Register calls it before using the hub.
+func (h *WSHub) init() {
+	h.initOnce.Do(func() {
+		if h.SendQueue <= 0 {
+			h.SendQueue = 256
+		}
+		if h.PingInterval <= 0 {
+			h.PingInterval = 30 * time.Second
+		}
+		if h.PongWait <= 0 {
+			h.PongWait = 2 * h.PingInterval
+		}
+		if h.WriteTimeout <= 0 {
+			h.WriteTimeout = 10 * time.Second
+		}
+		h.mu.Lock()
+		if h.conns == nil {
+			h.conns = make(map[string]*WSConn)
+		}
+		h.mu.Unlock()
+	})
+}
+
+// ServeHTTP upgrades the request and registers the connection.
+func (h *WSHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.Upgrade(w, r) // errors are already reported to the client by the upgrader
+}
+
+// Upgrade upgrades an HTTP request to a websocket and registers it.
+func (h *WSHub) Upgrade(w http.ResponseWriter, r *http.Request) (*WSConn, error) {
+	conn, err := h.Upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, err
+	}
+	return h.Register(conn), nil
+}
+
+// Register adds an already established connection to the hub and starts its
+// reader and writer goroutines.
+func (h *WSHub) Register(conn *websocket.Conn) *WSConn {
+	h.init()
+	c := &WSConn{
+		ID:   RandomUUID(),
+		conn: conn,
+		hub:  h,
+		send: make(chan []byte, h.SendQueue),
+		done: make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	h.conns[c.ID] = c
+	h.mu.Unlock()
+
+	go h.writePump(c)
+	go h.readPump(c)
+	return c
+}
+
+// Broadcast queues msg on every connection, dropping connections that can't keep up.
+func (h *WSHub) Broadcast(msg []byte) {
+	h.mu.RLock()
+	slow := make([]*WSConn, 0)
+	for _, c := range h.conns {
+		select {
+		case c.send <- msg:
+		default:
+			slow = append(slow, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, c := range slow {
+		c.Close()
+	}
+}
+
+// SendTo queues msg for the connection with the given id.
+func (h *WSHub) SendTo(id string, msg []byte) error {
+	h.mu.RLock()
+	c, ok := h.conns[id]
+	h.mu.RUnlock()
+	if !ok {
+		return ErrWSNotFound
+	}
+	return c.Send(msg)
+}
+
+// Connections returns the ids of the registered connections.
+func (h *WSHub) Connections() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	ids := make([]string, 0, len(h.conns))
+	for id := range h.conns {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Close closes every registered connection.
+func (h *WSHub) Close() {
+	h.mu.RLock()
+	conns := make([]*WSConn, 0, len(h.conns))
+	for _, c := range h.conns {
+		conns = append(conns, c)
+	}
+	h.mu.RUnlock()
+	for _, c := range conns {
+		c.Close()
+	}
+}
+
+func (h *WSHub) unregister(c *WSConn) {
+	h.mu.Lock()
+	_, ok := h.conns[c.ID]
+	delete(h.conns, c.ID)
+	h.mu.Unlock()
+	if ok && h.OnClose != nil {
+		h.OnClose(c)
+	}
+}
+
+// readPump consumes inbound messages and pongs until the connection fails.
+func (h *WSHub) readPump(c *WSConn) {
+	defer c.Close()
+
+	if h.MaxMessageSize > 0 {
+		c.conn.SetReadLimit(h.MaxMessageSize)
+	}
+	c.conn.SetReadDeadline(time.Now().Add(h.PongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(h.PongWait))
+	})
+
+	for {
+		_, msg, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if h.OnMessage != nil {
+			h.OnMessage(c, msg)
+		}
+	}
+}
+
+// writePump drains the send queue and emits keepalive pings.
+func (h *WSHub) writePump(c *WSConn) {
+	ticker := time.NewTicker(h.PingInterval)
+	defer func() {
+		ticker.Stop()
+		c.Close()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case <-c.done:
+			c.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+			return
+		case msg := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(h.WriteTimeout))
+			if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(h.WriteTimeout))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,27 @@
+// utility/winreg.go
+package Utility
+
+import "errors"
+
+// Windows registry
+// ----------------
+// ReadRegistryValue, WriteRegistryValue and DeleteRegistryKey give typed
+// access to any key of the registry. Values map to Go types as follows:
+// REG_SZ and REG_EXPAND_SZ <-> string, REG_DWORD <-> uint32 (int is accepted
+// when writing), REG_QWORD <-> uint64, REG_MULTI_SZ <-> []string and
+// REG_BINARY <-> []byte. On other platforms every call fails with
+// ErrRegistryUnsupported.
+
+// RegistryRoot is a predefined registry root key.
+type RegistryRoot int
+
+const (
+	RegistryLocalMachine RegistryRoot = iota
+	RegistryCurrentUser
+	RegistryClassesRoot
+	RegistryUsers
+	RegistryCurrentConfig
+)
+
+// ErrRegistryUnsupported is returned by the registry helpers outside Windows.
+var ErrRegistryUnsupported = errors.New("the registry is available on windows only")
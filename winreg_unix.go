@@ -0,0 +1,16 @@
+// utility/winreg_unix.go
+//go:build !windows
+
+package Utility
+
+func ReadRegistryValue(root RegistryRoot, path, name string) (interface{}, error) {
+	return nil, ErrRegistryUnsupported
+}
+
+func WriteRegistryValue(root RegistryRoot, path, name string, value interface{}) error {
+	return ErrRegistryUnsupported
+}
+
+func DeleteRegistryKey(root RegistryRoot, path, name string) error {
+	return ErrRegistryUnsupported
+}
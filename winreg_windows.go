@@ -0,0 +1,113 @@
+// utility/winreg_windows.go
+//go:build windows
+
+package Utility
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+func (r RegistryRoot) key() (registry.Key, error) {
+	switch r {
+	case RegistryLocalMachine:
+		return registry.LOCAL_MACHINE, nil
+	case RegistryCurrentUser:
+		return registry.CURRENT_USER, nil
+	case RegistryClassesRoot:
+		return registry.CLASSES_ROOT, nil
+	case RegistryUsers:
+		return registry.USERS, nil
+	case RegistryCurrentConfig:
+		return registry.CURRENT_CONFIG, nil
+	}
+	return 0, fmt.Errorf("unknown registry root %d", r)
+}
+
+// ReadRegistryValue returns the value name of the key root\path.
+func ReadRegistryValue(root RegistryRoot, path, name string) (interface{}, error) {
+	rk, err := root.key()
+	if err != nil {
+		return nil, err
+	}
+	k, err := registry.OpenKey(rk, path, registry.QUERY_VALUE)
+	if err != nil {
+		return nil, err
+	}
+	defer k.Close()
+
+	_, typ, err := k.GetValue(name, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch typ {
+	case registry.SZ, registry.EXPAND_SZ:
+		v, _, err := k.GetStringValue(name)
+		return v, err
+	case registry.DWORD:
+		v, _, err := k.GetIntegerValue(name)
+		return uint32(v), err
+	case registry.QWORD:
+		v, _, err := k.GetIntegerValue(name)
+		return v, err
+	case registry.MULTI_SZ:
+		v, _, err := k.GetStringsValue(name)
+		return v, err
+	case registry.BINARY:
+		v, _, err := k.GetBinaryValue(name)
+		return v, err
+	}
+	return nil, fmt.Errorf("registry value %s has unsupported type %d", name, typ)
+}
+
+// WriteRegistryValue sets the value name of the key root\path, creating the
+// key when needed.
+func WriteRegistryValue(root RegistryRoot, path, name string, value interface{}) error {
+	rk, err := root.key()
+	if err != nil {
+		return err
+	}
+	k, _, err := registry.CreateKey(rk, path, registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+
+	switch v := value.(type) {
+	case string:
+		return k.SetStringValue(name, v)
+	case uint32:
+		return k.SetDWordValue(name, v)
+	case int:
+		if v < 0 || v > 0xffffffff {
+			return fmt.Errorf("registry value %s: %d does not fit a DWORD", name, v)
+		}
+		return k.SetDWordValue(name, uint32(v))
+	case uint64:
+		return k.SetQWordValue(name, v)
+	case []string:
+		return k.SetStringsValue(name, v)
+	case []byte:
+		return k.SetBinaryValue(name, v)
+	}
+	return fmt.Errorf("registry value %s: unsupported type %T", name, value)
+}
+
+// DeleteRegistryKey deletes the value name of the key root\path, or the key
+// itself (which must have no subkeys) when name is empty.
+func DeleteRegistryKey(root RegistryRoot, path, name string) error {
+	rk, err := root.key()
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return registry.DeleteKey(rk, path)
+	}
+	k, err := registry.OpenKey(rk, path, registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	return k.DeleteValue(name)
+}